@@ -25,9 +25,11 @@ import (
 	"strings"
 
 	"github.com/chiisen/mini_bot/pkg/agent"
+	"github.com/chiisen/mini_bot/pkg/channels"
 	"github.com/chiisen/mini_bot/pkg/config"
 	"github.com/chiisen/mini_bot/pkg/i18n"
 	"github.com/chiisen/mini_bot/pkg/logger"
+	"github.com/chiisen/mini_bot/pkg/tools"
 )
 
 // ============================================================================
@@ -89,6 +91,12 @@ func RunAgent(args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to initialize agent instance: %w", err)
 	}
+	// 結束時關閉外部資源 (例如 MCP 伺服器子程序)
+	defer instance.Close()
+
+	// send_message 工具透過頻道送出通知 (例如任務完成後通知 Telegram)，
+	// 只需要 bot token，不需要啟動 gateway
+	instance.SetMessageSender(channels.NewSender(&cfg.Channels))
 
 	// -------------------------------------------------------------------------
 	// 步驟 4: 建立上下文
@@ -123,6 +131,9 @@ func RunAgent(args []string) error {
 	// 所有的 CLI 互動都使用相同的會話鍵 "cli_default"
 	sessionKey := "cli_default"
 
+	// CLI 的執行以頻道 "cli" 識別，供工具權限政策判斷
+	ctx = tools.WithCaller(ctx, tools.Caller{Channel: "cli", ChatID: sessionKey})
+
 	// 定義回調函數，用於處理 Agent 的回覆
 	// 當 Agent 產生回覆時，這個函數會被調用
 	printReply := func(msg string) {
@@ -158,8 +169,9 @@ func RunAgent(args []string) error {
 	go func() {
 		<-c // 阻塞直到收到信號
 		fmt.Println("\nInterrupt received. Exiting...")
-		cancel()   // 取消上下文
-		os.Exit(0) // 正常退出程式
+		cancel()         // 取消上下文
+		instance.Close() // os.Exit 不會執行 defer，需手動釋放資源
+		os.Exit(0)       // 正常退出程式
 	}()
 
 	// 建立 Scanner 從標準輸入讀取使用者輸入
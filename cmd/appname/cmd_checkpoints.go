@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chiisen/mini_bot/pkg/agent"
+	"github.com/chiisen/mini_bot/pkg/config"
+	"github.com/chiisen/mini_bot/pkg/tools"
+)
+
+// RunCheckpoints handles the 'app checkpoints' command.
+// It lists the file checkpoints taken before the agent modified files, and restores them.
+//
+// Usage:
+//
+//	app checkpoints list [--session <key>]
+//	app checkpoints restore <id> [--session <key>]
+func RunCheckpoints(args []string) error {
+	cfg, err := config.Load("~/.minibot.go/config.json")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	sandbox, err := tools.NewSandbox(cfg.Agents.Defaults.Workspace)
+	if err != nil {
+		return fmt.Errorf("sandbox initialization failed: %w", err)
+	}
+	store := agent.NewCheckpointStore(sandbox)
+
+	var session string
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--session" && i+1 < len(args) {
+			session = args[i+1]
+			i++
+			continue
+		}
+		rest = append(rest, args[i])
+	}
+
+	sessions := store.Sessions()
+	if session != "" {
+		sessions = []string{session}
+	}
+
+	if len(rest) == 0 || rest[0] == "list" {
+		found := false
+		for _, s := range sessions {
+			for _, cp := range store.List(s) {
+				found = true
+				fmt.Printf("%s  %-8s %s  %-16s %s\n", cp.ID, s, cp.Time.Format("2006-01-02 15:04:05"), cp.Tool, strings.Join(cp.Paths(), ", "))
+			}
+		}
+		if !found {
+			fmt.Println("No checkpoints.")
+		}
+		return nil
+	}
+
+	if rest[0] != "restore" || len(rest) < 2 {
+		return fmt.Errorf("usage: app checkpoints list [--session <key>] | restore <id> [--session <key>]")
+	}
+	id := rest[1]
+	for _, s := range sessions {
+		if !hasCheckpoint(store, s, id) {
+			continue
+		}
+		restored, err := store.Restore(s, id)
+		for _, cp := range restored {
+			fmt.Printf("↩️  Reverted %s (%s): %s\n", cp.ID, cp.Tool, strings.Join(cp.Paths(), ", "))
+		}
+		return err
+	}
+	return fmt.Errorf("checkpoint %s not found", id)
+}
+
+func hasCheckpoint(store *tools.CheckpointStore, session, id string) bool {
+	for _, cp := range store.List(session) {
+		if cp.ID == id {
+			return true
+		}
+	}
+	return false
+}
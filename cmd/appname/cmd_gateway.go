@@ -30,6 +30,7 @@ func RunGateway(args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to initialize agent instance: %w", err)
 	}
+	defer instance.Close()
 
 	// 3. Create Bus and start background listeners
 	ctx, cancel := context.WithCancel(context.Background())
@@ -40,6 +41,8 @@ func RunGateway(args []string) error {
 
 	// 4. Register enabled channels
 	manager := channels.NewManager()
+	sender := channels.NewSender(&cfg.Channels)
+	instance.SetMessageSender(sender)
 
 	if cfg.Channels.Telegram.Enabled {
 		if cfg.Channels.Telegram.Token == "" || cfg.Channels.Telegram.Token == "YOUR_BOT_TOKEN_HERE" {
@@ -47,6 +50,7 @@ func RunGateway(args []string) error {
 		} else {
 			tgChan := channels.NewTelegramChannel(&cfg.Channels.Telegram, messageBus)
 			manager.Register(tgChan)
+			sender.Register("telegram", tgChan)
 			logger.Info("Registered Telegram Channel")
 		}
 	} else {
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/chiisen/mini_bot/pkg/agent"
+	"github.com/chiisen/mini_bot/pkg/config"
+	"github.com/chiisen/mini_bot/pkg/i18n"
+	"github.com/chiisen/mini_bot/pkg/logger"
+	"github.com/chiisen/mini_bot/pkg/mcp"
+)
+
+// RunMCPServe handles the 'app mcp-serve' command.
+// It exposes MiniBot's sandboxed built-in tools to other agents and editors as an MCP server over stdio.
+//
+// Usage: app mcp-serve [--workspace <dir>]
+func RunMCPServe(args []string) error {
+	// stdout carries the JSON-RPC stream, so everything else (config warnings,
+	// stray prints) must go to stderr. Keep the real stdout for the protocol only.
+	protocolOut := os.Stdout
+	os.Stdout = os.Stderr
+
+	logger.Init(false)
+
+	cfg, err := config.Load("~/.minibot.go/config.json")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	workspaceDir := cfg.Agents.Defaults.Workspace
+	for i, arg := range args {
+		if arg == "--workspace" && i+1 < len(args) {
+			workspaceDir = expandHome(args[i+1])
+		}
+	}
+
+	sandbox, err := agent.NewSandbox(cfg, workspaceDir)
+	if err != nil {
+		return fmt.Errorf("sandbox initialization failed for workspace %s: %w", workspaceDir, err)
+	}
+
+	// Only built-in tools are served; external MCP servers from config are not re-exported.
+	processes := agent.NewProcessManager(cfg)
+	defer processes.Shutdown()
+	registry := agent.NewBuiltinRegistry(cfg, sandbox, processes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		cancel()
+	}()
+
+	t := i18n.GetInstance()
+	server := mcp.NewServer(registry, mcp.Implementation{Name: t.T("app.name"), Version: t.T("app.version")})
+
+	logger.Info("Serving MCP over stdio", "workspace", sandbox.Workspace)
+	return server.ServeStdio(ctx, os.Stdin, protocolOut)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"runtime"
 
+	"github.com/chiisen/mini_bot/pkg/agent"
 	"github.com/chiisen/mini_bot/pkg/config"
 	"github.com/chiisen/mini_bot/pkg/i18n"
 )
@@ -57,6 +58,7 @@ func RunStatus(args []string) error {
 		fmt.Printf("❌ Workspace directory missing: %s\n", cfg.Agents.Defaults.Workspace)
 	} else {
 		fmt.Printf("✅ Workspace Directory: Found\n")
+		printSandboxStatus(cfg)
 	}
 
 	// Telegram
@@ -77,6 +79,56 @@ func RunStatus(args []string) error {
 	return nil
 }
 
+// printSandboxStatus prints the extra sandbox roots and workspace usage against the quota.
+func printSandboxStatus(cfg *config.Config) {
+	sandbox, err := agent.NewSandbox(cfg, cfg.Agents.Defaults.Workspace)
+	if err != nil {
+		fmt.Printf("❌ Sandbox: %v\n", err)
+		return
+	}
+	for _, root := range sandbox.Roots {
+		mode := "rw"
+		if root.ReadOnly {
+			mode = "ro"
+		}
+		fmt.Printf("📁 Root %s (%s): %s\n", root.Name, mode, root.Path)
+	}
+
+	usage, err := sandbox.Usage()
+	if err != nil {
+		fmt.Printf("❌ Workspace usage: %v\n", err)
+		return
+	}
+	q := sandbox.Quota
+	icon := "💾"
+	if nearLimit(usage.Bytes, q.MaxTotalBytes) || nearLimit(usage.Files, q.MaxFiles) {
+		icon = "⚠️ "
+	}
+	fmt.Printf("%s Workspace Usage: %s, %s files\n", icon,
+		quotaUsage(float64(usage.Bytes)/(1<<20), float64(q.MaxTotalBytes)/(1<<20), " MB"),
+		quotaUsage(float64(usage.Files), float64(q.MaxFiles), ""))
+	if q.MaxFileBytes > 0 {
+		fmt.Printf("💾 Max File Size: %d MB\n", q.MaxFileBytes>>20)
+	}
+}
+
+// quotaUsage formats used against limit, e.g. "12.3 / 1024 MB (1%)"; a zero limit means unlimited.
+func quotaUsage(used, limit float64, unit string) string {
+	format := "%.0f"
+	if unit != "" {
+		format = "%.1f"
+	}
+	if limit <= 0 {
+		return fmt.Sprintf(format+"%s (no limit)", used, unit)
+	}
+	return fmt.Sprintf(format+" / "+format+"%s (%.0f%%)", used, limit, unit, used/limit*100)
+}
+
+// nearLimit reports whether used is at least 90% of a nonzero limit.
+func nearLimit(used, limit int64) bool {
+	return limit > 0 && used*10 >= limit*9
+}
+
 // bToMb converts bytes to Megabytes
 func bToMb(b uint64) uint64 {
 	return b / 1024 / 1024
@@ -8,6 +8,8 @@ package main
 //   - onboard   : 初始化配置和工作區
 //   - agent     : 啟動單次對話或互動模式
 //   - gateway   : 啟動 Telegram 閘道器
+//   - mcp-serve : 以 MCP 伺服器 (stdio) 對外提供沙盒工具
+//   - checkpoints: 列出或還原檔案修改前的檢查點
 //   - version   : 顯示版本資訊
 //   - status    : 顯示系統狀態
 //   - help      : 顯示說明資訊
@@ -68,6 +70,21 @@ func main() {
 			fmt.Fprintf(os.Stderr, t.T("cli.error")+"\n", err)
 			os.Exit(1)
 		}
+	case "mcp-serve":
+		// mcp-serve 命令：以 MCP 伺服器模式透過 stdio 提供沙盒工具
+		// 讓其他 Agent 或編輯器可以使用 MiniBot 的工作區沙盒與命令白名單
+		if err := RunMCPServe(args); err != nil {
+			t := i18n.GetInstance()
+			fmt.Fprintf(os.Stderr, t.T("cli.error")+"\n", err)
+			os.Exit(1)
+		}
+	case "checkpoints":
+		// checkpoints 命令：列出或還原 Agent 修改檔案前建立的檢查點
+		if err := RunCheckpoints(args); err != nil {
+			t := i18n.GetInstance()
+			fmt.Fprintf(os.Stderr, t.T("cli.error")+"\n", err)
+			os.Exit(1)
+		}
 	case "version":
 		// version 命令：顯示程式版本
 		t := i18n.GetInstance()
@@ -104,6 +121,8 @@ func printHelp() {
 ` + t.T("cli.commands.onboard") + `
     ` + t.T("cli.commands.agent") + `
     ` + t.T("cli.commands.gateway") + `
+    ` + t.T("cli.commands.mcp_serve") + `
+    ` + t.T("cli.commands.checkpoints") + `
     ` + t.T("cli.commands.version") + `
     ` + t.T("cli.commands.status") + `
 `)
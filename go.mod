@@ -1,3 +1,5 @@
 module github.com/chiisen/mini_bot
 
 go 1.25.0
+
+require golang.org/x/text v0.36.0
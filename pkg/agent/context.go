@@ -134,6 +134,10 @@ func SanitizeInput(input string) string {
 // 系統提示詞是傳給 LLM 的初始指令，定義了 AI 的身份、能力邊界和行為規則。
 type Builder struct {
 	WorkspacePath string // 工作區的根目錄路徑
+
+	// Roots 是工作區以外可存取的沙盒根目錄，例如 "docs (read-only)"
+	// 會附加在工具使用指南中，讓 AI 知道可以使用 "名稱:路徑"
+	Roots []string
 }
 
 // NewContextBuilder 建立一個新的 ContextBuilder 實例
@@ -228,6 +232,9 @@ func (b *Builder) Build(tools []providers.ToolDefinition) (string, error) {
 
 		// 添加工具使用說明
 		toolDesc.WriteString("\nWhen you need to perform an action, output a tool call request. Do not try to hallucinate commands execution in plain text, use the provided tools.\n")
+		if len(b.Roots) > 0 {
+			toolDesc.WriteString(fmt.Sprintf("Besides the workspace, file tools can access these directories with \"name:path\" paths: %s.\n", strings.Join(b.Roots, ", ")))
+		}
 
 		// 將工具說明添加到 parts 中
 		parts = append(parts, toolDesc.String())
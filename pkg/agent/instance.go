@@ -17,10 +17,17 @@ package agent
 // ============================================================================
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/chiisen/mini_bot/pkg/config"
+	"github.com/chiisen/mini_bot/pkg/logger"
+	"github.com/chiisen/mini_bot/pkg/mcp"
+	"github.com/chiisen/mini_bot/pkg/plugin"
 	"github.com/chiisen/mini_bot/pkg/providers"
 	"github.com/chiisen/mini_bot/pkg/session"
 	"github.com/chiisen/mini_bot/pkg/tools"
@@ -37,15 +44,22 @@ import (
 //   - Sessions:     對話會話管理器，負責歷史記錄的持久化
 //   - CtxBuilder:   上下文建構器，用於生成系統提示詞
 //   - WorkspaceDir: 工作區目錄路徑
+//   - Usage:        使用統計 (工具呼叫次數、參數 JSON 修復次數等)
+//   - Processes:    背景行程管理器 (關閉時終止所有背景行程)
+//   - Messenger:    send_message 工具 (未設定目的地時為 nil)
 //
 // ============================================================================
 type AgentInstance struct {
-	Config       *config.Config        // 應用程式配置
-	Provider     providers.LLMProvider // LLM 提供者介面
-	Registry     *tools.ToolRegistry   // 工具註冊表
-	Sessions     *session.Manager      // 對話會話管理器
-	CtxBuilder   *Builder              // 上下文建構器
-	WorkspaceDir string                // 工作區目錄路徑
+	Config       *config.Config         // 應用程式配置
+	Provider     providers.LLMProvider  // LLM 提供者介面
+	Registry     *tools.ToolRegistry    // 工具註冊表
+	Sessions     *session.Manager       // 對話會話管理器
+	CtxBuilder   *Builder               // 上下文建構器
+	WorkspaceDir string                 // 工作區目錄路徑
+	Usage        *UsageTracker          // 使用統計
+	MCPClients   []*mcp.Client          // 外部 MCP 伺服器連線 (關閉時需釋放)
+	Processes    *tools.ProcessManager  // 背景行程 (關閉時需終止)
+	Messenger    *tools.SendMessageTool // 主動傳送訊息的工具，送出方式由 SetMessageSender 設定
 }
 
 // ============================================================================
@@ -101,8 +115,8 @@ func NewInstance(cfg *config.Config) (*AgentInstance, error) {
 	workspaceDir := cfg.Agents.Defaults.Workspace
 
 	// 建立沙盒環境
-	// 沙盒確保工具只能在工作區目錄內操作，防止目錄穿越攻擊
-	sandbox, err := tools.NewSandbox(workspaceDir)
+	// 沙盒確保工具只能在工作區與設定的根目錄內操作，防止目錄穿越攻擊
+	sandbox, err := NewSandbox(cfg, workspaceDir)
 	if err != nil {
 		return nil, fmt.Errorf("sandbox initialization failed for workspace %s: %w", workspaceDir, err)
 	}
@@ -110,21 +124,27 @@ func NewInstance(cfg *config.Config) (*AgentInstance, error) {
 	// -------------------------------------------------------------------------
 	// 步驟 4: 建立工具註冊表並註冊工具
 	// -------------------------------------------------------------------------
-	// 建立空的工具註冊表
-	registry := tools.NewRegistry()
+	// 註冊所有內建工具 (檔案操作、命令執行、網路搜尋與擷取)
+	processes := NewProcessManager(cfg)
+	registry := NewBuiltinRegistry(cfg, sandbox, processes)
 
-	// 註冊檔案操作工具
-	registry.Register(&tools.ReadFileTool{Sandbox: sandbox})   // 讀取檔案
-	registry.Register(&tools.WriteFileTool{Sandbox: sandbox})  // 寫入檔案
-	registry.Register(&tools.AppendFileTool{Sandbox: sandbox}) // 追加檔案
-	registry.Register(&tools.ListDirTool{Sandbox: sandbox})    // 列出目錄
-	registry.Register(&tools.EditFileTool{Sandbox: sandbox})   // 編輯檔案
+	// 主動傳送訊息到白名單中的目的地；實際送出由呼叫端以 SetMessageSender 設定
+	// (agent 套件不能匯入 channels，否則會形成循環匯入)
+	messenger := buildSendMessageTool(cfg.Tools.SendMessage)
+	if messenger != nil {
+		registry.Register(messenger)
+	}
 
-	// 註冊命令執行工具
-	registry.Register(&tools.ExecTool{Sandbox: sandbox}) // 執行 Shell 命令
+	// 註冊外部 MCP 伺服器提供的工具
+	// 無法連線的伺服器只會記錄警告並略過，不會中斷啟動
+	mcpClients := mcp.RegisterServers(context.Background(), cfg.Tools.MCPServers, registry)
 
-	// 註冊網路工具
-	registry.Register(&tools.WebSearchTool{}) // 網路搜尋
+	// 註冊外掛目錄中的外掛工具 (任何語言撰寫的可執行檔)
+	// 新增外掛只需放入可執行檔，不必修改此處
+	registerPlugins(registry, cfg, sandbox)
+
+	// 依頻道、聊天室與使用者限制可用的工具 (每次執行時過濾工具列表)
+	registry.Permissions = buildPermissionPolicy(cfg.Tools.Permissions)
 
 	// -------------------------------------------------------------------------
 	// 步驟 5: 建立對話會話管理器
@@ -138,6 +158,13 @@ func NewInstance(cfg *config.Config) (*AgentInstance, error) {
 	// -------------------------------------------------------------------------
 	// 上下文建構器用於根據工作區設定檔生成系統提示詞
 	ctxBuilder := NewContextBuilder(workspaceDir)
+	for _, root := range sandbox.Roots {
+		mode := "read-write"
+		if root.ReadOnly {
+			mode = "read-only"
+		}
+		ctxBuilder.Roots = append(ctxBuilder.Roots, fmt.Sprintf("%s (%s)", root.Name, mode))
+	}
 
 	// -------------------------------------------------------------------------
 	// 完成: 返回初始化完成的 Agent 實例
@@ -149,5 +176,311 @@ func NewInstance(cfg *config.Config) (*AgentInstance, error) {
 		Sessions:     sessMgr,      // 對話會話管理器
 		CtxBuilder:   ctxBuilder,   // 上下文建構器
 		WorkspaceDir: workspaceDir, // 工作區目錄
+		Usage:        NewUsageTracker(),
+		MCPClients:   mcpClients,
+		Processes:    processes,
+		Messenger:    messenger,
 	}, nil
 }
+
+// ============================================================================
+// NewBuiltinRegistry: 建立內建工具註冊表
+// ============================================================================
+// 建立只包含 MiniBot 內建工具的註冊表，所有工具都受同一個沙盒保護。
+// Agent 與 mcp-serve 指令共用這個函數，確保兩者提供的工具與安全限制完全一致。
+//
+// 參數：
+//   - cfg:     應用程式配置
+//   - sandbox:   工作區沙盒
+//   - processes: 背景行程管理器，由呼叫端在結束時呼叫 Shutdown
+//
+// 回傳：
+//   - *tools.ToolRegistry: 已註冊內建工具的註冊表
+//
+// ============================================================================
+func NewBuiltinRegistry(cfg *config.Config, sandbox *tools.Sandbox, processes *tools.ProcessManager) *tools.ToolRegistry {
+	registry := tools.NewRegistry()
+
+	// 過長的工具結果只保留頭尾，完整內容保存到 workspace/.tool-output
+	if cfg.Tools.Output.MaxTokens >= 0 {
+		registry.Output = tools.NewOutputPolicy(sandbox.Workspace, cfg.Tools.Output.MaxTokens)
+	}
+
+	// 修改檔案前的檢查點，供 undo_last_change 與 app checkpoints 還原
+	checkpoints := NewCheckpointStore(sandbox)
+
+	// 註冊檔案操作工具
+	registry.Register(&tools.ReadFileTool{Sandbox: sandbox})                                // 讀取檔案
+	registry.Register(&tools.WriteFileTool{Sandbox: sandbox, Checkpoints: checkpoints})     // 寫入檔案
+	registry.Register(&tools.AppendFileTool{Sandbox: sandbox, Checkpoints: checkpoints})    // 追加檔案
+	registry.Register(&tools.ListDirTool{Sandbox: sandbox})                                 // 列出目錄
+	registry.Register(&tools.EditFileTool{Sandbox: sandbox, Checkpoints: checkpoints})      // 編輯檔案
+	registry.Register(&tools.ReplaceInFileTool{Sandbox: sandbox, Checkpoints: checkpoints}) // 搜尋取代
+	registry.Register(&tools.ApplyPatchTool{Sandbox: sandbox, Checkpoints: checkpoints})    // 套用修補檔
+	registry.Register(&tools.UndoLastChangeTool{Checkpoints: checkpoints})                  // 復原上一次修改
+	registry.Register(&tools.MovePathTool{Sandbox: sandbox, Checkpoints: checkpoints})      // 移動或重新命名
+	registry.Register(&tools.CopyPathTool{Sandbox: sandbox, Checkpoints: checkpoints})      // 複製檔案或目錄
+	registry.Register(&tools.MakeDirTool{Sandbox: sandbox})                                 // 建立目錄
+	registry.Register(&tools.SearchFilesTool{Sandbox: sandbox})                             // 搜尋檔案內容
+	registry.Register(&tools.FindFilesTool{Sandbox: sandbox})                               // 依 Glob 尋找檔案
+	registry.Register(&tools.DeletePathTool{                                                // 刪除 (移到垃圾桶)
+		Sandbox:     sandbox,
+		TrashDir:    filepath.Join(sandbox.Workspace, ".trash"),
+		Checkpoints: checkpoints,
+	})
+
+	// 註冊命令執行工具
+	execTool := &tools.ExecTool{
+		Sandbox:   sandbox,
+		Policy:    buildExecPolicy(cfg.Tools.Exec),
+		Isolation: buildExecIsolation(cfg.Tools.Exec.Sandbox),
+	}
+	registry.Register(execTool)                                                    // 執行命令 (不經過 Shell)
+	registry.Register(&tools.ProcessStartTool{Exec: execTool, Manager: processes}) // 啟動背景行程
+	registry.Register(&tools.ProcessPollTool{Manager: processes})                  // 讀取背景行程輸出
+	registry.Register(&tools.ProcessListTool{Manager: processes})                  // 列出背景行程
+	registry.Register(&tools.ProcessInputTool{Manager: processes})                 // 寫入背景行程 stdin
+	registry.Register(&tools.ProcessKillTool{Manager: processes})                  // 終止背景行程
+
+	// 註冊網路工具
+	registry.Register(&tools.WebSearchTool{ // 網路搜尋
+		Engines:    buildSearchEngines(cfg.Tools.Search),
+		MaxResults: cfg.Tools.Search.MaxResults,
+	})
+	registry.Register(&tools.WebFetchTool{}) // 擷取網頁內容
+
+	// 註冊 ~/.minibot.go/tools/*.json 定義的自訂工具 (經過相同的命令政策與隔離)
+	// 必須在所有內建工具之後，與內建工具同名的範本才會被略過而不是覆蓋內建工具
+	registerCustomTools(registry, cfg.Tools.Custom, sandbox, execTool)
+
+	return registry
+}
+
+// NewCheckpointStore 建立工作區的檢查點儲存區 (workspace/.checkpoints)，
+// 並保護儲存目錄，避免 Agent 以檔案工具偽造檢查點後透過還原寫入唯讀的位置
+func NewCheckpointStore(sandbox *tools.Sandbox) *tools.CheckpointStore {
+	store := tools.NewCheckpointStore(sandbox.Workspace, filepath.Join(sandbox.Workspace, ".checkpoints"))
+	store.Sandbox = sandbox
+	if err := sandbox.Protect(store.Dir); err != nil {
+		logger.Warn("Cannot protect the checkpoint store", "dir", store.Dir, "error", err)
+	}
+	return store
+}
+
+// registerCustomTools 載入自訂工具範本；格式錯誤或與內建工具同名的範本會記錄警告後略過
+//
+// 範本等同於程式碼 (可以執行命令並把允許的環境變數送到任意主機)，
+// 所以 Agent 能以檔案工具寫入的目錄 (工作區或可寫入的根目錄) 一律不載入
+func registerCustomTools(registry *tools.ToolRegistry, cfg config.CustomToolsConfig, sandbox *tools.Sandbox, execTool *tools.ExecTool) {
+	if cfg.Dir == "" {
+		return
+	}
+	if sandbox.InWritableRoot(cfg.Dir) {
+		logger.Warn("Skipping custom tools in a directory the agent can write; move them out of the workspace or configure the directory as a read-only root", "dir", cfg.Dir)
+		return
+	}
+	custom, errs := tools.LoadCustomTools(cfg.Dir, execTool, cfg.Env)
+	for _, err := range errs {
+		logger.Warn("Skipping custom tool", "error", err)
+	}
+	for _, tool := range custom {
+		if registry.Has(tool.Name()) {
+			logger.Warn("Skipping custom tool that shadows a built-in tool", "name", tool.Name())
+			continue
+		}
+		registry.Register(tool)
+		logger.Info("Registered custom tool", "name", tool.Name())
+	}
+}
+
+// registerPlugins 註冊外掛目錄中的外掛工具，外掛以 exec 相同的清理過的環境變數執行
+//
+// 外掛是任意的可執行檔，所以與自訂工具相同，Agent 能以檔案工具寫入的目錄一律不載入
+func registerPlugins(registry *tools.ToolRegistry, cfg *config.Config, sandbox *tools.Sandbox) []*plugin.Plugin {
+	pluginCfg := cfg.Tools.Plugins
+	if pluginCfg.Dir == "" {
+		return nil
+	}
+	if sandbox.InWritableRoot(pluginCfg.Dir) {
+		logger.Warn("Skipping plugins in a directory the agent can write; move them out of the workspace or configure the directory as a read-only root", "dir", pluginCfg.Dir)
+		return nil
+	}
+	// 外掛是使用者安裝的程式，HOME 指向使用者的家目錄 (不能是 Agent 可寫入的工作區)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.TempDir()
+	}
+	env := buildExecIsolation(cfg.Tools.Exec.Sandbox).Environ(home)
+	return plugin.RegisterPlugins(context.Background(), pluginCfg, sandbox.Workspace, env, registry)
+}
+
+// NewSandbox 依設定建立沙盒：加入額外的根目錄 (唯讀或可寫入) 與寫入配額，
+// restrictToWorkspace 為 false 時允許存取所有根目錄以外的絕對路徑
+//
+// 無效的根目錄 (名稱不合法、目錄不存在、模式錯誤) 只會記錄警告並略過
+func NewSandbox(cfg *config.Config, workspaceDir string) (*tools.Sandbox, error) {
+	sandbox, err := tools.NewSandbox(workspaceDir)
+	if err != nil {
+		return nil, err
+	}
+	sandbox.Unrestricted = !cfg.Agents.Defaults.RestrictToWorkspace
+	sandbox.Quota = buildQuota(cfg.Agents.Defaults.Quota)
+
+	names := make([]string, 0, len(cfg.Agents.Defaults.Roots))
+	for name := range cfg.Agents.Defaults.Roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		root := cfg.Agents.Defaults.Roots[name]
+		var readOnly bool
+		switch root.Mode {
+		case "", "ro":
+			readOnly = true
+		case "rw":
+		default:
+			logger.Warn("Skipping sandbox root", "root", name, "error", fmt.Sprintf("unknown mode %q (use ro or rw)", root.Mode))
+			continue
+		}
+		if err := sandbox.AddRoot(name, root.Path, readOnly); err != nil {
+			logger.Warn("Skipping sandbox root", "root", name, "error", err)
+		}
+	}
+	return sandbox, nil
+}
+
+// buildQuota 將設定檔的配額轉為 tools.Quota；0 使用預設值，負數表示不限制
+func buildQuota(cfg config.QuotaConfig) *tools.Quota {
+	quota := tools.DefaultQuota()
+	limit := func(value int, unit int64, target *int64) {
+		switch {
+		case value < 0:
+			*target = 0
+		case value > 0:
+			*target = int64(value) * unit
+		}
+	}
+	limit(cfg.MaxFileMB, 1<<20, &quota.MaxFileBytes)
+	limit(cfg.MaxTotalMB, 1<<20, &quota.MaxTotalBytes)
+	limit(cfg.MaxFiles, 1, &quota.MaxFiles)
+	return quota
+}
+
+// NewProcessManager 依設定建立背景行程管理器
+func NewProcessManager(cfg *config.Config) *tools.ProcessManager {
+	processes := tools.NewProcessManager()
+	processes.MaxPerSession = cfg.Tools.Exec.MaxBackgroundProcesses
+	return processes
+}
+
+// buildPermissionPolicy 將設定檔的權限規則轉為 tools.PermissionPolicy；沒有規則時回傳 nil (不限制)
+func buildPermissionPolicy(rules []config.ToolPermissionConfig) *tools.PermissionPolicy {
+	if len(rules) == 0 {
+		return nil
+	}
+	policy := &tools.PermissionPolicy{Rules: make([]tools.PermissionRule, 0, len(rules))}
+	for _, rule := range rules {
+		policy.Rules = append(policy.Rules, tools.PermissionRule{
+			Channel: rule.Channel,
+			ChatID:  rule.ChatID,
+			UserID:  rule.UserID,
+			Allow:   rule.Allow,
+			Deny:    rule.Deny,
+			Args:    rule.Args,
+		})
+	}
+	return policy
+}
+
+// buildSendMessageTool 將設定檔的目的地轉為 send_message 工具；沒有有效目的地時回傳 nil
+func buildSendMessageTool(cfg config.SendMessageConfig) *tools.SendMessageTool {
+	targets := make(map[string]tools.MessageTarget, len(cfg.Targets))
+	for name, target := range cfg.Targets {
+		if name == "" || target.Channel == "" || target.ChatID == "" {
+			logger.Warn("Skipping send_message target", "name", name, "error", "channel and chatId are required")
+			continue
+		}
+		targets[name] = tools.MessageTarget{
+			Channel:     target.Channel,
+			ChatID:      target.ChatID,
+			Description: target.Description,
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	return &tools.SendMessageTool{Targets: targets}
+}
+
+// buildExecPolicy 以設定檔的規則覆蓋預設的命令白名單
+func buildExecPolicy(cfg config.ExecConfig) *tools.ExecPolicy {
+	overrides := make(map[string]tools.CommandRule, len(cfg.Commands))
+	for name, rule := range cfg.Commands {
+		overrides[name] = tools.CommandRule{Allow: rule.Allow, Deny: rule.Deny}
+	}
+	return tools.DefaultExecPolicy().Merge(overrides)
+}
+
+// buildExecIsolation 將設定檔的資源限制轉為 tools.ExecIsolation；0 使用預設值，負數表示不限制
+func buildExecIsolation(cfg config.ExecSandboxConfig) *tools.ExecIsolation {
+	iso := tools.DefaultExecIsolation()
+	limit := func(value int, unit uint64, target *uint64) {
+		switch {
+		case value < 0:
+			*target = 0
+		case value > 0:
+			*target = uint64(value) * unit
+		}
+	}
+	limit(cfg.CPUSeconds, 1, &iso.CPUSeconds)
+	limit(cfg.MemoryMB, 1<<20, &iso.MemoryBytes)
+	limit(cfg.FileSizeMB, 1<<20, &iso.FileSizeBytes)
+	limit(cfg.MaxProcesses, 1, &iso.MaxProcesses)
+	iso.Namespaces = cfg.Namespaces
+	iso.AllowNetwork = cfg.AllowNetwork
+	iso.PassEnv = cfg.PassEnv
+	return iso
+}
+
+// buildSearchEngines 依設定建立搜尋後端，設定錯誤的後端會記錄警告後略過
+func buildSearchEngines(cfg config.SearchConfig) []tools.SearchEngine {
+	var engines []tools.SearchEngine
+	for _, name := range cfg.Engines {
+		name = strings.ToLower(strings.TrimSpace(name))
+		var endpoint, key string
+		switch name {
+		case "searxng":
+			endpoint = cfg.SearXNGURL
+		case "brave":
+			key = cfg.BraveAPIKey
+		case "tavily":
+			key = cfg.TavilyAPIKey
+		}
+		engine, err := tools.NewSearchEngine(name, endpoint, key)
+		if err != nil {
+			logger.Warn("Skipping search engine", "engine", name, "error", err)
+			continue
+		}
+		engines = append(engines, engine)
+	}
+	return engines
+}
+
+// SetMessageSender 設定 send_message 工具送出訊息的方式 (通常是 channels.Sender)；
+// 未設定任何目的地時不做任何事
+func (a *AgentInstance) SetMessageSender(sender tools.MessageSender) {
+	if a.Messenger != nil {
+		a.Messenger.Sender = sender
+	}
+}
+
+// Close 釋放 Agent 持有的外部資源 (MCP 伺服器子程序與背景行程)
+func (a *AgentInstance) Close() {
+	for _, c := range a.MCPClients {
+		_ = c.Close()
+	}
+	a.MCPClients = nil
+	if a.Processes != nil {
+		a.Processes.Shutdown()
+	}
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/chiisen/mini_bot/pkg/config"
+	"github.com/chiisen/mini_bot/pkg/tools"
+)
+
+func TestRegisterPlugins_RefusesWritableDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake plugin is a shell script")
+	}
+	ws := t.TempDir()
+	sandbox, _ := tools.NewSandbox(ws)
+	dir := filepath.Join(ws, "plugins")
+	os.MkdirAll(dir, 0755)
+	marker := filepath.Join(t.TempDir(), "ran")
+	script := "#!/bin/sh\ntouch " + marker + "\ncat >/dev/null\n" +
+		`echo '{"protocolVersion":1,"tools":[{"name":"sh_tool","description":"x"}]}'` + "\n"
+	os.WriteFile(filepath.Join(dir, "sh"), []byte(script), 0755)
+
+	cfg := &config.Config{}
+	cfg.Tools.Plugins.Dir = dir
+
+	// Agent 能寫入的外掛目錄不會被執行
+	registry := tools.NewRegistry()
+	if plugins := registerPlugins(registry, cfg, sandbox); plugins != nil || registry.Has("sh_tool") {
+		t.Fatalf("plugins in the workspace must not load, got %v", plugins)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatal("plugin in the workspace was executed")
+	}
+
+	// 設為唯讀根目錄後才會載入
+	if err := sandbox.AddRoot("plugins", dir, true); err != nil {
+		t.Fatal(err)
+	}
+	registry = tools.NewRegistry()
+	if plugins := registerPlugins(registry, cfg, sandbox); len(plugins) != 1 || !registry.Has("sh_tool") {
+		t.Fatalf("plugins in a read-only root should load, got %v", plugins)
+	}
+}
+
+func TestNewBuiltinRegistry_CustomToolsCannotShadowBuiltins(t *testing.T) {
+	sandbox, _ := tools.NewSandbox(t.TempDir())
+	dir := t.TempDir()
+	builtins := []string{"read_file", "exec", "web_search", "web_fetch"}
+	for _, name := range append(builtins, "greet") {
+		data, _ := json.Marshal(map[string]any{"name": name, "description": "custom " + name, "command": "echo hi"})
+		os.WriteFile(filepath.Join(dir, name+".json"), data, 0644)
+	}
+	cfg := &config.Config{}
+	cfg.Tools.Custom.Dir = dir
+
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	registry := NewBuiltinRegistry(cfg, sandbox, nil)
+	for _, def := range registry.Definitions() {
+		if name := def.Function.Name; name != "greet" && def.Function.Description == "custom "+name {
+			t.Errorf("custom tool replaced the built-in %s", name)
+		}
+	}
+	// 同名的範本必須以警告略過，而不是先註冊後被內建工具靜默覆蓋
+	for _, name := range builtins {
+		if !strings.Contains(logs.String(), `msg="Skipping custom tool that shadows a built-in tool" name=`+name+"\n") {
+			t.Errorf("no warning for the custom tool named %s:\n%s", name, logs.String())
+		}
+	}
+	if !registry.Has("greet") {
+		t.Error("custom tools with new names should be registered")
+	}
+}
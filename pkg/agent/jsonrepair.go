@@ -0,0 +1,257 @@
+package agent
+
+// ============================================================================
+// 工具參數 JSON 修復 (Tool Argument JSON Repair)
+// ============================================================================
+// 小型本地模型產生的工具參數經常不是合法的 JSON，常見問題包括：
+//   - 被 Markdown 程式碼區塊包住 (```json ... ```)
+//   - 結尾多餘的逗號: {"a": 1,}
+//   - 使用單引號: {'path': 'a.txt'}
+//   - 字串內含未跳脫的換行或 Tab
+//   - 未加引號的鍵: {path: "a.txt"}
+//   - Python 風格的常值: True / False / None
+//   - 輸出被截斷，缺少結尾的引號或括號
+//
+// RepairJSON 以單次掃描修正上述問題，修正後仍必須通過 json.Valid 才算成功，
+// 因此不會把無法理解的輸入「猜」成錯誤的參數。
+// ============================================================================
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// RepairJSON 嘗試修復不合法的 JSON 字串
+//
+// 回傳：
+//   - string: 修復後的 JSON (若失敗則為原始輸入)
+//   - bool:   是否成功修復為合法 JSON
+func RepairJSON(input string) (string, bool) {
+	s := strings.TrimSpace(stripCodeFence(input))
+	if s == "" {
+		return "{}", true
+	}
+
+	// 只保留第一個 JSON 物件或陣列之後的內容 (去掉模型附加的前言)
+	if i := strings.IndexAny(s, "{["); i > 0 {
+		s = s[i:]
+	}
+
+	repaired := repairTokens(s)
+	if json.Valid([]byte(repaired)) {
+		return repaired, true
+	}
+	return input, false
+}
+
+// stripCodeFence 去除包住內容的 Markdown 程式碼區塊標記
+func stripCodeFence(s string) string {
+	t := strings.TrimSpace(s)
+	if !strings.HasPrefix(t, "```") {
+		return s
+	}
+	t = strings.TrimPrefix(t, "```")
+	if nl := strings.IndexByte(t, '\n'); nl >= 0 {
+		t = t[nl+1:]
+	} else {
+		t = strings.TrimPrefix(t, "json")
+	}
+	return strings.TrimSuffix(strings.TrimSpace(t), "```")
+}
+
+// repairTokens 逐字元掃描並修正常見缺陷，最後補齊未關閉的字串與括號
+func repairTokens(s string) string {
+	out := make([]byte, 0, len(s)+8)
+	var stack []byte // 尚未關閉的 '{' 或 '['
+
+	inString := false
+	var quote byte // 目前字串的引號字元 (' 或 ")
+	escaped := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+				if c == '\'' {
+					// \' 在 JSON 中不是合法跳脫，改為單純的單引號
+					out[len(out)-1] = '\''
+					continue
+				}
+				out = append(out, c)
+			case c == '\\':
+				escaped = true
+				out = append(out, c)
+			case c == quote:
+				inString = false
+				out = append(out, '"')
+			case c == '"':
+				// 單引號字串內的雙引號需要跳脫
+				out = append(out, '\\', '"')
+			case c == '\n':
+				out = append(out, '\\', 'n')
+			case c == '\r':
+				out = append(out, '\\', 'r')
+			case c == '\t':
+				out = append(out, '\\', 't')
+			case c < 0x20:
+				// 其他控制字元直接略過
+			default:
+				out = append(out, c)
+			}
+			continue
+		}
+
+		switch c {
+		case '"', '\'':
+			inString = true
+			quote = c
+			out = append(out, '"')
+		case '{', '[':
+			stack = append(stack, c)
+			out = append(out, c)
+		case '}', ']':
+			out = trimTrailingComma(out)
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			out = append(out, c)
+		default:
+			if isIdentStart(c) {
+				j := i
+				for j < len(s) && isIdentChar(s[j]) {
+					j++
+				}
+				word := s[i:j]
+				if lit, ok := pythonLiterals[word]; ok {
+					out = append(out, lit...)
+				} else if nextNonSpace(s, j) == ':' {
+					// 未加引號的鍵
+					out = append(out, '"')
+					out = append(out, word...)
+					out = append(out, '"')
+				} else {
+					out = append(out, word...)
+				}
+				i = j - 1
+				continue
+			}
+			out = append(out, c)
+		}
+	}
+
+	// 處理被截斷的輸出：補上字串結尾、移除懸空的逗號或鍵，再關閉所有括號
+	if inString {
+		if escaped {
+			out = out[:len(out)-1]
+		}
+		out = append(out, '"')
+	}
+	for len(stack) > 0 {
+		out = trimDangling(out, stack[len(stack)-1] == '{')
+		if stack[len(stack)-1] == '{' {
+			out = append(out, '}')
+		} else {
+			out = append(out, ']')
+		}
+		stack = stack[:len(stack)-1]
+	}
+
+	return string(out)
+}
+
+var pythonLiterals = map[string]string{
+	"True":  "true",
+	"False": "false",
+	"None":  "null",
+}
+
+// trimTrailingComma 移除即將關閉的括號前多餘的逗號
+func trimTrailingComma(out []byte) []byte {
+	trimmed := trimSpaceRight(out)
+	if len(trimmed) > 0 && trimmed[len(trimmed)-1] == ',' {
+		return trimmed[:len(trimmed)-1]
+	}
+	return out
+}
+
+// trimDangling 移除截斷處懸空的逗號、冒號或沒有值的鍵
+func trimDangling(out []byte, inObject bool) []byte {
+	out = trimSpaceRight(out)
+	if len(out) == 0 {
+		return out
+	}
+	switch out[len(out)-1] {
+	case ',':
+		return out[:len(out)-1]
+	case ':':
+		// {"a": 1, "b":  -> 去掉 "b":
+		return dropLastKey(trimSpaceRight(out[:len(out)-1]))
+	case '"':
+		// {"a": 1, "b"  -> 物件中緊接在 '{' 或 ',' 之後的字串是沒有值的鍵
+		if k := lastKeyStart(out); inObject && k >= 0 {
+			before := trimSpaceRight(out[:k])
+			if len(before) > 0 && (before[len(before)-1] == ',' || before[len(before)-1] == '{') {
+				return dropLastKey(out)
+			}
+		}
+	}
+	return out
+}
+
+// dropLastKey 移除結尾的鍵以及它前面的逗號
+func dropLastKey(out []byte) []byte {
+	k := lastKeyStart(out)
+	if k < 0 {
+		return out
+	}
+	out = trimSpaceRight(out[:k])
+	if len(out) > 0 && out[len(out)-1] == ',' {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+func trimSpaceRight(b []byte) []byte {
+	for len(b) > 0 {
+		switch b[len(b)-1] {
+		case ' ', '\t', '\r', '\n':
+			b = b[:len(b)-1]
+		default:
+			return b
+		}
+	}
+	return b
+}
+
+// lastKeyStart 找出結尾字串 (鍵) 的起始引號位置
+func lastKeyStart(b []byte) int {
+	if len(b) < 2 || b[len(b)-1] != '"' {
+		return -1
+	}
+	for i := len(b) - 2; i >= 0; i-- {
+		if b[i] == '"' && (i == 0 || b[i-1] != '\\') {
+			return i
+		}
+	}
+	return -1
+}
+
+func nextNonSpace(s string, i int) byte {
+	for ; i < len(s); i++ {
+		if s[i] != ' ' && s[i] != '\t' && s[i] != '\n' && s[i] != '\r' {
+			return s[i]
+		}
+	}
+	return 0
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/chiisen/mini_bot/pkg/config"
+	"github.com/chiisen/mini_bot/pkg/providers"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]any
+	}{
+		{"trailing comma", `{"path": "a.txt", "line": 3,}`, map[string]any{"path": "a.txt", "line": float64(3)}},
+		{"trailing comma in array", `{"items": [1, 2, ], }`, map[string]any{"items": []any{float64(1), float64(2)}}},
+		{"single quotes", `{'path': 'notes/a.txt'}`, map[string]any{"path": "notes/a.txt"}},
+		{"single quotes with double quote inside", `{'content': 'say "hi"'}`, map[string]any{"content": `say "hi"`}},
+		{"escaped single quote", `{'content': 'it\'s'}`, map[string]any{"content": "it's"}},
+		{"unescaped newline", "{\"content\": \"line1\nline2\tend\"}", map[string]any{"content": "line1\nline2\tend"}},
+		{"unquoted keys", `{path: "a.txt", start_line: 1}`, map[string]any{"path": "a.txt", "start_line": float64(1)}},
+		{"python literals", `{"a": True, "b": False, "c": None}`, map[string]any{"a": true, "b": false}},
+		{"code fence", "```json\n{\"path\": \"a.txt\"}\n```", map[string]any{"path": "a.txt"}},
+		{"leading prose", `Here are the args: {"path": "a.txt"}`, map[string]any{"path": "a.txt"}},
+		{"truncated string", `{"path": "a.txt", "content": "hello wor`, map[string]any{"path": "a.txt", "content": "hello wor"}},
+		{"truncated after colon", `{"path": "a.txt", "content":`, map[string]any{"path": "a.txt"}},
+		{"truncated after key", `{"path": "a.txt", "content"`, map[string]any{"path": "a.txt"}},
+		{"truncated nested", `{"opts": {"x": [1, 2`, map[string]any{"opts": map[string]any{"x": []any{float64(1), float64(2)}}}},
+		{"empty", "", map[string]any{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repaired, ok := RepairJSON(tt.input)
+			if !ok {
+				t.Fatalf("expected repair to succeed for %q", tt.input)
+			}
+			var got map[string]any
+			if err := json.Unmarshal([]byte(repaired), &got); err != nil {
+				t.Fatalf("repaired JSON is invalid: %v (%s)", err, repaired)
+			}
+			// null values are irrelevant for the comparison
+			for k, v := range got {
+				if v == nil {
+					delete(got, k)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v (%s)", tt.want, got, repaired)
+			}
+		})
+	}
+}
+
+func TestRepairJSON_Unrecoverable(t *testing.T) {
+	for _, input := range []string{`{"a": hello world}`, `not json at all`} {
+		if out, ok := RepairJSON(input); ok {
+			t.Errorf("expected %q to be unrecoverable, got %s", input, out)
+		}
+	}
+}
+
+func TestParseToolArgs_RecordsRepairs(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Model = "ollama/qwen"
+	a := &AgentInstance{Config: cfg, Usage: NewUsageTracker()}
+
+	call := providers.ToolCall{Function: providers.FunctionCall{Name: "read_file", Arguments: `{'path': 'a.txt',}`}}
+	args, err := a.parseToolArgs(&call)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if args["path"] != "a.txt" {
+		t.Errorf("expected path a.txt, got %v", args["path"])
+	}
+	if call.Function.Arguments != `{"path": "a.txt"}` {
+		t.Errorf("expected arguments to be replaced with repaired JSON, got %s", call.Function.Arguments)
+	}
+
+	bad := providers.ToolCall{Function: providers.FunctionCall{Name: "read_file", Arguments: `{"path": what}`}}
+	if _, err := a.parseToolArgs(&bad); err == nil {
+		t.Error("expected error for unrecoverable arguments")
+	}
+
+	good := providers.ToolCall{Function: providers.FunctionCall{Name: "read_file", Arguments: `{"path": "b.txt"}`}}
+	if _, err := a.parseToolArgs(&good); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stats := a.Usage.GetArgRepairStats()["ollama/qwen"]
+	if stats.Repaired != 1 || stats.Failed != 1 {
+		t.Errorf("expected 1 repaired and 1 failed, got %+v", stats)
+	}
+}
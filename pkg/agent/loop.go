@@ -22,7 +22,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/chiisen/mini_bot/pkg/logger"
 	"github.com/chiisen/mini_bot/pkg/providers"
+	"github.com/chiisen/mini_bot/pkg/tools"
 )
 
 // ============================================================================
@@ -57,12 +59,15 @@ func (a *AgentInstance) Run(
 	userInput string,
 	onReply func(msg string),
 ) error {
+	// 讓工具 (例如檢查點) 可以依會話保存狀態
+	ctx = tools.WithSessionKey(ctx, sessionKey)
 
 	// -------------------------------------------------------------------------
 	// 步驟 1: 建構系統提示詞 (Build System Prompt)
 	// -------------------------------------------------------------------------
-	// 從工具註冊表取得所有可用工具的定義
-	toolDefs := a.Registry.Definitions()
+	// 從工具註冊表取得此呼叫者 (頻道、聊天室、使用者) 可以使用的工具定義
+	// 不允許的工具不會出現在系統提示詞與工具列表中
+	toolDefs := a.Registry.DefinitionsFor(tools.CallerFrom(ctx))
 
 	// 使用 ContextBuilder 根據工作區設定檔建構系統提示詞
 	// 系統提示詞包含：身份定義、Agent 指南、性格特徵、使用者偏好、可用工具列表
@@ -89,9 +94,12 @@ func (a *AgentInstance) Run(
 	messages := make([]providers.Message, 0, len(history)+2)
 
 	// 加入系統提示詞 (包含 AI 的身份和工具定義)
+	// 系統提示詞在每輪對話中都是相同的前綴，標記為可快取，
+	// 讓支援的提供者可以重複使用已計算的 Prompt Cache
 	messages = append(messages, providers.Message{
-		Role:    "system",
-		Content: systemPrompt,
+		Role:         "system",
+		Content:      systemPrompt,
+		CacheControl: true,
 	})
 
 	// 加入之前的對話歷史
@@ -153,6 +161,13 @@ func (a *AgentInstance) Run(
 			return fmt.Errorf("llm chat provider error: %w", err)
 		}
 
+		// 記錄 Token 使用量 (包含命中 Prompt Cache 的部分)
+		logger.Debug("LLM usage",
+			"prompt", response.Usage.PromptTokens,
+			"completion", response.Usage.CompletionTokens,
+			"cached", response.Usage.CachedTokens,
+		)
+
 		// 將 LLM 的原始回覆加入到對話歷史中
 		// 這樣 LLM 就能「記住」自己說了什麼或呼叫了哪些工具
 		// 注意：OpenAI API 要求精確地將 tool_calls 回顯到對話歷史中
@@ -179,11 +194,12 @@ func (a *AgentInstance) Run(
 		// 如果 LLM 請求執行工具，逐一處理每個工具呼叫
 		if len(response.ToolCalls) > 0 {
 			// 遍歷所有工具呼叫
-			for _, call := range response.ToolCalls {
+			assistantIdx := len(messages) - 1
+			for i, call := range response.ToolCalls {
 				// 解析工具參數
 				// LLM 傳來的參數是 JSON 格式的字符串，需要反序列化為 map
-				var args map[string]any
-				if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+				args, err := a.parseToolArgs(&call)
+				if err != nil {
 					// 如果解析失敗，將錯誤訊息傳回給 LLM
 					messages = append(messages, providers.Message{
 						Role:       "tool",
@@ -192,6 +208,9 @@ func (a *AgentInstance) Run(
 					})
 					continue // 繼續處理下一個工具呼叫
 				}
+				// 參數經過修復時，同步更新歷史中的 tool_calls，
+				// 避免下一輪請求把不合法的 JSON 回傳給提供者
+				messages[assistantIdx].ToolCalls[i].Function.Arguments = call.Function.Arguments
 
 				// 執行工具
 				// 透過工具註冊表查找並執行對應的工具
@@ -240,3 +259,48 @@ func (a *AgentInstance) Run(
 
 	return nil
 }
+
+// ============================================================================
+// parseToolArgs: 解析工具參數
+// ============================================================================
+// 先以標準的 json.Unmarshal 解析；失敗時執行 RepairJSON 修復常見的格式錯誤
+// (結尾逗號、單引號、未跳脫的換行、被截斷的輸出等)，再解析一次。
+// 每次需要修復都會依模型記錄到 UsageTracker，方便評估小型模型的可靠度。
+//
+// 修復成功時，call.Function.Arguments 會被替換為修復後的 JSON。
+// ============================================================================
+func (a *AgentInstance) parseToolArgs(call *providers.ToolCall) (map[string]any, error) {
+	var args map[string]any
+	err := json.Unmarshal([]byte(call.Function.Arguments), &args)
+	if err == nil {
+		return args, nil
+	}
+
+	model := a.Config.Agents.Defaults.Model
+	repaired, ok := RepairJSON(call.Function.Arguments)
+	if ok {
+		var repairedArgs map[string]any
+		if json.Unmarshal([]byte(repaired), &repairedArgs) == nil {
+			call.Function.Arguments = repaired
+			a.recordArgRepair(model, call.Function.Name, true)
+			return repairedArgs, nil
+		}
+	}
+
+	a.recordArgRepair(model, call.Function.Name, false)
+	return nil, err
+}
+
+func (a *AgentInstance) recordArgRepair(model, tool string, ok bool) {
+	if a.Usage == nil {
+		return
+	}
+	stats := a.Usage.RecordArgRepair(model, ok)
+	logger.Warn("Malformed tool arguments",
+		"model", model,
+		"tool", tool,
+		"repaired", ok,
+		"repaired_total", stats.Repaired,
+		"failed_total", stats.Failed,
+	)
+}
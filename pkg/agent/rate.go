@@ -81,12 +81,21 @@ type UsageTracker struct {
 	toolCalls    map[string]int
 	totalCalls   int
 	sessionCalls map[string]int
+	argRepairs   map[string]ArgRepairStats
+}
+
+// ArgRepairStats counts, per model, how often tool-call arguments were not valid JSON
+// and whether the repair pass could recover them.
+type ArgRepairStats struct {
+	Repaired int
+	Failed   int
 }
 
 func NewUsageTracker() *UsageTracker {
 	return &UsageTracker{
 		toolCalls:    make(map[string]int),
 		sessionCalls: make(map[string]int),
+		argRepairs:   make(map[string]ArgRepairStats),
 	}
 }
 
@@ -115,3 +124,30 @@ func (ut *UsageTracker) GetStats() (total int, toolStats map[string]int, session
 
 	return ut.totalCalls, toolStats, sessionStats
 }
+
+// RecordArgRepair records that a model produced malformed tool arguments.
+// ok reports whether RepairJSON managed to fix them.
+func (ut *UsageTracker) RecordArgRepair(model string, ok bool) ArgRepairStats {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	stats := ut.argRepairs[model]
+	if ok {
+		stats.Repaired++
+	} else {
+		stats.Failed++
+	}
+	ut.argRepairs[model] = stats
+	return stats
+}
+
+func (ut *UsageTracker) GetArgRepairStats() map[string]ArgRepairStats {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	stats := make(map[string]ArgRepairStats, len(ut.argRepairs))
+	for k, v := range ut.argRepairs {
+		stats[k] = v
+	}
+	return stats
+}
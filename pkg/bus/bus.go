@@ -5,12 +5,14 @@ import (
 
 	"github.com/chiisen/mini_bot/pkg/agent"
 	"github.com/chiisen/mini_bot/pkg/logger"
+	"github.com/chiisen/mini_bot/pkg/tools"
 )
 
 // InboundMessage represents a standardized message arriving from any channel.
 type InboundMessage struct {
 	Channel    string // "telegram" | "cli"
 	ChatID     string // Used for routing reply back
+	UserID     string // Sender, used by tool permission policies
 	Content    string // Message content
 	SessionKey string // Session key for conversation context
 	ReplyChan  chan string // Optional: channel to send synchronous replies directly back
@@ -41,8 +43,9 @@ func (b *MessageBus) Start(ctx context.Context) {
 				logger.Info("MessageBus shutting down")
 				return
 			case msg := <-b.inbound:
-				// Process the message via Agent loop
-				err := b.agent.Run(ctx, msg.SessionKey, msg.Content, func(reply string) {
+				// Process the message via Agent loop; the caller decides which tools it may use
+				runCtx := tools.WithCaller(ctx, tools.Caller{Channel: msg.Channel, ChatID: msg.ChatID, UserID: msg.UserID})
+				err := b.agent.Run(runCtx, msg.SessionKey, msg.Content, func(reply string) {
 					// Route the reply back to the appropriate channel
 					if msg.ReplyChan != nil {
 						msg.ReplyChan <- reply
@@ -0,0 +1,62 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chiisen/mini_bot/pkg/config"
+)
+
+// OutboundChannel can deliver a message to a chat without a running listener.
+type OutboundChannel interface {
+	SendMessage(ctx context.Context, chatID string, text string) error
+}
+
+// Sender routes outbound messages to channels by name. It implements
+// tools.MessageSender for the send_message tool.
+type Sender struct {
+	channels map[string]OutboundChannel
+}
+
+// NewSender builds a sender for every channel that has credentials configured.
+// Sending only needs the bot token, so it also works from the CLI when the
+// gateway is not running.
+func NewSender(cfg *config.ChannelsConfig) *Sender {
+	s := &Sender{channels: make(map[string]OutboundChannel)}
+	if token := cfg.Telegram.Token; token != "" && token != "YOUR_BOT_TOKEN_HERE" {
+		s.Register("telegram", &TelegramChannel{
+			Token:  token,
+			client: &http.Client{Timeout: 30 * time.Second},
+		})
+	}
+	return s
+}
+
+// Register adds or replaces the channel used for name.
+func (s *Sender) Register(name string, ch OutboundChannel) {
+	s.channels[name] = ch
+}
+
+func (s *Sender) SendMessage(ctx context.Context, channel, chatID, text string) error {
+	ch, ok := s.channels[channel]
+	if !ok {
+		return fmt.Errorf("channel %q is not configured (available: %s)", channel, s.names())
+	}
+	return ch.SendMessage(ctx, chatID, text)
+}
+
+func (s *Sender) names() string {
+	if len(s.channels) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(s.channels))
+	for name := range s.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -87,6 +87,7 @@ func (t *TelegramChannel) Start(ctx context.Context) error {
 			t.Bus.Send(bus.InboundMessage{
 				Channel:    "telegram",
 				ChatID:     chatIDStr,
+				UserID:     userIDStr,
 				Content:    update.Message.Text,
 				SessionKey: sessionKey,
 				ReplyChan:  replyChan,
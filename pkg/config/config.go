@@ -15,6 +15,7 @@ type Config struct {
 	Agents    AgentsConfig           `json:"agents"`
 	Providers map[string]ModelConfig `json:"providers"`
 	Channels  ChannelsConfig         `json:"channels"`
+	Tools     ToolsConfig            `json:"tools"`
 	Language  string                 `json:"language"`
 }
 
@@ -30,11 +31,33 @@ type AgentDefaults struct {
 	MaxToolIterations   int     `json:"maxToolIterations"`
 	MemoryWindow        int     `json:"memoryWindow"`
 	RestrictToWorkspace bool    `json:"restrictToWorkspace"`
+	// Roots grants file tools access to extra directories, addressed as "name:path".
+	Roots map[string]SandboxRootConfig `json:"roots,omitempty"`
+	// Quota limits what file tools may write into the workspace.
+	Quota QuotaConfig `json:"quota,omitempty"`
+}
+
+// QuotaConfig limits the size of a single written file (default 20 MB, applies
+// to every root), the total size of the workspace (default 1024 MB) and its
+// number of files (default 20000). 0 uses the default and -1 disables a limit.
+type QuotaConfig struct {
+	MaxFileMB  int `json:"maxFileMB,omitempty"`
+	MaxTotalMB int `json:"maxTotalMB,omitempty"`
+	MaxFiles   int `json:"maxFiles,omitempty"`
+}
+
+// SandboxRootConfig is an extra directory file tools may access. Mode is "ro"
+// (default, read-only) or "rw" (read-write).
+type SandboxRootConfig struct {
+	Path string `json:"path"`
+	Mode string `json:"mode,omitempty"`
 }
 
 type ModelConfig struct {
 	APIKey  string `json:"apiKey"`
 	APIBase string `json:"apiBase,omitempty"`
+	// PromptCache sends explicit cache-control breakpoints for the stable system prompt.
+	PromptCache bool `json:"promptCache,omitempty"`
 
 	// internal fields
 	Vendor string `json:"-"`
@@ -51,6 +74,149 @@ type TelegramConfig struct {
 	AllowFrom []string `json:"allow_from"`
 }
 
+type ToolsConfig struct {
+	// MCPServers declares external Model Context Protocol servers keyed by a short name.
+	// Their tools are registered as "<name>_<tool>".
+	MCPServers map[string]MCPServerConfig `json:"mcpServers,omitempty"`
+	// Search configures the backends used by the web_search tool.
+	Search SearchConfig `json:"search,omitempty"`
+	// Exec adds or overrides the programs the exec tool may run.
+	Exec ExecConfig `json:"exec,omitempty"`
+	// Output limits the size of a single tool result.
+	Output OutputConfig `json:"output,omitempty"`
+	// Custom configures declarative command and HTTP tools.
+	Custom CustomToolsConfig `json:"custom,omitempty"`
+	// Plugins configures out-of-process tool plugins.
+	Plugins PluginsConfig `json:"plugins,omitempty"`
+	// Permissions restricts the tools available to each channel, chat and user.
+	Permissions []ToolPermissionConfig `json:"permissions,omitempty"`
+	// SendMessage lists the destinations the send_message tool may contact.
+	SendMessage SendMessageConfig `json:"sendMessage,omitempty"`
+}
+
+// SendMessageConfig enables the send_message tool, which lets the agent post to
+// the named destinations in Targets, e.g. {"me": {"channel": "telegram",
+// "chatId": "123456789"}}. The agent only sees the names; other chats cannot be
+// contacted. The tool is not registered when Targets is empty.
+type SendMessageConfig struct {
+	Targets map[string]MessageTargetConfig `json:"targets,omitempty"`
+}
+
+// MessageTargetConfig is one send_message destination. Description tells the
+// model when to use it.
+type MessageTargetConfig struct {
+	Channel     string `json:"channel"`
+	ChatID      string `json:"chatId"`
+	Description string `json:"description,omitempty"`
+}
+
+// ToolPermissionConfig is one tool permission rule. Rules are checked in order
+// and the first one whose channel, chatId and userId all match the caller
+// applies; empty fields match anyone and values may use * and ? wildcards.
+// When no rule matches, every tool is available.
+//
+// Allow lists the tool names the caller may use (empty allows all) and Deny
+// removes names from that set; both accept wildcards such as "issues_*".
+// Args restricts argument values per tool, e.g. {"exec": {"command": ["git status"]}}.
+// The exec command rule must match every pipeline stage, and also applies to
+// process_start and custom command tools.
+type ToolPermissionConfig struct {
+	Channel string                         `json:"channel,omitempty"`
+	ChatID  string                         `json:"chatId,omitempty"`
+	UserID  string                         `json:"userId,omitempty"`
+	Allow   []string                       `json:"allow,omitempty"`
+	Deny    []string                       `json:"deny,omitempty"`
+	Args    map[string]map[string][]string `json:"args,omitempty"`
+}
+
+// CustomToolsConfig locates declarative tool manifests: every *.json file in
+// Dir (default ~/.minibot.go/tools) defines one tool. Dir is skipped when file
+// tools can write it, i.e. inside the workspace or a read-write root, unless it
+// is also configured as a read-only root. HTTP headers may only reference the
+// environment variables listed in Env, e.g. ["WEATHER_TOKEN"].
+type CustomToolsConfig struct {
+	Dir string   `json:"dir,omitempty"`
+	Env []string `json:"env,omitempty"`
+}
+
+// PluginsConfig locates tool plugins: every executable file in Dir (default
+// ~/.minibot.go/plugins) is asked to describe its tools at startup. Values in
+// Env may reference environment variables as ${VAR}.
+type PluginsConfig struct {
+	Dir      string            `json:"dir,omitempty"`
+	Timeout  int               `json:"timeout,omitempty"` // seconds per call, default 30
+	Env      map[string]string `json:"env,omitempty"`
+	Disabled []string          `json:"disabled,omitempty"` // plugin file names to skip
+}
+
+// OutputConfig limits tool results sent to the model. Results longer than
+// MaxTokens (default 8000, -1 disables) keep only their head and tail; the full
+// output is saved to workspace/.tool-output/ so it can be paged with read_file.
+type OutputConfig struct {
+	MaxTokens int `json:"maxTokens,omitempty"`
+}
+
+// ExecConfig adds or overrides exec tool rules per program, on top of the
+// built-in read-only defaults (ls, cat, grep, find, ...). Programs that are not
+// listed in either are rejected. A rule with deny ["*"] removes a default program.
+type ExecConfig struct {
+	Commands map[string]ExecCommandRule `json:"commands,omitempty"`
+	// Sandbox limits what allowed commands can do once they run.
+	Sandbox ExecSandboxConfig `json:"sandbox,omitempty"`
+	// MaxBackgroundProcesses limits how many process_start commands may run at
+	// once in each conversation. Defaults to 4.
+	MaxBackgroundProcesses int `json:"maxBackgroundProcesses,omitempty"`
+}
+
+// ExecSandboxConfig sets OS-level limits for exec child processes. Children
+// always get a scrubbed environment (no API keys from .env) and run in their
+// own process group, which is killed on timeout. Resource limits and
+// namespaces apply on Linux only. Zero values use the defaults (60s CPU,
+// 2048 MB memory, 256 MB files, 512 processes); -1 disables a limit.
+type ExecSandboxConfig struct {
+	CPUSeconds   int `json:"cpuSeconds,omitempty"`
+	MemoryMB     int `json:"memoryMB,omitempty"`
+	FileSizeMB   int `json:"fileSizeMB,omitempty"`
+	MaxProcesses int `json:"maxProcesses,omitempty"`
+	// Namespaces runs commands in new user, mount and network namespaces so the
+	// workspace is the only writable path and there is no network access.
+	Namespaces   bool     `json:"namespaces,omitempty"`
+	AllowNetwork bool     `json:"allowNetwork,omitempty"`
+	PassEnv      []string `json:"passEnv,omitempty"` // extra environment variables to pass through
+}
+
+// ExecCommandRule restricts the arguments of one program. Allow lists argument
+// prefixes such as "status" or "log -n *"; when empty any arguments are allowed.
+// Deny lists words that must not appear, in order, anywhere in the arguments,
+// such as "push" or "reset --hard". Words may use * and ? wildcards.
+type ExecCommandRule struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// SearchConfig selects web_search backends. Engines are tried in order and the
+// next one is used when a backend fails. Defaults to ["duckduckgo"].
+type SearchConfig struct {
+	Engines      []string `json:"engines,omitempty"` // duckduckgo, searxng, brave, tavily
+	MaxResults   int      `json:"maxResults,omitempty"`
+	SearXNGURL   string   `json:"searxngUrl,omitempty"`
+	BraveAPIKey  string   `json:"braveApiKey,omitempty"`
+	TavilyAPIKey string   `json:"tavilyApiKey,omitempty"`
+}
+
+// MCPServerConfig describes how to reach one MCP server. Set Command for a stdio
+// server or URL for a streamable HTTP server. Values in Env and Headers may
+// reference environment variables as ${VAR} so secrets can stay in .env.
+type MCPServerConfig struct {
+	Command  string            `json:"command,omitempty"`
+	Args     []string          `json:"args,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Timeout  int               `json:"timeout,omitempty"` // seconds per request, default 30
+	Disabled bool              `json:"disabled,omitempty"`
+}
+
 // Load loads the configuration with a 3-tier priority: Default -> JSON -> Env
 func Load(configPath string) (*Config, error) {
 	cfg := &Config{}
@@ -94,8 +260,14 @@ func Load(configPath string) (*Config, error) {
 	// 3. Apply Environment Variable overrides
 	applyEnvOverrides(cfg)
 
-	// Expand workspace path
+	// Expand workspace, root, plugin and custom tool paths
 	cfg.Agents.Defaults.Workspace = expandHome(cfg.Agents.Defaults.Workspace)
+	cfg.Tools.Plugins.Dir = expandHome(cfg.Tools.Plugins.Dir)
+	cfg.Tools.Custom.Dir = expandHome(cfg.Tools.Custom.Dir)
+	for name, root := range cfg.Agents.Defaults.Roots {
+		root.Path = expandHome(root.Path)
+		cfg.Agents.Defaults.Roots[name] = root
+	}
 
 	// Check workspace directory isolation
 	_ = checkWorkspaceIsolation(cfg.Agents.Defaults.Workspace)
@@ -111,6 +283,8 @@ func applyDefaults(cfg *Config) {
 	cfg.Agents.Defaults.MaxToolIterations = DefaultMaxToolIterations
 	cfg.Agents.Defaults.RestrictToWorkspace = DefaultRestrictToWS
 	cfg.Language = DefaultLanguage
+	cfg.Tools.Plugins.Dir = DefaultPluginDir
+	cfg.Tools.Custom.Dir = DefaultCustomToolDir
 }
 
 func loadEnvFile() {
@@ -180,6 +354,19 @@ func applyEnvOverrides(cfg *Config) {
 		}
 	}
 
+	if v := os.Getenv("MINIBOT_TOOLS_SEARCH_ENGINES"); v != "" {
+		cfg.Tools.Search.Engines = strings.Split(v, ",")
+	}
+	if v := os.Getenv("MINIBOT_TOOLS_SEARCH_SEARXNG_URL"); v != "" {
+		cfg.Tools.Search.SearXNGURL = v
+	}
+	if v := os.Getenv("MINIBOT_TOOLS_SEARCH_BRAVE_API_KEY"); v != "" {
+		cfg.Tools.Search.BraveAPIKey = v
+	}
+	if v := os.Getenv("MINIBOT_TOOLS_SEARCH_TAVILY_API_KEY"); v != "" {
+		cfg.Tools.Search.TavilyAPIKey = v
+	}
+
 	if v := os.Getenv("MINIBOT_CHANNELS_TELEGRAM_BOT_TOKEN"); v != "" {
 		cfg.Channels.Telegram.Enabled = true
 		cfg.Channels.Telegram.Token = v
@@ -220,7 +407,7 @@ func expandHome(path string) string {
 
 func warnIfSensitiveDataPresent(data []byte) {
 	content := string(data)
-	sensitiveKeys := []string{"apiKey", "botToken", "APikey", "token"}
+	sensitiveKeys := []string{"apiKey", "botToken", "APikey", "token", "braveApiKey", "tavilyApiKey"}
 
 	for _, key := range sensitiveKeys {
 		if idx := strings.Index(content, `"`+key+`"`); idx != -1 {
@@ -10,4 +10,6 @@ const (
 	DefaultConfigDir         = "~/.minibot.go"
 	DefaultConfigFile        = "~/.minibot.go/config.json"
 	DefaultLanguage          = "en"
+	DefaultPluginDir         = "~/.minibot.go/plugins"
+	DefaultCustomToolDir     = "~/.minibot.go/tools"
 )
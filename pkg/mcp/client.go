@@ -0,0 +1,173 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chiisen/mini_bot/pkg/config"
+)
+
+// DefaultTimeout applies to each request when MCPServerConfig.Timeout is unset.
+const DefaultTimeout = 30 * time.Second
+
+// Client is a connection to one MCP server.
+type Client struct {
+	Name       string
+	ServerInfo Implementation
+	Timeout    time.Duration
+	transport  transport
+}
+
+// Implementation identifies an MCP client or server.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// ToolInfo is a tool advertised by a server in tools/list.
+type ToolInfo struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// Content is one item of a tool result.
+type Content struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	MimeType string    `json:"mimeType,omitempty"`
+	Data     string    `json:"data,omitempty"`
+	Resource *Resource `json:"resource,omitempty"`
+}
+
+// Resource is an embedded resource inside tool result content.
+type Resource struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// CallToolResult is the result of tools/call.
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Connect starts (stdio) or contacts (HTTP) the server and performs the
+// initialize handshake.
+func Connect(ctx context.Context, name string, cfg config.MCPServerConfig) (*Client, error) {
+	var t transport
+	switch {
+	case cfg.URL != "":
+		t = newHTTPTransport(name, cfg.URL, cfg.Headers)
+	case cfg.Command != "":
+		st, err := newStdioTransport(name, cfg.Command, cfg.Args, cfg.Env)
+		if err != nil {
+			return nil, err
+		}
+		t = st
+	default:
+		return nil, fmt.Errorf("MCP server %s needs either a command or a url", name)
+	}
+
+	timeout := DefaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	c := &Client{Name: name, Timeout: timeout, transport: t}
+	if err := c.initialize(ctx); err != nil {
+		_ = t.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	raw, err := c.transport.Call(ctx, "initialize", map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      Implementation{Name: "minibot", Version: "0.1.0"},
+	})
+	if err != nil {
+		return fmt.Errorf("MCP server %s initialize failed: %w", c.Name, err)
+	}
+
+	var result struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		ServerInfo      Implementation `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return fmt.Errorf("MCP server %s sent an invalid initialize result: %w", c.Name, err)
+	}
+	c.ServerInfo = result.ServerInfo
+
+	return c.transport.Notify(ctx, "notifications/initialized", nil)
+}
+
+// ListTools returns every tool the server offers, following pagination cursors.
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	var all []ToolInfo
+	cursor := ""
+	for {
+		var params map[string]any
+		if cursor != "" {
+			params = map[string]any{"cursor": cursor}
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+		raw, err := c.transport.Call(callCtx, "tools/list", params)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("MCP server %s tools/list failed: %w", c.Name, err)
+		}
+
+		var page struct {
+			Tools      []ToolInfo `json:"tools"`
+			NextCursor string     `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return nil, fmt.Errorf("MCP server %s sent an invalid tools/list result: %w", c.Name, err)
+		}
+		all = append(all, page.Tools...)
+
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return all, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a tool on the server.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*CallToolResult, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	raw, err := c.transport.Call(ctx, "tools/call", map[string]any{
+		"name":      name,
+		"arguments": args,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result CallToolResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("invalid tools/call result: %w", err)
+	}
+	return &result, nil
+}
+
+// Close shuts the connection down.
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
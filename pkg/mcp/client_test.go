@@ -0,0 +1,270 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/chiisen/mini_bot/pkg/config"
+	"github.com/chiisen/mini_bot/pkg/tools"
+)
+
+// TestMain lets the test binary double as a fake stdio MCP server.
+func TestMain(m *testing.M) {
+	if os.Getenv("MINIBOT_FAKE_MCP_SERVER") == "1" {
+		runFakeStdioServer(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+var fakeTools = []ToolInfo{
+	{
+		Name:        "echo",
+		Description: "Echo the message back",
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"message": map[string]any{"type": "string"}},
+			"required":   []any{"message"},
+		},
+	},
+	{Name: "fail", Description: "Always fails"},
+}
+
+// handleFake answers one request the way a minimal MCP server would.
+func handleFake(msg *message) any {
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "fake", "version": "1.0"},
+		}
+	case "tools/list":
+		var params struct {
+			Cursor string `json:"cursor"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		// paginate: one tool per page
+		if params.Cursor == "" {
+			return map[string]any{"tools": fakeTools[:1], "nextCursor": "page2"}
+		}
+		return map[string]any{"tools": fakeTools[1:]}
+	case "tools/call":
+		var params struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		if params.Name == "fail" {
+			return map[string]any{"content": []any{map[string]any{"type": "text", "text": "it broke"}}, "isError": true}
+		}
+		return map[string]any{"content": []any{map[string]any{"type": "text", "text": fmt.Sprint(params.Arguments["message"])}}}
+	}
+	return nil
+}
+
+func runFakeStdioServer(in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		var msg message
+		if json.Unmarshal(scanner.Bytes(), &msg) != nil || !msg.isRequest() {
+			continue
+		}
+		data, _ := newResponse(msg.ID, handleFake(&msg), nil)
+		fmt.Fprintf(out, "%s\n", data)
+	}
+}
+
+func checkClient(t *testing.T, client *Client) {
+	t.Helper()
+	ctx := context.Background()
+
+	if client.ServerInfo.Name != "fake" {
+		t.Errorf("expected server name fake, got %q", client.ServerInfo.Name)
+	}
+
+	list, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 tools across pages, got %d", len(list))
+	}
+
+	registry := tools.NewRegistry()
+	for _, info := range list {
+		registry.Register(NewProxyTool(client, info))
+	}
+
+	defs := registry.Definitions()
+	if defs[0].Function.Name != "fake_echo" || defs[1].Function.Name != "fake_fail" {
+		t.Errorf("unexpected tool names: %s, %s", defs[0].Function.Name, defs[1].Function.Name)
+	}
+
+	result := registry.Execute(ctx, "fake_echo", map[string]any{"message": "hello"})
+	if result.IsError || result.ForLLM != "hello" {
+		t.Errorf("expected echo result, got %+v", result)
+	}
+
+	// schema from the server is enforced locally
+	result = registry.Execute(ctx, "fake_echo", map[string]any{})
+	if !result.IsError || !strings.Contains(result.ForLLM, "message") {
+		t.Errorf("expected validation error, got %+v", result)
+	}
+
+	result = registry.Execute(ctx, "fake_fail", map[string]any{})
+	if !result.IsError || result.ForLLM != "it broke" {
+		t.Errorf("expected tool error to be passed through, got %+v", result)
+	}
+}
+
+func TestClient_Stdio(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := Connect(context.Background(), "fake", config.MCPServerConfig{
+		Command: exe,
+		Env:     map[string]string{"MINIBOT_FAKE_MCP_SERVER": "1"},
+	})
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer client.Close()
+
+	checkClient(t, client)
+}
+
+func newFakeHTTPServer(t *testing.T, sse bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			return
+		}
+
+		var msg message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if msg.Method == "initialize" {
+			w.Header().Set("Mcp-Session-Id", "session-1")
+		} else if r.Header.Get("Mcp-Session-Id") != "session-1" {
+			http.Error(w, "missing session", http.StatusBadRequest)
+			return
+		}
+		if !msg.isRequest() {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		data, _ := newResponse(msg.ID, handleFake(&msg), nil)
+		if sse {
+			w.Header().Set("Content-Type", "text/event-stream")
+			// an unrelated notification first, then the response
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+}
+
+func TestClient_HTTP(t *testing.T) {
+	for _, sse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("sse=%v", sse), func(t *testing.T) {
+			srv := newFakeHTTPServer(t, sse)
+			defer srv.Close()
+
+			t.Setenv("FAKE_MCP_TOKEN", "secret")
+			client, err := Connect(context.Background(), "fake", config.MCPServerConfig{
+				URL:     srv.URL,
+				Headers: map[string]string{"Authorization": "Bearer ${FAKE_MCP_TOKEN}"},
+			})
+			if err != nil {
+				t.Fatalf("Connect failed: %v", err)
+			}
+			defer client.Close()
+
+			checkClient(t, client)
+		})
+	}
+}
+
+func TestRegisterServers_SkipsBrokenServers(t *testing.T) {
+	registry := tools.NewRegistry()
+	clients := RegisterServers(context.Background(), map[string]config.MCPServerConfig{
+		"missing":  {Command: "/nonexistent/mcp-server"},
+		"empty":    {},
+		"disabled": {Command: "/nonexistent/mcp-server", Disabled: true},
+	}, registry)
+
+	if len(clients) != 0 {
+		t.Errorf("expected no clients, got %d", len(clients))
+	}
+	if len(registry.Definitions()) != 0 {
+		t.Errorf("expected no tools to be registered")
+	}
+}
+
+func TestToolName(t *testing.T) {
+	if got := toolName("issue tracker", "create.issue"); got != "issue_tracker_create_issue" {
+		t.Errorf("unexpected name %q", got)
+	}
+	if got := toolName(strings.Repeat("s", 40), strings.Repeat("t", 40)); len(got) != 64 {
+		t.Errorf("expected name to be truncated to 64 chars, got %d", len(got))
+	}
+	long := strings.Repeat("t", 70)
+	if a, b := toolName("srv", long+"_a"), toolName("srv", long+"_b"); a == b {
+		t.Errorf("truncated names must stay distinct, both are %q", a)
+	}
+}
+
+// builtinTool stands in for a built-in tool an MCP server tries to shadow.
+type builtinTool struct{ name string }
+
+func (b builtinTool) Name() string               { return b.name }
+func (b builtinTool) Description() string        { return "built-in" }
+func (b builtinTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+func (b builtinTool) Execute(ctx context.Context, args map[string]any) *tools.ToolResult {
+	return &tools.ToolResult{ForLLM: "built-in"}
+}
+
+func TestRegisterServers_DoesNotReplaceRegisteredTools(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := tools.NewRegistry()
+	registry.Register(builtinTool{name: "fake_echo"})
+
+	clients := RegisterServers(context.Background(), map[string]config.MCPServerConfig{
+		"fake": {Command: exe, Env: map[string]string{"MINIBOT_FAKE_MCP_SERVER": "1"}},
+	}, registry)
+	for _, c := range clients {
+		defer c.Close()
+	}
+	if len(clients) != 1 {
+		t.Fatalf("expected one client, got %d", len(clients))
+	}
+
+	if res := registry.Execute(context.Background(), "fake_echo", map[string]any{"message": "hi"}); res.ForLLM != "built-in" {
+		t.Errorf("the built-in tool was replaced: %+v", res)
+	}
+	if !registry.Has("fake_fail") {
+		t.Error("tools without a collision should still be registered")
+	}
+}
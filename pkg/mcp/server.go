@@ -0,0 +1,208 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/chiisen/mini_bot/pkg/logger"
+	"github.com/chiisen/mini_bot/pkg/tools"
+)
+
+// supportedVersions are the protocol revisions the server accepts from clients.
+var supportedVersions = map[string]bool{
+	"2024-11-05":    true,
+	ProtocolVersion: true,
+	"2025-06-18":    true,
+}
+
+// Server exposes a ToolRegistry to MCP clients.
+type Server struct {
+	Registry *tools.ToolRegistry
+	Info     Implementation
+
+	writeMu sync.Mutex
+	out     io.Writer
+
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+}
+
+func NewServer(registry *tools.ToolRegistry, info Implementation) *Server {
+	return &Server{
+		Registry: registry,
+		Info:     info,
+		inflight: make(map[string]context.CancelFunc),
+	}
+}
+
+// ServeStdio reads newline-delimited JSON-RPC from in and writes responses to out
+// until in is closed or ctx is cancelled. Tool calls run concurrently so a slow
+// command doesn't block pings or other calls.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.out = out
+	reader := bufio.NewReaderSize(in, 64*1024)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	lines := make(chan []byte)
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			line, err := readLine(reader)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.cancelAll()
+			return nil
+		case err := <-errCh:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case line := <-lines:
+			var msg message
+			if err := json.Unmarshal(line, &msg); err != nil {
+				s.reply(json.RawMessage("null"), nil, &RPCError{Code: codeParseError, Message: err.Error()})
+				continue
+			}
+			switch {
+			case msg.isRequest():
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					s.handleRequest(ctx, &msg)
+				}()
+			case msg.isNotification():
+				s.handleNotification(&msg)
+			}
+		}
+	}
+}
+
+func (s *Server) handleRequest(ctx context.Context, msg *message) {
+	ctx, cancel := context.WithCancel(ctx)
+	key := string(msg.ID)
+	s.mu.Lock()
+	s.inflight[key] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.inflight, key)
+		s.mu.Unlock()
+		cancel()
+	}()
+
+	result, rpcErr := s.dispatch(ctx, msg)
+	s.reply(msg.ID, result, rpcErr)
+}
+
+func (s *Server) dispatch(ctx context.Context, msg *message) (any, *RPCError) {
+	switch msg.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string         `json:"protocolVersion"`
+			ClientInfo      Implementation `json:"clientInfo"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		version := ProtocolVersion
+		if supportedVersions[params.ProtocolVersion] {
+			version = params.ProtocolVersion
+		}
+		logger.Info("MCP client connected", "client", params.ClientInfo.Name, "version", version)
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+			"serverInfo":      s.Info,
+		}, nil
+
+	case "ping":
+		return map[string]any{}, nil
+
+	case "tools/list":
+		defs := s.Registry.Definitions()
+		list := make([]ToolInfo, 0, len(defs))
+		for _, d := range defs {
+			list = append(list, ToolInfo{
+				Name:        d.Function.Name,
+				Description: d.Function.Description,
+				InputSchema: d.Function.Parameters,
+			})
+		}
+		return map[string]any{"tools": list}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil || params.Name == "" {
+			return nil, &RPCError{Code: codeInvalidParams, Message: "tools/call requires a tool name"}
+		}
+		if !s.Registry.Has(params.Name) {
+			return nil, &RPCError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", params.Name)}
+		}
+		// Argument validation, sandboxing and panic recovery all happen inside the registry,
+		// exactly as they do for the agent's own tool calls.
+		res := s.Registry.Execute(ctx, params.Name, params.Arguments)
+		return CallToolResult{
+			Content: []Content{{Type: "text", Text: res.ForLLM}},
+			IsError: res.IsError,
+		}, nil
+	}
+
+	return nil, &RPCError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+func (s *Server) handleNotification(msg *message) {
+	if msg.Method != "notifications/cancelled" {
+		return
+	}
+	var params struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if json.Unmarshal(msg.Params, &params) != nil {
+		return
+	}
+	s.mu.Lock()
+	if cancel, ok := s.inflight[string(params.RequestID)]; ok {
+		cancel()
+	}
+	s.mu.Unlock()
+}
+
+func (s *Server) cancelAll() {
+	s.mu.Lock()
+	for _, cancel := range s.inflight {
+		cancel()
+	}
+	s.mu.Unlock()
+}
+
+func (s *Server) reply(id json.RawMessage, result any, rpcErr *RPCError) {
+	data, err := newResponse(id, result, rpcErr)
+	if err != nil {
+		data, _ = newResponse(id, nil, &RPCError{Code: codeInternalError, Message: err.Error()})
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := s.out.Write(append(data, '\n')); err != nil {
+		logger.Error("Failed to write MCP response", "error", err)
+	}
+}
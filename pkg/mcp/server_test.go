@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chiisen/mini_bot/pkg/tools"
+)
+
+// serve runs the server over the given request lines and returns responses keyed by id.
+func serve(t *testing.T, registry *tools.ToolRegistry, requests ...string) map[int64]*message {
+	t.Helper()
+	in := strings.NewReader(strings.Join(requests, "\n") + "\n")
+	var out bytes.Buffer
+
+	server := NewServer(registry, Implementation{Name: "MiniBot.go", Version: "test"})
+	if err := server.ServeStdio(context.Background(), in, &out); err != nil {
+		t.Fatalf("ServeStdio failed: %v", err)
+	}
+
+	responses := make(map[int64]*message)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var msg message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("invalid response line %q: %v", line, err)
+		}
+		id, _ := parseID(msg.ID)
+		responses[id] = &msg
+	}
+	return responses
+}
+
+func TestServer_ToolsOverStdio(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "note.txt"), []byte("hello mcp"), 0644)
+	sandbox, _ := tools.NewSandbox(tmpDir)
+
+	registry := tools.NewRegistry()
+	registry.Register(&tools.ReadFileTool{Sandbox: sandbox})
+
+	responses := serve(t, registry,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","clientInfo":{"name":"editor"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"read_file","arguments":{"path":"note.txt"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"read_file","arguments":{"path":"../../etc/passwd"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"nope","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"resources/list"}`,
+	)
+
+	var init struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		ServerInfo      Implementation `json:"serverInfo"`
+	}
+	json.Unmarshal(responses[1].Result, &init)
+	if init.ProtocolVersion != "2024-11-05" {
+		t.Errorf("expected negotiated version 2024-11-05, got %s", init.ProtocolVersion)
+	}
+	if init.ServerInfo.Name != "MiniBot.go" {
+		t.Errorf("unexpected server info %+v", init.ServerInfo)
+	}
+
+	var list struct {
+		Tools []ToolInfo `json:"tools"`
+	}
+	json.Unmarshal(responses[2].Result, &list)
+	if len(list.Tools) != 1 || list.Tools[0].Name != "read_file" || list.Tools[0].InputSchema["type"] != "object" {
+		t.Errorf("unexpected tools/list result: %s", responses[2].Result)
+	}
+
+	var call CallToolResult
+	json.Unmarshal(responses[3].Result, &call)
+	if call.IsError || !strings.Contains(call.Content[0].Text, "hello mcp") {
+		t.Errorf("unexpected tools/call result: %s", responses[3].Result)
+	}
+
+	var escaped CallToolResult
+	json.Unmarshal(responses[4].Result, &escaped)
+	if !escaped.IsError {
+		t.Errorf("expected sandbox to reject path outside workspace, got %s", responses[4].Result)
+	}
+
+	if responses[5].Error == nil || responses[5].Error.Code != codeInvalidParams {
+		t.Errorf("expected invalid params error for unknown tool, got %+v", responses[5])
+	}
+	if responses[6].Error == nil || responses[6].Error.Code != codeMethodNotFound {
+		t.Errorf("expected method not found, got %+v", responses[6])
+	}
+}
@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/chiisen/mini_bot/pkg/config"
+	"github.com/chiisen/mini_bot/pkg/logger"
+	"github.com/chiisen/mini_bot/pkg/tools"
+)
+
+// ProxyTool exposes one remote MCP tool as a local tools.Tool.
+type ProxyTool struct {
+	Client *Client
+	Remote ToolInfo
+	name   string
+}
+
+// NewProxyTool names the tool "<server>_<tool>" so tools from different servers can't collide.
+func NewProxyTool(c *Client, info ToolInfo) *ProxyTool {
+	return &ProxyTool{Client: c, Remote: info, name: toolName(c.Name, info.Name)}
+}
+
+// invalidNameChars matches characters OpenAI-style function names may not contain.
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// toolName builds "<server>_<tool>". Names over 64 characters keep a prefix and
+// a hash of the full name, so long names that share a prefix stay distinct.
+func toolName(server, tool string) string {
+	name := invalidNameChars.ReplaceAllString(server+"_"+tool, "_")
+	if len(name) > 64 {
+		sum := sha256.Sum256([]byte(server + "\x00" + tool))
+		name = name[:55] + "_" + hex.EncodeToString(sum[:4])
+	}
+	return name
+}
+
+func (t *ProxyTool) Name() string { return t.name }
+
+func (t *ProxyTool) Description() string {
+	desc := strings.TrimSpace(t.Remote.Description)
+	if desc == "" {
+		desc = t.Remote.Name
+	}
+	return fmt.Sprintf("[MCP %s] %s", t.Client.Name, desc)
+}
+
+func (t *ProxyTool) Parameters() map[string]any {
+	if t.Remote.InputSchema == nil {
+		return map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return t.Remote.InputSchema
+}
+
+func (t *ProxyTool) Execute(ctx context.Context, args map[string]any) *tools.ToolResult {
+	result, err := t.Client.CallTool(ctx, t.Remote.Name, args)
+	if err != nil {
+		return &tools.ToolResult{ForLLM: fmt.Sprintf("MCP tool %s failed: %v", t.name, err), IsError: true}
+	}
+	return &tools.ToolResult{ForLLM: formatContent(result.Content), IsError: result.IsError}
+}
+
+// formatContent flattens tool result content into text for the LLM.
+func formatContent(content []Content) string {
+	var parts []string
+	for _, c := range content {
+		switch c.Type {
+		case "text":
+			parts = append(parts, c.Text)
+		case "resource":
+			if c.Resource == nil {
+				continue
+			}
+			if c.Resource.Text != "" {
+				parts = append(parts, fmt.Sprintf("[resource %s]\n%s", c.Resource.URI, c.Resource.Text))
+			} else {
+				parts = append(parts, fmt.Sprintf("[resource %s (%s)]", c.Resource.URI, c.Resource.MimeType))
+			}
+		default:
+			// images and audio can't be passed through a text tool message
+			parts = append(parts, fmt.Sprintf("[%s content omitted (%s)]", c.Type, c.MimeType))
+		}
+	}
+	if len(parts) == 0 {
+		return "(no output)"
+	}
+	return strings.Join(parts, "\n")
+}
+
+// RegisterServers connects to every enabled server, registers its tools and
+// returns the live clients so the caller can close them on shutdown.
+// A server that fails to start is logged and skipped rather than aborting startup.
+func RegisterServers(ctx context.Context, servers map[string]config.MCPServerConfig, registry *tools.ToolRegistry) []*Client {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var clients []*Client
+	for _, name := range names {
+		cfg := servers[name]
+		if cfg.Disabled {
+			continue
+		}
+
+		client, err := Connect(ctx, name, cfg)
+		if err != nil {
+			logger.Warn("Skipping MCP server", "server", name, "error", err)
+			continue
+		}
+
+		remoteTools, err := client.ListTools(ctx)
+		if err != nil {
+			logger.Warn("Skipping MCP server", "server", name, "error", err)
+			_ = client.Close()
+			continue
+		}
+
+		registered := 0
+		for _, info := range remoteTools {
+			tool := NewProxyTool(client, info)
+			// never replace a built-in tool or a tool from another server
+			if registry.Has(tool.Name()) {
+				logger.Warn("Skipping MCP tool whose name is already registered", "server", name, "tool", info.Name, "name", tool.Name())
+				continue
+			}
+			registry.Register(tool)
+			registered++
+		}
+		logger.Info("Registered MCP server", "server", name, "tools", registered)
+		clients = append(clients, client)
+	}
+	return clients
+}
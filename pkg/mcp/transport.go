@@ -0,0 +1,435 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chiisen/mini_bot/pkg/logger"
+)
+
+// transport carries JSON-RPC messages to one MCP server.
+type transport interface {
+	Call(ctx context.Context, method string, params any) (json.RawMessage, error)
+	Notify(ctx context.Context, method string, params any) error
+	Close() error
+}
+
+// maxMessageSize bounds a single JSON-RPC line read from a stdio server.
+const maxMessageSize = 16 << 20
+
+// ============================================================================
+// stdio transport: newline-delimited JSON over a child process's stdin/stdout
+// ============================================================================
+
+type stdioTransport struct {
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	nextID atomic.Int64
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[int64]chan *message
+	closed  bool
+	done    chan struct{}
+	readErr error
+}
+
+func newStdioTransport(name, command string, args []string, env map[string]string) (*stdioTransport, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v))
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start MCP server %s: %w", name, err)
+	}
+
+	t := &stdioTransport{
+		name:    name,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan *message),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(stdout)
+	go t.logStderr(stderr)
+	return t, nil
+}
+
+func (t *stdioTransport) readLoop(r io.Reader) {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	var err error
+	for {
+		var line []byte
+		line, err = readLine(reader)
+		if err != nil {
+			break
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var msg message
+		if jsonErr := json.Unmarshal(line, &msg); jsonErr != nil {
+			logger.Warn("MCP server sent invalid JSON", "server", t.name, "error", jsonErr)
+			continue
+		}
+		t.dispatch(&msg)
+	}
+
+	t.mu.Lock()
+	t.readErr = err
+	for id, ch := range t.pending {
+		close(ch)
+		delete(t.pending, id)
+	}
+	t.mu.Unlock()
+	close(t.done)
+}
+
+// readLine reads one '\n'-terminated line, refusing lines over maxMessageSize.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	var buf []byte
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, chunk...)
+		if len(buf) > maxMessageSize {
+			return nil, fmt.Errorf("message exceeds %d bytes", maxMessageSize)
+		}
+		if !isPrefix {
+			return buf, nil
+		}
+	}
+}
+
+func (t *stdioTransport) dispatch(msg *message) {
+	switch {
+	case msg.isResponse():
+		id, ok := parseID(msg.ID)
+		if !ok {
+			return
+		}
+		t.mu.Lock()
+		ch, ok := t.pending[id]
+		delete(t.pending, id)
+		t.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	case msg.isRequest():
+		// Servers may ping us; every other server-to-client feature is unsupported.
+		var data []byte
+		if msg.Method == "ping" {
+			data, _ = newResponse(msg.ID, map[string]any{}, nil)
+		} else {
+			data, _ = newResponse(msg.ID, nil, &RPCError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method})
+		}
+		_ = t.write(data)
+	case msg.isNotification():
+		logger.Debug("MCP notification", "server", t.name, "method", msg.Method)
+	}
+}
+
+func (t *stdioTransport) logStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		logger.Debug("MCP server stderr", "server", t.name, "line", scanner.Text())
+	}
+}
+
+func (t *stdioTransport) write(data []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err := t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *stdioTransport) Call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	id := t.nextID.Add(1)
+	data, err := newRequest(id, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *message, 1)
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("MCP server %s is closed", t.name)
+	}
+	t.pending[id] = ch
+	t.mu.Unlock()
+
+	if err := t.write(data); err != nil {
+		t.forget(id)
+		return nil, fmt.Errorf("failed to write to MCP server %s: %w", t.name, err)
+	}
+
+	select {
+	case <-ctx.Done():
+		t.forget(id)
+		// Tell the server we gave up so it can stop working on the request.
+		_ = t.Notify(context.Background(), "notifications/cancelled", map[string]any{"requestId": id, "reason": ctx.Err().Error()})
+		return nil, ctx.Err()
+	case msg, ok := <-ch:
+		if !ok {
+			return nil, fmt.Errorf("MCP server %s exited: %v", t.name, t.readErr)
+		}
+		if msg.Error != nil {
+			return nil, msg.Error
+		}
+		return msg.Result, nil
+	}
+}
+
+func (t *stdioTransport) forget(id int64) {
+	t.mu.Lock()
+	delete(t.pending, id)
+	t.mu.Unlock()
+}
+
+func (t *stdioTransport) Notify(ctx context.Context, method string, params any) error {
+	data, err := newNotification(method, params)
+	if err != nil {
+		return err
+	}
+	return t.write(data)
+}
+
+// Close closes stdin, which is how the MCP spec asks stdio servers to exit,
+// and kills the process if it doesn't do so promptly.
+func (t *stdioTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.mu.Unlock()
+
+	_ = t.stdin.Close()
+	select {
+	case <-t.done:
+	case <-time.After(2 * time.Second):
+		_ = t.cmd.Process.Kill()
+	}
+	_ = t.cmd.Wait()
+	return nil
+}
+
+// ============================================================================
+// streamable HTTP transport: one POST per message, JSON or SSE response
+// ============================================================================
+
+type httpTransport struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+	nextID  atomic.Int64
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func newHTTPTransport(name, url string, headers map[string]string) *httpTransport {
+	expanded := make(map[string]string, len(headers))
+	for k, v := range headers {
+		expanded[k] = os.ExpandEnv(v)
+	}
+	return &httpTransport{
+		name:    name,
+		url:     url,
+		headers: expanded,
+		client:  &http.Client{},
+	}
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, t.url, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	req.Header.Set("MCP-Protocol-Version", ProtocolVersion)
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	t.mu.Unlock()
+	return req, nil
+}
+
+func (t *httpTransport) post(ctx context.Context, body []byte) (*http.Response, error) {
+	req, err := t.newRequest(ctx, http.MethodPost, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("MCP server %s request failed: %w", t.name, err)
+	}
+	if sid := resp.Header.Get("Mcp-Session-Id"); sid != "" {
+		t.mu.Lock()
+		t.sessionID = sid
+		t.mu.Unlock()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, fmt.Errorf("MCP server %s returned HTTP %d: %s", t.name, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) Call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	id := t.nextID.Add(1)
+	data, err := newRequest(id, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.post(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var msg *message
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		msg, err = readSSEResponse(resp.Body, id)
+	} else {
+		msg = &message{}
+		err = json.NewDecoder(io.LimitReader(resp.Body, maxMessageSize)).Decode(msg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("MCP server %s sent an invalid response: %w", t.name, err)
+	}
+	if msg.Error != nil {
+		return nil, msg.Error
+	}
+	return msg.Result, nil
+}
+
+// readSSEResponse reads server-sent events until the response for id arrives.
+// Requests and notifications the server interleaves on the stream are skipped.
+func readSSEResponse(r io.Reader, id int64) (*message, error) {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	var data strings.Builder
+
+	// flush parses the buffered event and reports whether it is our response.
+	flush := func() *message {
+		if data.Len() == 0 {
+			return nil
+		}
+		var msg message
+		err := json.Unmarshal([]byte(data.String()), &msg)
+		data.Reset()
+		if err != nil || !msg.isResponse() {
+			return nil
+		}
+		if got, ok := parseID(msg.ID); ok && got == id {
+			return &msg
+		}
+		return nil
+	}
+
+	for {
+		line, err := readLine(reader)
+		if err != nil {
+			if msg := flush(); msg != nil {
+				return msg, nil
+			}
+			if err == io.EOF {
+				return nil, fmt.Errorf("stream ended before response %d", id)
+			}
+			return nil, err
+		}
+		text := string(line)
+		switch {
+		case strings.HasPrefix(text, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(text, "data:"), " "))
+		case text == "":
+			if msg := flush(); msg != nil {
+				return msg, nil
+			}
+		}
+	}
+}
+
+func (t *httpTransport) Notify(ctx context.Context, method string, params any) error {
+	data, err := newNotification(method, params)
+	if err != nil {
+		return err
+	}
+	resp, err := t.post(ctx, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Close terminates the server-side session, if the server assigned one.
+func (t *httpTransport) Close() error {
+	t.mu.Lock()
+	sid := t.sessionID
+	t.mu.Unlock()
+	if sid == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := t.newRequest(ctx, http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,216 @@
+// Package plugin runs tools implemented as external executables, so tools can
+// be written in any language without changing MiniBot.
+//
+// Protocol: each message is a separate run of the plugin executable. MiniBot
+// writes one JSON request to the plugin's stdin and closes it; the plugin
+// writes one JSON response to stdout and exits. Anything written to stderr is
+// reported when the call fails.
+//
+//	-> {"type":"describe","protocolVersion":1,"supportedVersions":[1]}
+//	<- {"protocolVersion":1,"name":"pytools","version":"0.3.0",
+//	    "tools":[{"name":"csv_stats","description":"...","parameters":{...}}]}
+//
+//	-> {"type":"execute","protocolVersion":1,"tool":"csv_stats","arguments":{...},"workspace":"/home/me/ws"}
+//	<- {"output":"3 rows","isError":false}    or    {"error":"file not found"}
+//
+// The plugin picks a version from supportedVersions and echoes it in its
+// describe response; plugins that answer with an unsupported version are
+// skipped. Because every call is a fresh process, a plugin that crashes, hangs
+// or prints garbage only fails that one call.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ProtocolVersion is the newest protocol version this host speaks.
+const ProtocolVersion = 1
+
+// SupportedVersions lists every protocol version this host accepts.
+var SupportedVersions = []int{1}
+
+// DefaultTimeout applies to each call when no timeout is configured.
+const DefaultTimeout = 30 * time.Second
+
+const (
+	maxResponseBytes = 5 << 20
+	maxStderrBytes   = 4 << 10
+)
+
+// Request is the message sent to a plugin on stdin.
+type Request struct {
+	Type              string         `json:"type"` // "describe" or "execute"
+	ProtocolVersion   int            `json:"protocolVersion"`
+	SupportedVersions []int          `json:"supportedVersions,omitempty"`
+	Tool              string         `json:"tool,omitempty"`
+	Arguments         map[string]any `json:"arguments,omitempty"`
+	Workspace         string         `json:"workspace,omitempty"`
+}
+
+// ToolInfo is a tool advertised in a describe response.
+type ToolInfo struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+// DescribeResponse is a plugin's answer to a describe request.
+type DescribeResponse struct {
+	ProtocolVersion int        `json:"protocolVersion"`
+	Name            string     `json:"name,omitempty"`
+	Version         string     `json:"version,omitempty"`
+	Tools           []ToolInfo `json:"tools"`
+}
+
+// ExecuteResponse is a plugin's answer to an execute request.
+type ExecuteResponse struct {
+	Output  string `json:"output"`
+	IsError bool   `json:"isError,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Plugin is one plugin executable whose describe handshake succeeded.
+type Plugin struct {
+	Name      string // file name of the executable
+	Path      string
+	Timeout   time.Duration
+	Env       map[string]string // extra variables; values may reference ${VAR}
+	BaseEnv   []string          // scrubbed environment, see tools.ExecIsolation.Environ
+	Workspace string
+
+	Version int // negotiated protocol version
+	Info    DescribeResponse
+}
+
+// Describe runs the describe handshake and negotiates the protocol version.
+func (p *Plugin) Describe(ctx context.Context) error {
+	var resp DescribeResponse
+	err := p.call(ctx, Request{
+		Type:              "describe",
+		ProtocolVersion:   ProtocolVersion,
+		SupportedVersions: SupportedVersions,
+	}, &resp)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(SupportedVersions, resp.ProtocolVersion) {
+		return fmt.Errorf("plugin %s speaks protocol version %d; supported versions: %v", p.Name, resp.ProtocolVersion, SupportedVersions)
+	}
+	p.Version = resp.ProtocolVersion
+	p.Info = resp
+	return nil
+}
+
+// Execute runs one tool call.
+func (p *Plugin) Execute(ctx context.Context, tool string, args map[string]any) (*ExecuteResponse, error) {
+	var resp ExecuteResponse
+	err := p.call(ctx, Request{
+		Type:            "execute",
+		ProtocolVersion: p.Version,
+		Tool:            tool,
+		Arguments:       args,
+		Workspace:       p.Workspace,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// call starts the plugin, sends req and decodes its single JSON response.
+func (p *Plugin) call(ctx context.Context, req Request, resp any) error {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Dir = p.Workspace
+	// Only the scrubbed environment and the configured variables reach the
+	// plugin, so API keys loaded from .env are not handed to every plugin.
+	cmd.Env = slices.Clone(p.BaseEnv)
+	for k, v := range p.Env {
+		cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v))
+	}
+	cmd.Stdin = bytes.NewReader(input)
+	stdout := &limitedBuffer{limit: maxResponseBytes}
+	stderr := &limitedBuffer{limit: maxStderrBytes, keepTail: true}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = 2 * time.Second
+	setProcessGroup(cmd)
+
+	runErr := cmd.Run()
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return fmt.Errorf("plugin %s timed out after %s%s", p.Name, timeout, stderrSuffix(stderr))
+	case runErr != nil:
+		var exitErr *exec.ExitError
+		if errors.As(runErr, &exitErr) {
+			return fmt.Errorf("plugin %s crashed (%v)%s", p.Name, exitErr, stderrSuffix(stderr))
+		}
+		return fmt.Errorf("plugin %s could not run: %w", p.Name, runErr)
+	case stdout.truncated:
+		return fmt.Errorf("plugin %s response exceeds %d bytes", p.Name, maxResponseBytes)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(stdout.Bytes()))
+	if err := dec.Decode(resp); err != nil {
+		return fmt.Errorf("plugin %s returned an invalid response: %v%s", p.Name, err, stderrSuffix(stderr))
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("plugin %s wrote more than one JSON value to stdout", p.Name)
+	}
+	return nil
+}
+
+func stderrSuffix(stderr *limitedBuffer) string {
+	text := strings.TrimSpace(stderr.String())
+	if text == "" {
+		return ""
+	}
+	return "\nstderr: " + text
+}
+
+// limitedBuffer keeps at most limit bytes: the head, or the tail when keepTail is set.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	keepTail  bool
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.keepTail {
+		b.Buffer.Write(p)
+		if over := b.Len() - b.limit; over > 0 {
+			b.Next(over)
+			b.truncated = true
+		}
+		return n, nil
+	}
+	if room := b.limit - b.Len(); len(p) > room {
+		p = p[:max(room, 0)]
+		b.truncated = true
+	}
+	b.Buffer.Write(p)
+	return n, nil
+}
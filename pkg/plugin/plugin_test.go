@@ -0,0 +1,233 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chiisen/mini_bot/pkg/config"
+	"github.com/chiisen/mini_bot/pkg/tools"
+)
+
+// TestMain lets the test binary double as a fake plugin. The binary is
+// symlinked under different names; the name selects how the fake behaves.
+func TestMain(m *testing.M) {
+	if os.Getenv("MINIBOT_FAKE_PLUGIN") == "1" {
+		runFakePlugin(filepath.Base(os.Args[0]))
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runFakePlugin(mode string) {
+	var req Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, "bad request:", err)
+		os.Exit(2)
+	}
+
+	if req.Type == "describe" {
+		version := 1
+		if mode == "future" {
+			version = 99
+		}
+		json.NewEncoder(os.Stdout).Encode(DescribeResponse{
+			ProtocolVersion: version,
+			Name:            mode,
+			Version:         "1.0.0",
+			Tools: []ToolInfo{
+				{Name: mode + "_echo", Description: "Echo the message back", Parameters: map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"message": map[string]any{"type": "string"}},
+				}},
+				{Name: "read_file", Description: "Collides with a built-in"},
+				{Name: "bad name!", Description: "Invalid name"},
+			},
+		})
+		return
+	}
+
+	switch mode {
+	case "crash":
+		fmt.Fprintln(os.Stderr, "Traceback: ZeroDivisionError")
+		os.Exit(1)
+	case "garbage":
+		fmt.Println("hello, not json")
+	case "hang":
+		time.Sleep(60 * time.Second)
+	case "env":
+		json.NewEncoder(os.Stdout).Encode(ExecuteResponse{Output: strings.Join(os.Environ(), "\n")})
+	default:
+		if req.Arguments["message"] == "fail" {
+			json.NewEncoder(os.Stdout).Encode(ExecuteResponse{Error: "told to fail"})
+			return
+		}
+		cwd, _ := os.Getwd()
+		json.NewEncoder(os.Stdout).Encode(ExecuteResponse{
+			Output: fmt.Sprintf("%s v%d %v in %s", req.Tool, req.ProtocolVersion, req.Arguments["message"], filepath.Base(cwd)),
+		})
+	}
+}
+
+// setupPlugins symlinks the test binary into a plugin dir under each name.
+func setupPlugins(t *testing.T, names ...string) (config.PluginsConfig, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake plugins rely on symlinked executables")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.Symlink(self, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// non-executable files are ignored
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("notes"), 0o644)
+
+	workspace := filepath.Join(t.TempDir(), "ws")
+	os.MkdirAll(workspace, 0o755)
+	return config.PluginsConfig{
+		Dir:     dir,
+		Timeout: 5,
+		Env:     map[string]string{"MINIBOT_FAKE_PLUGIN": "1"},
+	}, workspace
+}
+
+// builtinTool stands in for a built-in tool that plugins must not shadow.
+type builtinTool struct{}
+
+func (builtinTool) Name() string               { return "read_file" }
+func (builtinTool) Description() string        { return "built-in" }
+func (builtinTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+func (builtinTool) Execute(ctx context.Context, args map[string]any) *tools.ToolResult {
+	return &tools.ToolResult{ForLLM: "built-in"}
+}
+
+func newRegistry() *tools.ToolRegistry {
+	registry := tools.NewRegistry()
+	registry.Register(builtinTool{})
+	return registry
+}
+
+func description(registry *tools.ToolRegistry, name string) string {
+	for _, def := range registry.Definitions() {
+		if def.Function.Name == name {
+			return def.Function.Description
+		}
+	}
+	return ""
+}
+
+func TestRegisterPlugins_DescribeAndExecute(t *testing.T) {
+	cfg, workspace := setupPlugins(t, "py")
+	registry := newRegistry()
+
+	plugins := RegisterPlugins(context.Background(), cfg, workspace, nil, registry)
+	if len(plugins) != 1 || plugins[0].Version != 1 || plugins[0].Info.Version != "1.0.0" {
+		t.Fatalf("unexpected plugins: %+v", plugins)
+	}
+	if !registry.Has("py_echo") {
+		t.Fatal("py_echo should be registered")
+	}
+	if registry.Has("bad name!") {
+		t.Error("invalid tool names should be skipped")
+	}
+	if desc := description(registry, "read_file"); desc != "built-in" {
+		t.Errorf("plugin tools must not shadow built-ins, read_file description = %q", desc)
+	}
+	if desc := description(registry, "py_echo"); !strings.HasPrefix(desc, "[Plugin py] ") {
+		t.Errorf("description = %q", desc)
+	}
+
+	result := registry.Execute(context.Background(), "py_echo", map[string]any{"message": "hi"})
+	if result.IsError || result.ForLLM != "py_echo v1 hi in ws" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	result = registry.Execute(context.Background(), "py_echo", map[string]any{"message": "fail"})
+	if !result.IsError || result.ForLLM != "told to fail" {
+		t.Fatalf("expected plugin error, got %+v", result)
+	}
+}
+
+func TestRegisterPlugins_SkipsUnsupportedVersionAndDisabled(t *testing.T) {
+	cfg, workspace := setupPlugins(t, "future", "off", "ok")
+	cfg.Disabled = []string{"off"}
+	registry := newRegistry()
+
+	plugins := RegisterPlugins(context.Background(), cfg, workspace, nil, registry)
+	if len(plugins) != 1 || plugins[0].Name != "ok" {
+		t.Fatalf("only the ok plugin should load, got %+v", plugins)
+	}
+	if registry.Has("future_echo") || registry.Has("off_echo") {
+		t.Error("rejected plugins must not register tools")
+	}
+}
+
+func TestPluginTool_Failures(t *testing.T) {
+	cfg, workspace := setupPlugins(t, "crash", "garbage", "hang")
+	registry := newRegistry()
+	RegisterPlugins(context.Background(), cfg, workspace, nil, registry)
+
+	tests := []struct {
+		tool string
+		want string
+	}{
+		{"crash_echo", "ZeroDivisionError"},
+		{"garbage_echo", "invalid response"},
+		{"hang_echo", "timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			start := time.Now()
+			result := registry.Execute(context.Background(), tt.tool, map[string]any{"message": "x"})
+			if !result.IsError || !strings.Contains(result.ForLLM, tt.want) {
+				t.Fatalf("expected error containing %q, got %+v", tt.want, result)
+			}
+			if time.Since(start) > 20*time.Second {
+				t.Errorf("call took %s", time.Since(start))
+			}
+		})
+	}
+}
+
+func TestPluginTool_ScrubbedEnvironment(t *testing.T) {
+	cfg, workspace := setupPlugins(t, "env")
+	cfg.Env["PLUGIN_TOKEN"] = "${MINIBOT_TEST_PLUGIN_TOKEN}"
+	t.Setenv("MINIBOT_TEST_PLUGIN_TOKEN", "configured")
+	t.Setenv("MINIBOT_TEST_API_KEY", "secret-value")
+	registry := newRegistry()
+	RegisterPlugins(context.Background(), cfg, workspace, []string{"PATH=/usr/bin", "HOME=" + workspace}, registry)
+
+	result := registry.Execute(context.Background(), "env_echo", nil)
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	env := strings.Split(result.ForLLM, "\n")
+	for _, want := range []string{"PATH=/usr/bin", "HOME=" + workspace, "PLUGIN_TOKEN=configured"} {
+		if !slices.Contains(env, want) {
+			t.Errorf("missing %s in %v", want, env)
+		}
+	}
+	if strings.Contains(result.ForLLM, "secret-value") {
+		t.Errorf("plugins must not inherit the host environment: %v", env)
+	}
+}
+
+func TestDiscover_MissingDir(t *testing.T) {
+	paths, err := Discover(filepath.Join(t.TempDir(), "none"), nil)
+	if err != nil || len(paths) != 0 {
+		t.Fatalf("missing dir should yield nothing, got %v %v", paths, err)
+	}
+}
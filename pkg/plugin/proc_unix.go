@@ -0,0 +1,23 @@
+//go:build !windows
+
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup runs the plugin in its own process group so a timeout also
+// kills any children it started.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// isExecutable reports whether any execute bit is set.
+func isExecutable(_ string, info os.FileInfo) bool {
+	return info.Mode().Perm()&0o111 != 0
+}
@@ -0,0 +1,26 @@
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// setProcessGroup is a no-op on Windows; the default Cancel kills the plugin process.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// isExecutable trusts the extensions listed in PATHEXT, since Windows has no execute bit.
+func isExecutable(path string, _ os.FileInfo) bool {
+	exts := os.Getenv("PATHEXT")
+	if exts == "" {
+		exts = ".COM;.EXE;.BAT;.CMD"
+	}
+	ext := strings.ToUpper(filepath.Ext(path))
+	for _, e := range strings.Split(exts, ";") {
+		if ext != "" && ext == strings.ToUpper(e) {
+			return true
+		}
+	}
+	return false
+}
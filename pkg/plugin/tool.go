@@ -0,0 +1,127 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chiisen/mini_bot/pkg/config"
+	"github.com/chiisen/mini_bot/pkg/logger"
+	"github.com/chiisen/mini_bot/pkg/tools"
+)
+
+// Tool exposes one tool advertised by a plugin as a local tools.Tool.
+type Tool struct {
+	Plugin *Plugin
+	Remote ToolInfo
+}
+
+// validName matches tool names OpenAI-style function calling accepts.
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+func (t *Tool) Name() string { return t.Remote.Name }
+
+func (t *Tool) Description() string {
+	desc := strings.TrimSpace(t.Remote.Description)
+	if desc == "" {
+		desc = t.Remote.Name
+	}
+	return fmt.Sprintf("[Plugin %s] %s", t.Plugin.Name, desc)
+}
+
+func (t *Tool) Parameters() map[string]any {
+	if t.Remote.Parameters == nil {
+		return map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return t.Remote.Parameters
+}
+
+func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.ToolResult {
+	resp, err := t.Plugin.Execute(ctx, t.Remote.Name, args)
+	if err != nil {
+		return &tools.ToolResult{ForLLM: fmt.Sprintf("Plugin tool %s failed: %v", t.Remote.Name, err), IsError: true}
+	}
+	if resp.Error != "" {
+		return &tools.ToolResult{ForLLM: resp.Error, IsError: true}
+	}
+	return &tools.ToolResult{ForLLM: resp.Output, IsError: resp.IsError}
+}
+
+// Discover returns the plugin executables in dir, sorted by name, skipping
+// hidden files and the names in disabled. A missing dir yields no plugins.
+func Discover(dir string, disabled []string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || slices.Contains(disabled, name) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path) // follow symlinks
+		if err != nil || !info.Mode().IsRegular() || !isExecutable(path, info) {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// RegisterPlugins describes every plugin in cfg.Dir and registers its tools.
+// Plugins run with env plus cfg.Env as their environment. Plugins that fail
+// the handshake, and tools whose names are invalid or already registered, are
+// logged and skipped rather than failing startup.
+func RegisterPlugins(ctx context.Context, cfg config.PluginsConfig, workspace string, env []string, registry *tools.ToolRegistry) []*Plugin {
+	paths, err := Discover(cfg.Dir, cfg.Disabled)
+	if err != nil {
+		logger.Warn("Cannot read plugin directory", "dir", cfg.Dir, "error", err)
+		return nil
+	}
+
+	var plugins []*Plugin
+	for _, path := range paths {
+		p := &Plugin{
+			Name:      filepath.Base(path),
+			Path:      path,
+			Timeout:   time.Duration(cfg.Timeout) * time.Second,
+			Env:       cfg.Env,
+			BaseEnv:   env,
+			Workspace: workspace,
+		}
+		if err := p.Describe(ctx); err != nil {
+			logger.Warn("Skipping plugin", "plugin", p.Name, "error", err)
+			continue
+		}
+
+		registered := 0
+		for _, info := range p.Info.Tools {
+			switch {
+			case !validName.MatchString(info.Name):
+				logger.Warn("Skipping plugin tool with invalid name", "plugin", p.Name, "name", info.Name)
+				continue
+			case registry.Has(info.Name):
+				logger.Warn("Skipping plugin tool that shadows an existing tool", "plugin", p.Name, "name", info.Name)
+				continue
+			}
+			registry.Register(&Tool{Plugin: p, Remote: info})
+			registered++
+		}
+		logger.Info("Registered plugin", "plugin", p.Name, "version", p.Info.Version, "protocol", p.Version, "tools", registered)
+		plugins = append(plugins, p)
+	}
+	return plugins
+}
@@ -37,5 +37,7 @@ func NewProvider(modelCfg *config.ModelConfig) (LLMProvider, error) {
 	}
 
 	// Route to OpenAI compat provider since most vendors support the `/chat/completions` format
-	return NewOpenAICompatProvider(apiBase, modelCfg.APIKey), nil
+	provider := NewOpenAICompatProvider(apiBase, modelCfg.APIKey)
+	provider.PromptCache = modelCfg.PromptCache
+	return provider, nil
 }
@@ -45,7 +45,11 @@ func tlsVersionName(v uint16) string {
 type OpenAICompatProvider struct {
 	BaseURL string
 	APIKey  string
-	client  *http.Client
+	// PromptCache enables explicit cache_control breakpoints on messages marked
+	// with Message.CacheControl (Anthropic models via OpenRouter and similar gateways).
+	// Vendors with automatic prefix caching (OpenAI, DeepSeek) don't need it.
+	PromptCache bool
+	client      *http.Client
 }
 
 func NewOpenAICompatProvider(baseURL, apiKey string) *OpenAICompatProvider {
@@ -92,7 +96,7 @@ func (p *OpenAICompatProvider) Chat(
 
 	payload := map[string]any{
 		"model":    model,
-		"messages": messages,
+		"messages": p.encodeMessages(messages),
 	}
 
 	if len(tools) > 0 {
@@ -143,9 +147,14 @@ func (p *OpenAICompatProvider) Chat(
 			} `json:"message"`
 		} `json:"choices"`
 		Usage struct {
-			PromptTokens     int `json:"prompt_tokens"`
-			CompletionTokens int `json:"completion_tokens"`
-			TotalTokens      int `json:"total_tokens"`
+			PromptTokens        int `json:"prompt_tokens"`
+			CompletionTokens    int `json:"completion_tokens"`
+			TotalTokens         int `json:"total_tokens"`
+			PromptTokensDetails struct {
+				CachedTokens int `json:"cached_tokens"`
+			} `json:"prompt_tokens_details"`
+			// DeepSeek reports cache hits with its own field name
+			PromptCacheHitTokens int `json:"prompt_cache_hit_tokens"`
 		} `json:"usage"`
 	}
 
@@ -157,6 +166,11 @@ func (p *OpenAICompatProvider) Chat(
 		return nil, fmt.Errorf("no choices returned from API")
 	}
 
+	cached := response.Usage.PromptTokensDetails.CachedTokens
+	if cached == 0 {
+		cached = response.Usage.PromptCacheHitTokens
+	}
+
 	msg := response.Choices[0].Message
 	return &LLMResponse{
 		Content:   msg.Content,
@@ -165,6 +179,36 @@ func (p *OpenAICompatProvider) Chat(
 			PromptTokens:     response.Usage.PromptTokens,
 			CompletionTokens: response.Usage.CompletionTokens,
 			TotalTokens:      response.Usage.TotalTokens,
+			CachedTokens:     cached,
 		},
 	}, nil
 }
+
+// encodeMessages converts messages to their wire format. Messages carrying a
+// CacheControl hint are sent as a single text content part with an ephemeral
+// cache_control breakpoint when PromptCache is enabled; all others are sent as-is.
+func (p *OpenAICompatProvider) encodeMessages(messages []Message) []any {
+	out := make([]any, 0, len(messages))
+	for _, m := range messages {
+		if !p.PromptCache || !m.CacheControl || m.Content == "" {
+			out = append(out, m)
+			continue
+		}
+		msg := map[string]any{
+			"role": m.Role,
+			"content": []map[string]any{{
+				"type":          "text",
+				"text":          m.Content,
+				"cache_control": map[string]string{"type": "ephemeral"},
+			}},
+		}
+		if len(m.ToolCalls) > 0 {
+			msg["tool_calls"] = m.ToolCalls
+		}
+		if m.ToolCallID != "" {
+			msg["tool_call_id"] = m.ToolCallID
+		}
+		out = append(out, msg)
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestServer(t *testing.T, respBody string, captured *map[string]any) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if captured != nil {
+			if err := json.Unmarshal(body, captured); err != nil {
+				t.Errorf("invalid request body: %v", err)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, respBody)
+	}))
+}
+
+func TestOpenAICompat_CachedTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{
+			name: "openai prompt_tokens_details",
+			body: `{"choices":[{"message":{"content":"hi"}}],"usage":{"prompt_tokens":100,"completion_tokens":5,"total_tokens":105,"prompt_tokens_details":{"cached_tokens":64}}}`,
+			want: 64,
+		},
+		{
+			name: "deepseek prompt_cache_hit_tokens",
+			body: `{"choices":[{"message":{"content":"hi"}}],"usage":{"prompt_tokens":100,"completion_tokens":5,"total_tokens":105,"prompt_cache_hit_tokens":32}}`,
+			want: 32,
+		},
+		{
+			name: "no cache info",
+			body: `{"choices":[{"message":{"content":"hi"}}],"usage":{"prompt_tokens":100,"completion_tokens":5,"total_tokens":105}}`,
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.body, nil)
+			defer srv.Close()
+
+			p := NewOpenAICompatProvider(srv.URL+"/v1", "")
+			resp, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "hello"}}, nil, "m", nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Usage.CachedTokens != tt.want {
+				t.Errorf("expected %d cached tokens, got %d", tt.want, resp.Usage.CachedTokens)
+			}
+		})
+	}
+}
+
+func TestOpenAICompat_CacheControl(t *testing.T) {
+	okBody := `{"choices":[{"message":{"content":"ok"}}]}`
+	messages := []Message{
+		{Role: "system", Content: "stable prefix", CacheControl: true},
+		{Role: "user", Content: "hello"},
+	}
+
+	t.Run("disabled sends plain content", func(t *testing.T) {
+		var captured map[string]any
+		srv := newTestServer(t, okBody, &captured)
+		defer srv.Close()
+
+		p := NewOpenAICompatProvider(srv.URL, "")
+		if _, err := p.Chat(context.Background(), messages, nil, "m", nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		sys := captured["messages"].([]any)[0].(map[string]any)
+		if sys["content"] != "stable prefix" {
+			t.Errorf("expected plain string content, got %v", sys["content"])
+		}
+	})
+
+	t.Run("enabled adds ephemeral breakpoint", func(t *testing.T) {
+		var captured map[string]any
+		srv := newTestServer(t, okBody, &captured)
+		defer srv.Close()
+
+		p := NewOpenAICompatProvider(srv.URL, "")
+		p.PromptCache = true
+		if _, err := p.Chat(context.Background(), messages, nil, "m", nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		msgs := captured["messages"].([]any)
+		parts, ok := msgs[0].(map[string]any)["content"].([]any)
+		if !ok || len(parts) != 1 {
+			t.Fatalf("expected one content part, got %v", msgs[0])
+		}
+		part := parts[0].(map[string]any)
+		if part["text"] != "stable prefix" {
+			t.Errorf("expected text to be preserved, got %v", part["text"])
+		}
+		cc, _ := part["cache_control"].(map[string]any)
+		if cc["type"] != "ephemeral" {
+			t.Errorf("expected ephemeral cache_control, got %v", part["cache_control"])
+		}
+		if msgs[1].(map[string]any)["content"] != "hello" {
+			t.Errorf("expected unmarked message to stay plain, got %v", msgs[1])
+		}
+	})
+}
@@ -10,6 +10,11 @@ type Message struct {
 	Content    string     `json:"content"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
+
+	// CacheControl marks this message as a stable prefix worth caching on the
+	// provider side. It is only a hint: providers that don't support explicit
+	// cache breakpoints ignore it, and it is never persisted to session files.
+	CacheControl bool `json:"-"`
 }
 
 // ToolCall represents a tool call requested by the LLM.
@@ -37,6 +42,8 @@ type UsageInfo struct {
 	PromptTokens     int
 	CompletionTokens int
 	TotalTokens      int
+	// CachedTokens is the part of PromptTokens served from the provider's prompt cache.
+	CachedTokens int
 }
 
 // ToolResult represents the result of executing a tool.
@@ -0,0 +1,327 @@
+package tools
+
+// ============================================================================
+// ApplyPatchTool: 套用修補檔工具
+// ============================================================================
+// 功能：將 unified diff 或多檔案修補封包套用到工作區內的檔案
+// 工具名稱：apply_patch
+//
+// 安全機制：
+//   - 所有路徑都經過 Sandbox 檢查
+//   - 全有或全無：任何 hunk 無法套用時不修改任何檔案；
+//     寫入途中失敗時會還原已寫入的檔案
+// ============================================================================
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chiisen/mini_bot/pkg/i18n"
+)
+
+type ApplyPatchTool struct {
+	Sandbox     *Sandbox
+	Checkpoints *CheckpointStore // 修改前保存檢查點 (可為 nil)
+}
+
+func (t *ApplyPatchTool) Name() string        { return "apply_patch" }
+func (t *ApplyPatchTool) Description() string { return i18n.GetInstance().T("tools.apply_patch") }
+func (t *ApplyPatchTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"patch": map[string]any{
+				"type":        "string",
+				"description": i18n.GetInstance().T("tool_params.patch"),
+			},
+			"dry_run": map[string]any{
+				"type":        "boolean",
+				"description": i18n.GetInstance().T("tool_params.dry_run"),
+			},
+		},
+		"required": []string{"patch"},
+	}
+}
+
+// fileChange 是準備寫入的單一檔案變更
+type fileChange struct {
+	abs     string
+	content *string // nil 表示刪除
+	backup  []byte  // 原始內容 (existed 為 true 時有效)
+	existed bool
+	mode    os.FileMode
+}
+
+// Execute 執行套用修補檔
+//
+// 執行步驟：
+//  1. 解析修補檔
+//  2. 在記憶體中對每個檔案套用所有 hunk，收集無法套用的 hunk
+//  3. 有任何失敗時回報並結束，不修改檔案
+//  4. 依序寫入，失敗時還原
+func (t *ApplyPatchTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	patchText, _ := args["patch"].(string)
+	dryRun := boolArg(args, "dry_run")
+
+	patches, err := parsePatch(patchText)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: could not parse patch: %v", err), IsError: true}
+	}
+
+	pending := map[string]*fileChange{} // 以絕對路徑索引，同一檔案可被多個區段修改
+	var order []string
+	var summary, rejects []string
+
+	stage := func(abs string) (*fileChange, error) {
+		if c, ok := pending[abs]; ok {
+			return c, nil
+		}
+		c := &fileChange{abs: abs, mode: 0600}
+		if info, err := os.Stat(abs); err == nil {
+			if info.IsDir() {
+				return nil, fmt.Errorf("is a directory")
+			}
+			data, err := os.ReadFile(abs)
+			if err != nil {
+				return nil, err
+			}
+			content := string(data)
+			c.backup, c.existed, c.content, c.mode = data, true, &content, info.Mode().Perm()
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		pending[abs] = c
+		order = append(order, abs)
+		return c, nil
+	}
+
+	for _, fp := range patches {
+		oldAbs, newAbs, err := t.resolvePaths(fp)
+		if err != nil {
+			rejects = append(rejects, fmt.Sprintf("%s: %v", fp.path(), err))
+			continue
+		}
+
+		src, err := stage(oldAbs)
+		if err != nil {
+			rejects = append(rejects, fmt.Sprintf("%s: %v", fp.path(), err))
+			continue
+		}
+		exists := src.content != nil
+		switch {
+		case fp.op == patchAdd && exists:
+			rejects = append(rejects, fmt.Sprintf("%s: file already exists", fp.path()))
+			continue
+		case fp.op != patchAdd && !exists:
+			rejects = append(rejects, fmt.Sprintf("%s: file does not exist", fp.path()))
+			continue
+		}
+
+		if fp.op == patchDelete {
+			src.content = nil
+			summary = append(summary, "D "+fp.oldPath)
+			continue
+		}
+
+		original := ""
+		if exists {
+			original = *src.content
+		}
+		updated, results, hunkRejects := applyHunks(original, fp.hunks)
+		if len(hunkRejects) > 0 {
+			for _, r := range hunkRejects {
+				rejects = append(rejects, formatReject(fp, r))
+			}
+			continue
+		}
+
+		added, removed := countChanges(fp.hunks)
+		if oldAbs != newAbs {
+			dst, err := stage(newAbs)
+			if err != nil {
+				rejects = append(rejects, fmt.Sprintf("%s: %v", fp.newPath, err))
+				continue
+			}
+			if dst.content != nil {
+				rejects = append(rejects, fmt.Sprintf("%s: cannot move, destination already exists", fp.newPath))
+				continue
+			}
+			src.content = nil
+			dst.content = &updated
+			summary = append(summary, fmt.Sprintf("R %s -> %s (+%d -%d)", fp.oldPath, fp.newPath, added, removed))
+		} else {
+			src.content = &updated
+			code := "M"
+			if fp.op == patchAdd {
+				code = "A"
+			}
+			summary = append(summary, fmt.Sprintf("%s %s (+%d -%d)", code, fp.path(), added, removed))
+		}
+		for i, r := range results {
+			if r.offset != 0 || r.fuzz != "" {
+				note := fmt.Sprintf("  hunk #%d applied at line %d", i+1, r.line)
+				if r.offset != 0 {
+					note += fmt.Sprintf(" (offset %+d lines)", r.offset)
+				}
+				if r.fuzz != "" {
+					note += " [" + r.fuzz + "]"
+				}
+				summary = append(summary, note)
+			}
+		}
+	}
+
+	if len(rejects) > 0 {
+		return &ToolResult{
+			ForLLM: "Error: patch was NOT applied; no files were changed.\n\n" + strings.Join(rejects, "\n\n") +
+				"\n\nRe-read the affected files and regenerate the failing hunks.",
+			IsError: true,
+		}
+	}
+
+	if dryRun {
+		return &ToolResult{ForLLM: "Patch applies cleanly (dry run, nothing written):\n" + strings.Join(summary, "\n")}
+	}
+
+	changes := make([]*fileChange, 0, len(order))
+	sizes := make([]FileChange, 0, len(order))
+	for _, abs := range order {
+		c := pending[abs]
+		changes = append(changes, c)
+		size := int64(-1)
+		if c.content != nil {
+			size = int64(len(*c.content))
+		}
+		sizes = append(sizes, FileChange{Path: abs, Size: size})
+	}
+	if err := t.Sandbox.CheckQuota(sizes...); err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+	if err := t.Checkpoints.Snapshot(SessionKeyFrom(ctx), t.Name(), order...); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.checkpoint_failed"), err), IsError: true}
+	}
+	if err := commitChanges(changes); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: writing patched files failed, all changes were rolled back: %v", err), IsError: true}
+	}
+	return &ToolResult{ForLLM: "Patch applied:\n" + strings.Join(summary, "\n")}
+}
+
+// resolvePaths 驗證並取得修補檔中舊路徑與新路徑的絕對路徑
+func (t *ApplyPatchTool) resolvePaths(fp *filePatch) (string, string, error) {
+	check := func(p string) (string, error) {
+		if p == "" {
+			return "", fmt.Errorf("missing path")
+		}
+		if err := validatePathName(p); err != nil {
+			return "", err
+		}
+		return t.Sandbox.CheckWritePath(p)
+	}
+	oldPath := fp.oldPath
+	if fp.op == patchAdd {
+		oldPath = fp.newPath
+	}
+	oldAbs, err := check(oldPath)
+	if err != nil {
+		return "", "", err
+	}
+	newAbs := oldAbs
+	if fp.newPath != "" && fp.newPath != oldPath {
+		if newAbs, err = check(fp.newPath); err != nil {
+			return "", "", err
+		}
+	}
+	return oldAbs, newAbs, nil
+}
+
+// formatReject 描述失敗的 hunk，並列出預期的內容以便模型比對
+func formatReject(fp *filePatch, r hunkReject) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: hunk #%d (%s) rejected: %s.", fp.path(), r.index, r.header, r.reason)
+	old := fp.hunks[r.index-1].oldLines()
+	if len(old) > 0 {
+		sb.WriteString(" Expected these lines:")
+		for i, l := range old {
+			if i == 8 {
+				fmt.Fprintf(&sb, "\n  ... (%d more)", len(old)-i)
+				break
+			}
+			sb.WriteString("\n  | " + l)
+		}
+	}
+	return sb.String()
+}
+
+func countChanges(hunks []patchHunk) (added, removed int) {
+	for _, h := range hunks {
+		for _, l := range h.lines {
+			switch l.kind {
+			case '+':
+				added++
+			case '-':
+				removed++
+			}
+		}
+	}
+	return added, removed
+}
+
+// commitChanges 依序寫入所有變更；任何一步失敗時還原已完成的變更
+func commitChanges(changes []*fileChange) error {
+	var done []*fileChange
+	rollback := func() {
+		for i := len(done) - 1; i >= 0; i-- {
+			c := done[i]
+			if c.existed {
+				_ = writeFileAtomic(c.abs, c.backup, c.mode)
+			} else {
+				_ = os.Remove(c.abs)
+			}
+		}
+	}
+
+	for _, c := range changes {
+		var err error
+		switch {
+		case c.content == nil && c.existed:
+			err = os.Remove(c.abs)
+		case c.content == nil:
+			continue
+		case c.existed && *c.content == string(c.backup):
+			continue
+		default:
+			if err = os.MkdirAll(filepath.Dir(c.abs), 0755); err == nil {
+				err = writeFileAtomic(c.abs, []byte(*c.content), c.mode)
+			}
+		}
+		if err != nil {
+			rollback()
+			return err
+		}
+		done = append(done, c)
+	}
+	return nil
+}
+
+// writeFileAtomic 先寫入同目錄的暫存檔再更名，避免寫到一半的檔案
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,214 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupPatchWorkspace(t *testing.T) (string, *ApplyPatchTool) {
+	t.Helper()
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "a.txt"), []byte("one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"), 0644)
+	os.MkdirAll(filepath.Join(ws, "src"), 0755)
+	os.WriteFile(filepath.Join(ws, "src", "main.go"), []byte("package main\n\nfunc main() {\n    println(\"hi\")   \n}\n"), 0644)
+	os.WriteFile(filepath.Join(ws, "old.txt"), []byte("obsolete\n"), 0644)
+	sandbox, _ := NewSandbox(ws)
+	return ws, &ApplyPatchTool{Sandbox: sandbox}
+}
+
+func readWS(t *testing.T, ws, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(ws, filepath.FromSlash(name)))
+	if err != nil {
+		return "<missing>"
+	}
+	return string(data)
+}
+
+func TestApplyPatch_UnifiedMultiFile(t *testing.T) {
+	ws, tool := setupPatchWorkspace(t)
+	patch := `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -2,3 +2,3 @@
+ two
+-three
++THREE
+ four
+@@ -8,3 +8,4 @@
+ eight
+ nine
+ ten
++eleven
+--- /dev/null
++++ b/docs/new.md
+@@ -0,0 +1,2 @@
++# New
++file
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-obsolete
+`
+	res := tool.Execute(context.Background(), map[string]any{"patch": patch})
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+	if got := readWS(t, ws, "a.txt"); got != "one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n" {
+		t.Errorf("unexpected a.txt:\n%s", got)
+	}
+	if got := readWS(t, ws, "docs/new.md"); got != "# New\nfile\n" {
+		t.Errorf("unexpected docs/new.md: %q", got)
+	}
+	if got := readWS(t, ws, "old.txt"); got != "<missing>" {
+		t.Errorf("old.txt should be deleted, got %q", got)
+	}
+	for _, want := range []string{"M a.txt (+2 -1)", "A docs/new.md (+2 -0)", "D old.txt"} {
+		if !strings.Contains(res.ForLLM, want) {
+			t.Errorf("expected %q in summary:\n%s", want, res.ForLLM)
+		}
+	}
+}
+
+func TestApplyPatch_Fuzzy(t *testing.T) {
+	ws, tool := setupPatchWorkspace(t)
+
+	// 行號錯誤、行尾空白不同、空白上下文行缺少前置空格
+	patch := `--- a/src/main.go
++++ b/src/main.go
+@@ -10,4 +10,4 @@
+ package main
+
+ func main() {
+-    println("hi")
++    println("hello")
+ }
+`
+	res := tool.Execute(context.Background(), map[string]any{"patch": patch})
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+	if got := readWS(t, ws, "src/main.go"); got != "package main\n\nfunc main() {\n    println(\"hello\")\n}\n" {
+		t.Errorf("unexpected content:\n%s", got)
+	}
+	if !strings.Contains(res.ForLLM, "ignoring trailing whitespace") || !strings.Contains(res.ForLLM, "offset -9 lines") {
+		t.Errorf("expected fuzz note in summary:\n%s", res.ForLLM)
+	}
+}
+
+func TestApplyPatch_Envelope(t *testing.T) {
+	ws, tool := setupPatchWorkspace(t)
+	patch := "```\n*** Begin Patch\n" +
+		"*** Update File: a.txt\n" +
+		"@@ four\n" +
+		" five\n" +
+		"-six\n" +
+		"+6\n" +
+		" seven\n" +
+		"*** Update File: src/main.go\n" +
+		"*** Move to: cmd/main.go\n" +
+		"@@\n" +
+		" func main() {\n" +
+		"-    println(\"hi\")   \n" +
+		"+    println(\"moved\")\n" +
+		"*** Add File: notes/todo.md\n" +
+		"+- write tests\n" +
+		"*** Delete File: old.txt\n" +
+		"*** End Patch\n```"
+	res := tool.Execute(context.Background(), map[string]any{"patch": patch})
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+	if got := readWS(t, ws, "a.txt"); !strings.Contains(got, "five\n6\nseven\n") {
+		t.Errorf("unexpected a.txt:\n%s", got)
+	}
+	if got := readWS(t, ws, "src/main.go"); got != "<missing>" {
+		t.Errorf("src/main.go should have been moved")
+	}
+	if got := readWS(t, ws, "cmd/main.go"); !strings.Contains(got, `println("moved")`) {
+		t.Errorf("unexpected cmd/main.go:\n%s", got)
+	}
+	if got := readWS(t, ws, "notes/todo.md"); got != "- write tests\n" {
+		t.Errorf("unexpected notes/todo.md: %q", got)
+	}
+	if !strings.Contains(res.ForLLM, "R src/main.go -> cmd/main.go") {
+		t.Errorf("expected rename in summary:\n%s", res.ForLLM)
+	}
+}
+
+func TestApplyPatch_AtomicReject(t *testing.T) {
+	ws, tool := setupPatchWorkspace(t)
+	before := readWS(t, ws, "a.txt")
+
+	patch := `--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+-one
++ONE
+ two
+--- a/src/main.go
++++ b/src/main.go
+@@ -1,3 +1,3 @@
+ package main
+-func nothing() {
++func something() {
+`
+	res := tool.Execute(context.Background(), map[string]any{"patch": patch})
+	if !res.IsError {
+		t.Fatalf("expected reject, got %s", res.ForLLM)
+	}
+	if !strings.Contains(res.ForLLM, "src/main.go: hunk #1") || !strings.Contains(res.ForLLM, "| func nothing() {") {
+		t.Errorf("reject should name the hunk and expected lines:\n%s", res.ForLLM)
+	}
+	if readWS(t, ws, "a.txt") != before {
+		t.Error("a.txt was modified even though another file was rejected")
+	}
+}
+
+func TestApplyPatch_DryRunAndErrors(t *testing.T) {
+	ws, tool := setupPatchWorkspace(t)
+	ctx := context.Background()
+
+	patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-one\n+uno\n"
+	res := tool.Execute(ctx, map[string]any{"patch": patch, "dry_run": true})
+	if res.IsError || !strings.Contains(res.ForLLM, "dry run") {
+		t.Errorf("unexpected dry run result: %+v", res)
+	}
+	if !strings.HasPrefix(readWS(t, ws, "a.txt"), "one\n") {
+		t.Error("dry run modified the file")
+	}
+
+	cases := map[string]string{
+		"garbage":        "this is not a patch",
+		"outside":        "--- a/../escape.txt\n+++ b/../escape.txt\n@@ -1 +1 @@\n-a\n+b\n",
+		"missing file":   "--- a/nope.txt\n+++ b/nope.txt\n@@ -1 +1 @@\n-a\n+b\n",
+		"add existing":   "--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1 @@\n+x\n",
+		"unterminated":   "*** Begin Patch\n*** Add File: x.txt\n+x\n",
+		"bad add prefix": "*** Begin Patch\n*** Add File: x.txt\nx\n*** End Patch\n",
+	}
+	for name, p := range cases {
+		if res := tool.Execute(ctx, map[string]any{"patch": p}); !res.IsError {
+			t.Errorf("%s: expected error, got %s", name, res.ForLLM)
+		}
+	}
+}
+
+func TestApplyHunks_NoNewlineAndCRLF(t *testing.T) {
+	patches, err := parsePatch("--- a/x\n+++ b/x\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, rejects := applyHunks("a\nb", patches[0].hunks)
+	if len(rejects) > 0 || got != "a\nc" {
+		t.Errorf("got %q, rejects %v", got, rejects)
+	}
+
+	patches, _ = parsePatch("--- a/x\n+++ b/x\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n")
+	got, _, rejects = applyHunks("a\r\nb\r\n", patches[0].hunks)
+	if len(rejects) > 0 || got != "a\r\nc\r\n" {
+		t.Errorf("got %q, rejects %v", got, rejects)
+	}
+}
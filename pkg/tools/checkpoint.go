@@ -0,0 +1,352 @@
+package tools
+
+// ============================================================================
+// 檢查點 (Checkpoints)
+// ============================================================================
+// 修改檔案的工具在寫入前，先把檔案原本的狀態保存為檢查點，
+// 讓 Agent (undo_last_change) 或使用者 (app checkpoints) 可以還原。
+//
+// 儲存結構 (位於工作區內，依會話分開)：
+//   {Dir}/{會話}/{檢查點 ID}/manifest.json   檢查點資訊與檔案清單
+//   {Dir}/{會話}/{檢查點 ID}/{n}              各檔案修改前的內容
+//
+// 設計原理：
+//   - 儲存目錄必須以 Sandbox.Protect 保護，工具無法寫入或偽造檢查點；
+//     還原時每個目標仍會經過 Sandbox.CheckWritePath (解析符號連結、遵守唯讀根目錄)
+//   - 檢查點 ID 以時間排序，還原時由新到舊逐一復原
+//   - 修改前不存在的檔案也會記錄，還原時會刪除它
+//   - 每個會話只保留最近 MaxPerSession 個檢查點
+//   - 檢查點的內容計入 Sandbox 的工作區配額，超過配額時不建立檢查點 (修改也隨之取消)
+// ============================================================================
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultMaxCheckpoints = 50
+
+// Checkpoint 描述一次檔案修改前的狀態
+type Checkpoint struct {
+	ID      string           `json:"id"`
+	Session string           `json:"session"`
+	Tool    string           `json:"tool"`
+	Time    time.Time        `json:"time"`
+	Files   []CheckpointFile `json:"files"`
+}
+
+// CheckpointFile 是檢查點中的單一檔案
+type CheckpointFile struct {
+	Path    string      `json:"path"`    // 相對於工作區
+	Existed bool        `json:"existed"` // 修改前是否存在
+	Mode    os.FileMode `json:"mode,omitempty"`
+	Blob    string      `json:"blob,omitempty"` // 內容檔名 (Existed 為 true 時)
+}
+
+// Paths 回傳檢查點涵蓋的檔案路徑
+func (c *Checkpoint) Paths() []string {
+	paths := make([]string, len(c.Files))
+	for i, f := range c.Files {
+		paths[i] = f.Path
+	}
+	return paths
+}
+
+// CheckpointStore 管理工作區內的檢查點
+type CheckpointStore struct {
+	Dir           string   // 檢查點根目錄，例如 workspace/.checkpoints
+	Workspace     string   // 工作區根目錄，用於轉換相對路徑
+	MaxPerSession int      // 每個會話保留的檢查點數量，0 表示使用預設值
+	Sandbox       *Sandbox // 檢查還原目標；nil 時只限制在工作區內
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewCheckpointStore 建立檢查點儲存區
+//
+// 參數：
+//   - workspace: 工作區根目錄
+//   - dir:       檢查點儲存目錄
+func NewCheckpointStore(workspace, dir string) *CheckpointStore {
+	if resolved, err := filepath.EvalSymlinks(workspace); err == nil {
+		workspace = resolved
+	}
+	return &CheckpointStore{Dir: dir, Workspace: workspace, MaxPerSession: defaultMaxCheckpoints}
+}
+
+var unsafeSessionChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// sessionDir 將會話鍵轉為安全的目錄名稱 (例如 a:b -> a_b)
+func (s *CheckpointStore) sessionDir(session string) string {
+	name := unsafeSessionChars.ReplaceAllString(session, "_")
+	if name == "" || strings.Trim(name, ".") == "" {
+		name = DefaultSessionKey
+	}
+	return filepath.Join(s.Dir, name)
+}
+
+// Snapshot 在修改前保存檔案目前的狀態
+//
+// 參數：
+//   - session: 會話鍵
+//   - tool:    即將修改檔案的工具名稱
+//   - paths:   即將被修改的檔案絕對路徑 (已通過 Sandbox 檢查)
+//
+// 可對 nil 的 CheckpointStore 呼叫 (不做任何事)，方便未啟用檢查點的情境。
+func (s *CheckpointStore) Snapshot(session, tool string, paths ...string) error {
+	if s == nil || len(paths) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.seq++
+	cp := Checkpoint{
+		ID:      fmt.Sprintf("%s-%03d", now.Format("20060102-150405.000000"), s.seq%1000),
+		Session: session,
+		Tool:    tool,
+		Time:    now,
+	}
+	dir := filepath.Join(s.sessionDir(session), cp.ID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("creating checkpoint: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, abs := range paths {
+		if seen[abs] {
+			continue
+		}
+		seen[abs] = true
+
+		f := CheckpointFile{Path: s.relative(abs)}
+		if path.IsAbs(f.Path) || f.Path == ".." || strings.HasPrefix(f.Path, "../") {
+			// 其他沙盒根目錄中的檔案：還原時無法安全寫回工作區外，不做快照
+			continue
+		}
+		info, err := os.Stat(abs)
+		switch {
+		case err == nil && info.Mode().IsRegular():
+			data, err := os.ReadFile(abs)
+			if err != nil {
+				s.remove(dir)
+				return fmt.Errorf("reading %s for checkpoint: %w", f.Path, err)
+			}
+			f.Existed, f.Mode = true, info.Mode().Perm()
+			f.Blob = fmt.Sprintf("%d", len(cp.Files))
+			blob := filepath.Join(dir, f.Blob)
+			if err := s.sandbox().CheckQuota(FileChange{Path: blob, Size: int64(len(data))}); err != nil {
+				s.remove(dir)
+				return err
+			}
+			if err := os.WriteFile(blob, data, 0600); err != nil {
+				s.remove(dir)
+				return fmt.Errorf("writing checkpoint: %w", err)
+			}
+		case err == nil:
+			// 目錄等非一般檔案不做快照
+			continue
+		case !os.IsNotExist(err):
+			s.remove(dir)
+			return err
+		}
+		cp.Files = append(cp.Files, f)
+	}
+
+	manifest, _ := json.MarshalIndent(cp, "", "  ")
+	manifestPath := filepath.Join(dir, "manifest.json")
+	if err := s.sandbox().CheckQuota(FileChange{Path: manifestPath, Size: int64(len(manifest))}); err != nil {
+		s.remove(dir)
+		return err
+	}
+	if err := os.WriteFile(manifestPath, manifest, 0600); err != nil {
+		s.remove(dir)
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	s.prune(session)
+	return nil
+}
+
+// prune 刪除超過保留數量的舊檢查點
+func (s *CheckpointStore) prune(session string) {
+	limit := s.MaxPerSession
+	if limit <= 0 {
+		limit = defaultMaxCheckpoints
+	}
+	ids := s.ids(session)
+	for len(ids) > limit {
+		s.remove(filepath.Join(s.sessionDir(session), ids[0]))
+		ids = ids[1:]
+	}
+}
+
+// remove 刪除一個檢查點目錄，並從配額的使用量中扣除它的檔案
+func (s *CheckpointStore) remove(dir string) {
+	if changes, err := treeChanges(dir, dir); err == nil {
+		for i := range changes {
+			changes[i].Size = -1
+		}
+		s.sandbox().CheckQuota(changes...)
+	}
+	os.RemoveAll(dir)
+}
+
+// ids 回傳會話的檢查點 ID (由舊到新)
+func (s *CheckpointStore) ids(session string) []string {
+	entries, err := os.ReadDir(s.sessionDir(session))
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() {
+			ids = append(ids, e.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *CheckpointStore) load(session, id string) (*Checkpoint, error) {
+	data, err := os.ReadFile(filepath.Join(s.sessionDir(session), id, "manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint %s not found", id)
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("checkpoint %s is corrupted: %w", id, err)
+	}
+	return &cp, nil
+}
+
+// Sessions 回傳有檢查點的會話目錄名稱
+func (s *CheckpointStore) Sessions() []string {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil
+	}
+	var sessions []string
+	for _, e := range entries {
+		if e.IsDir() {
+			sessions = append(sessions, e.Name())
+		}
+	}
+	sort.Strings(sessions)
+	return sessions
+}
+
+// List 回傳會話的檢查點 (由新到舊)
+func (s *CheckpointStore) List(session string) []*Checkpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := s.ids(session)
+	list := make([]*Checkpoint, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		if cp, err := s.load(session, ids[i]); err == nil {
+			list = append(list, cp)
+		}
+	}
+	return list
+}
+
+// Undo 還原會話最近一次的檢查點並刪除它
+func (s *CheckpointStore) Undo(session string) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := s.ids(session)
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no changes to undo in this session")
+	}
+	restored, err := s.rewind(session, ids[len(ids)-1:])
+	if err != nil {
+		return nil, err
+	}
+	return restored[0], nil
+}
+
+// Restore 將檔案還原到檢查點 id 建立之前的狀態：
+// 由新到舊依序復原 id 及其之後的所有檢查點，並刪除它們
+//
+// 回傳：
+//   - []*Checkpoint: 已復原的檢查點 (由新到舊)
+func (s *CheckpointStore) Restore(session, id string) ([]*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := s.ids(session)
+	idx := sort.SearchStrings(ids, id)
+	if idx >= len(ids) || ids[idx] != id {
+		return nil, fmt.Errorf("checkpoint %s not found in session %s", id, session)
+	}
+	return s.rewind(session, ids[idx:])
+}
+
+// rewind 由新到舊復原指定的檢查點
+func (s *CheckpointStore) rewind(session string, ids []string) ([]*Checkpoint, error) {
+	var restored []*Checkpoint
+	for i := len(ids) - 1; i >= 0; i-- {
+		cp, err := s.load(session, ids[i])
+		if err != nil {
+			return restored, err
+		}
+		dir := filepath.Join(s.sessionDir(session), cp.ID)
+		for _, f := range cp.Files {
+			rel := filepath.Clean(filepath.FromSlash(f.Path))
+			if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return restored, fmt.Errorf("checkpoint %s has a path outside the workspace: %s", cp.ID, f.Path)
+			}
+			abs, err := s.sandbox().CheckWritePath(filepath.Join(s.Workspace, rel))
+			if err != nil {
+				return restored, fmt.Errorf("checkpoint %s cannot restore %s: %w", cp.ID, f.Path, err)
+			}
+			if !f.Existed {
+				if err := os.Remove(abs); err != nil && !os.IsNotExist(err) {
+					return restored, fmt.Errorf("restoring %s: %w", f.Path, err)
+				}
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, f.Blob))
+			if err != nil {
+				return restored, fmt.Errorf("checkpoint %s is missing the contents of %s", cp.ID, f.Path)
+			}
+			if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+				return restored, err
+			}
+			if err := writeFileAtomic(abs, data, f.Mode); err != nil {
+				return restored, fmt.Errorf("restoring %s: %w", f.Path, err)
+			}
+		}
+		s.remove(dir)
+		restored = append(restored, cp)
+	}
+	return restored, nil
+}
+
+// sandbox 回傳檢查還原目標的沙盒
+func (s *CheckpointStore) sandbox() *Sandbox {
+	if s.Sandbox != nil {
+		return s.Sandbox
+	}
+	return &Sandbox{Workspace: s.Workspace}
+}
+
+// relative 回傳相對於工作區的路徑 (使用 "/")
+func (s *CheckpointStore) relative(abs string) string {
+	rel, err := filepath.Rel(s.Workspace, abs)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
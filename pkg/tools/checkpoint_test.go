@@ -0,0 +1,185 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestCheckpoints(t *testing.T) (string, *Sandbox, *CheckpointStore) {
+	t.Helper()
+	ws := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	return ws, sandbox, NewCheckpointStore(ws, filepath.Join(ws, ".checkpoints"))
+}
+
+func TestCheckpointStore_UndoAndRestore(t *testing.T) {
+	ws, _, store := newTestCheckpoints(t)
+	a := filepath.Join(ws, "a.txt")
+	b := filepath.Join(ws, "sub", "b.txt")
+	os.WriteFile(a, []byte("v1"), 0644)
+
+	// 1: 修改 a
+	if err := store.Snapshot("s1", "write_file", a); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(a, []byte("v2"), 0644)
+	// 2: 新增 b
+	store.Snapshot("s1", "write_file", b)
+	os.MkdirAll(filepath.Dir(b), 0755)
+	os.WriteFile(b, []byte("new"), 0644)
+	// 3: 再次修改 a
+	store.Snapshot("s1", "edit_file", a)
+	os.WriteFile(a, []byte("v3"), 0644)
+
+	list := store.List("s1")
+	if len(list) != 3 || list[0].Tool != "edit_file" || list[2].Paths()[0] != "a.txt" {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+	if len(store.List("s2")) != 0 {
+		t.Error("sessions must be isolated")
+	}
+
+	cp, err := store.Undo("s1")
+	if err != nil || cp.Tool != "edit_file" {
+		t.Fatalf("undo: %v %+v", err, cp)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "v2" {
+		t.Errorf("expected v2 after undo, got %q", data)
+	}
+
+	// 還原到第一個檢查點之前：b 被刪除，a 回到 v1
+	restored, err := store.Restore("s1", list[2].ID)
+	if err != nil || len(restored) != 2 {
+		t.Fatalf("restore: %v %d", err, len(restored))
+	}
+	if data, _ := os.ReadFile(a); string(data) != "v1" {
+		t.Errorf("expected v1 after restore, got %q", data)
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Error("file created after the checkpoint should be removed")
+	}
+	if _, err := store.Undo("s1"); err == nil {
+		t.Error("expected error when nothing is left to undo")
+	}
+}
+
+func TestCheckpointStore_Prune(t *testing.T) {
+	ws, _, store := newTestCheckpoints(t)
+	store.MaxPerSession = 3
+	a := filepath.Join(ws, "a.txt")
+	for i := 0; i < 5; i++ {
+		os.WriteFile(a, []byte{byte('0' + i)}, 0644)
+		store.Snapshot("telegram:1", "write_file", a)
+	}
+	list := store.List("telegram:1")
+	if len(list) != 3 {
+		t.Fatalf("expected 3 checkpoints, got %d", len(list))
+	}
+	if sessions := store.Sessions(); len(sessions) != 1 || sessions[0] != "telegram_1" {
+		t.Errorf("unexpected sessions: %v", sessions)
+	}
+}
+
+func TestCheckpointStore_Nil(t *testing.T) {
+	var store *CheckpointStore
+	if err := store.Snapshot("s", "write_file", "/tmp/x"); err != nil {
+		t.Errorf("nil store should be a no-op: %v", err)
+	}
+}
+
+func TestCheckpointStore_RejectsEscapingPath(t *testing.T) {
+	ws, _, store := newTestCheckpoints(t)
+	a := filepath.Join(ws, "a.txt")
+	os.WriteFile(a, []byte("v1"), 0644)
+	store.Snapshot("s", "write_file", a)
+
+	// 竄改 manifest，讓路徑指向工作區外
+	id := store.List("s")[0].ID
+	manifest := filepath.Join(ws, ".checkpoints", "s", id, "manifest.json")
+	data, _ := os.ReadFile(manifest)
+	os.WriteFile(manifest, []byte(strings.Replace(string(data), `"a.txt"`, `"../outside.txt"`, 1)), 0600)
+
+	if _, err := store.Undo("s"); err == nil || !strings.Contains(err.Error(), "outside the workspace") {
+		t.Errorf("expected escaping path to be rejected, got %v", err)
+	}
+}
+
+func TestUndoLastChangeTool(t *testing.T) {
+	ws, sandbox, store := newTestCheckpoints(t)
+	ctx := WithSessionKey(context.Background(), "cli:test")
+	write := &WriteFileTool{Sandbox: sandbox, Checkpoints: store}
+	replace := &ReplaceInFileTool{Sandbox: sandbox, Checkpoints: store}
+	undo := &UndoLastChangeTool{Checkpoints: store}
+
+	write.Execute(ctx, map[string]any{"path": "notes.md", "content": "hello world"})
+	replace.Execute(ctx, map[string]any{"path": "notes.md", "old_string": "world", "new_string": "there"})
+
+	// 其他會話沒有可復原的修改
+	if res := undo.Execute(context.Background(), nil); !res.IsError {
+		t.Errorf("expected error for a session without checkpoints, got %s", res.ForLLM)
+	}
+
+	res := undo.Execute(ctx, nil)
+	if res.IsError || !strings.Contains(res.ForLLM, "replace_in_file") || !strings.Contains(res.ForLLM, "restored notes.md") {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, "notes.md")); string(data) != "hello world" {
+		t.Errorf("unexpected content after undo: %q", data)
+	}
+
+	res = undo.Execute(ctx, nil)
+	if res.IsError || !strings.Contains(res.ForLLM, "removed notes.md") {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "notes.md")); !os.IsNotExist(err) {
+		t.Error("file created by write_file should be removed")
+	}
+}
+
+func TestCheckpointStore_ForgedManifest(t *testing.T) {
+	ws, sandbox, store := newTestCheckpoints(t)
+	toolsDir := filepath.Join(ws, "tools")
+	os.Mkdir(toolsDir, 0755)
+	os.WriteFile(filepath.Join(toolsDir, "safe.json"), []byte("{}"), 0644)
+	if err := sandbox.AddRoot("tools", toolsDir, true); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	os.Symlink(outside, filepath.Join(ws, "link"))
+	store.Sandbox = sandbox
+	sandbox.Protect(store.Dir)
+	ctx := context.Background()
+
+	// 檔案工具無法在檢查點目錄中建立檢查點
+	forged := ".checkpoints/s1/99999999-x"
+	if res := (&MakeDirTool{Sandbox: sandbox}).Execute(ctx, map[string]any{"path": forged}); !res.IsError || !strings.Contains(res.ForLLM, "internal directory") {
+		t.Errorf("make_dir in the checkpoint store should be refused, got %+v", res)
+	}
+	if res := (&WriteFileTool{Sandbox: sandbox}).Execute(ctx, map[string]any{"path": forged + "/manifest.json", "content": "{}"}); !res.IsError {
+		t.Errorf("write_file in the checkpoint store should be refused, got %+v", res)
+	}
+	if res := (&DeletePathTool{Sandbox: sandbox}).Execute(ctx, map[string]any{"path": ".checkpoints", "recursive": true}); !res.IsError {
+		t.Errorf("delete_path of the checkpoint store should be refused, got %+v", res)
+	}
+
+	// 即使檢查點被偽造，還原目標仍須通過 CheckWritePath
+	for _, target := range []string{"tools/evil.json", "link/pwned.txt"} {
+		dir := filepath.Join(store.Dir, "s1", "99999999-x")
+		os.MkdirAll(dir, 0700)
+		os.WriteFile(filepath.Join(dir, "0"), []byte("evil"), 0600)
+		os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{"id":"99999999-x","files":[{"path":"`+target+`","existed":true,"blob":"0"}]}`), 0600)
+		if _, err := store.Undo("s1"); err == nil {
+			t.Errorf("%s: restoring a forged checkpoint should fail", target)
+		}
+		os.RemoveAll(dir)
+	}
+	if _, err := os.Stat(filepath.Join(toolsDir, "evil.json")); err == nil {
+		t.Error("the read-only root was written")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "pwned.txt")); err == nil {
+		t.Error("a file was written through the symlink")
+	}
+}
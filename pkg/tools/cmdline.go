@@ -0,0 +1,175 @@
+package tools
+
+// ============================================================================
+// 命令列解析 (Command Line Parsing)
+// ============================================================================
+// exec 工具不經過 Shell 執行命令，而是自行把命令字串解析為 argv：
+//   - 支援單引號、雙引號與反斜線跳脫，引號內的 | ; & 等字元都是一般字元
+//   - 未加引號的 | 將命令串成管線 (Pipeline)，由 Go 連接各階段的輸入輸出
+//   - 不支援 ; && || & 重新導向、$(...)、反引號與變數展開，遇到時直接回報錯誤，
+//     而不是像過去一樣刪除字元後執行 (會破壞 grep "a|b" 這類合法參數)
+//   - 未加引號且含 * ? [ 的參數會在工作區內展開，沒有符合的檔案時保留原字串
+// ============================================================================
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// cmdWord 是解析出的一個參數
+type cmdWord struct {
+	text string
+	glob bool // 未加引號且含萬用字元，需要展開
+}
+
+// parseCommandLine 將命令字串解析為管線的各個階段
+func parseCommandLine(s string) ([][]cmdWord, error) {
+	var stages [][]cmdWord
+	var words []cmdWord
+	var cur strings.Builder
+	inWord, quoted := false, false
+
+	endWord := func() {
+		if inWord {
+			text := cur.String()
+			words = append(words, cmdWord{text: text, glob: !quoted && strings.ContainsAny(text, "*?[")})
+		}
+		cur.Reset()
+		inWord, quoted = false, false
+	}
+	endStage := func() error {
+		endWord()
+		if len(words) == 0 {
+			return fmt.Errorf("empty command in pipeline")
+		}
+		stages = append(stages, words)
+		words = nil
+		return nil
+	}
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t':
+			endWord()
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			cur.WriteString(string(runes[i+1 : end]))
+			inWord, quoted, i = true, true, end
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				switch c := runes[i]; {
+				case c == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]):
+					i++
+					cur.WriteRune(runes[i])
+				case c == '`':
+					return nil, errShellOperator("`")
+				case c == '$' && i+1 < len(runes) && isExpansionStart(runes[i+1]):
+					return nil, errShellOperator("$" + string(runes[i+1]))
+				default:
+					cur.WriteRune(c)
+				}
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord, quoted = true, true
+		case r == '\\':
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			i++
+			cur.WriteRune(runes[i])
+			inWord, quoted = true, true
+		case r == '|':
+			if i+1 < len(runes) && runes[i+1] == '|' {
+				return nil, errShellOperator("||")
+			}
+			if err := endStage(); err != nil {
+				return nil, err
+			}
+		case strings.ContainsRune(";&<>`()\n\r", r):
+			op := string(r)
+			if i+1 < len(runes) && (runes[i+1] == r || (r == '>' && runes[i+1] == '&')) {
+				op += string(runes[i+1])
+			}
+			return nil, errShellOperator(op)
+		case r == '$' && i+1 < len(runes) && isExpansionStart(runes[i+1]):
+			return nil, errShellOperator("$" + string(runes[i+1]))
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if err := endStage(); err != nil {
+		if len(stages) == 0 {
+			return nil, fmt.Errorf("empty command")
+		}
+		return nil, err
+	}
+	return stages, nil
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// isExpansionStart 判斷 $ 之後的字元是否構成 Shell 展開 ($VAR、${...}、$(...))
+func isExpansionStart(r rune) bool {
+	return r == '(' || r == '{' || r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z')
+}
+
+func errShellOperator(op string) error {
+	return fmt.Errorf("shell syntax %q is not supported: commands run without a shell; use | to pipe between commands and run separate commands in separate calls", op)
+}
+
+// expandWords 展開萬用字元，只回傳工作區內的符合項目 (相對路徑)
+func expandWords(sandbox *Sandbox, words []cmdWord) []string {
+	argv := make([]string, 0, len(words))
+	for i, w := range words {
+		if !w.glob || i == 0 {
+			argv = append(argv, w.text)
+			continue
+		}
+		pattern := w.text
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(sandbox.Workspace, pattern)
+		}
+		matches, _ := filepath.Glob(pattern)
+		var expanded []string
+		for _, m := range matches {
+			// 與 Shell 相同，* 不比對隱藏檔
+			if strings.HasPrefix(filepath.Base(m), ".") && !strings.HasPrefix(filepath.Base(pattern), ".") {
+				continue
+			}
+			if _, err := sandbox.CheckPath(m); err != nil {
+				continue
+			}
+			if !filepath.IsAbs(w.text) {
+				if rel, err := filepath.Rel(sandbox.Workspace, m); err == nil {
+					m = rel
+				}
+			}
+			expanded = append(expanded, m)
+		}
+		if len(expanded) == 0 {
+			argv = append(argv, w.text)
+			continue
+		}
+		sort.Strings(expanded)
+		argv = append(argv, expanded...)
+	}
+	return argv
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		want [][]string
+	}{
+		{`ls -la`, [][]string{{"ls", "-la"}}},
+		{`grep "a|b" file.txt`, [][]string{{"grep", "a|b", "file.txt"}}},
+		{`grep 'x; y && z' f`, [][]string{{"grep", "x; y && z", "f"}}},
+		{`echo "say \"hi\"" 'it''s'`, [][]string{{"echo", `say "hi"`, "its"}}},
+		{`echo a\ b \|`, [][]string{{"echo", "a b", "|"}}},
+		{`echo "cost: $5" '$HOME'`, [][]string{{"echo", "cost: $5", "$HOME"}}},
+		{`echo ''`, [][]string{{"echo", ""}}},
+		{`grep -rn TODO . | sort | uniq -c`, [][]string{{"grep", "-rn", "TODO", "."}, {"sort"}, {"uniq", "-c"}}},
+		{`cat 筆記.md|wc -l`, [][]string{{"cat", "筆記.md"}, {"wc", "-l"}}},
+		{`grep "^end$" f`, [][]string{{"grep", "^end$", "f"}}},
+	}
+	for _, tt := range tests {
+		stages, err := parseCommandLine(tt.in)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.in, err)
+			continue
+		}
+		var got [][]string
+		for _, s := range stages {
+			var argv []string
+			for _, w := range s {
+				argv = append(argv, w.text)
+			}
+			got = append(got, argv)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	rejected := map[string]string{
+		"":                     "empty command",
+		"ls; rm -rf x":         `";"`,
+		"make && make install": `"&&"`,
+		"a || b":               `"||"`,
+		"sleep 10 &":           `"&"`,
+		"echo hi > out.txt":    `">"`,
+		"cat < in":             `"<"`,
+		"echo $(id)":           `"$("`,
+		"echo \"$(id)\"":       `"$("`,
+		"echo `id`":            "\"`\"",
+		"echo $HOME":           `"$H"`,
+		"echo ${PATH}":         `"${"`,
+		"ls\nrm x":             `"\n"`,
+		"ls |":                 "empty command in pipeline",
+		"| ls":                 "empty command in pipeline",
+		"echo 'open":           "unterminated single quote",
+		`echo "open`:           "unterminated double quote",
+		`echo \`:               "trailing backslash",
+	}
+	for in, want := range rejected {
+		if _, err := parseCommandLine(in); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want error containing %s", in, err, want)
+		}
+	}
+}
+
+func TestExpandWords(t *testing.T) {
+	ws := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	for _, f := range []string{"a.go", "b.go", "c.txt", ".hidden.go", "sub/d.go"} {
+		os.MkdirAll(filepath.Dir(filepath.Join(ws, f)), 0755)
+		os.WriteFile(filepath.Join(ws, f), nil, 0644)
+	}
+
+	stages, _ := parseCommandLine(`wc -l *.go "*.go" sub/*.go *.rs`)
+	got := expandWords(sandbox, stages[0])
+	want := []string{"wc", "-l", "a.go", "b.go", "*.go", "sub/d.go", "*.rs"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,411 @@
+package tools
+
+// ============================================================================
+// 自訂工具 (Declarative Custom Tools)
+// ============================================================================
+// 不需重新編譯即可新增工具：~/.minibot.go/tools/*.json 的每個檔案定義一個工具，
+// 內容包含名稱、描述、參數 Schema，以及命令範本或 HTTP 請求範本，
+// 範本中的 {{參數}} 會以呼叫參數代入。
+//
+//	{
+//	  "name": "deploy_staging",
+//	  "description": "Deploy a git ref to staging",
+//	  "parameters": {"type": "object", "properties": {"ref": {"type": "string"}}, "required": ["ref"]},
+//	  "command": "scripts/deploy.sh --env staging {{ref}}",
+//	  "timeout": 300
+//	}
+//
+// 安全性：
+//   - 命令範本先解析為 argv 才代入參數，參數值不會被當成 Shell 語法或拆成多個參數
+//   - 代入後的命令與 exec 相同，必須通過 ExecPolicy、路徑檢查與 ExecIsolation
+//   - 單獨成為一個參數的值不可以 - 開頭，避免被當成選項
+//   - HTTP 範本的主機名稱不可含參數；路徑與查詢字串中的值會分別編碼
+//   - Headers 的 ${VAR} 只能使用設定檔明確列出的環境變數，並在代入參數之前展開，
+//     參數值中的 ${VAR} 不會被展開
+//   - 範本等同於程式碼，呼叫端必須確認 Agent 無法寫入範本目錄 (見 Sandbox.InWritableRoot)
+// ============================================================================
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	customToolDefaultTimeout = 30
+	customToolMaxTimeout     = 3600
+	customToolMaxBody        = 5 << 20
+)
+
+var (
+	customToolName   = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	placeholderRegex = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+)
+
+// CustomToolManifest 是範本檔 (*.json) 的內容，Command 與 HTTP 二擇一
+type CustomToolManifest struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters,omitempty"` // JSON Schema，省略時依範本中的參數產生
+	Command     string         `json:"command,omitempty"`
+	HTTP        *HTTPTemplate  `json:"http,omitempty"`
+	Timeout     int            `json:"timeout,omitempty"` // 秒，預設 30
+}
+
+// HTTPTemplate 是 HTTP 請求範本；Headers 中的 ${VAR} 會以允許的環境變數展開 (金鑰可以留在 .env)
+type HTTPTemplate struct {
+	Method  string            `json:"method,omitempty"` // 預設 GET，有 Body 時預設 POST
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    any               `json:"body,omitempty"` // 字串或 JSON；值剛好是 "{{x}}" 時保留參數原本的型別
+}
+
+// CustomTool 是由範本定義的工具
+type CustomTool struct {
+	manifest CustomToolManifest
+	exec     *ExecTool
+	words    [][]cmdWord // 預先解析的命令範本
+	client   *http.Client
+	env      map[string]bool // Headers 可以使用的環境變數
+}
+
+// NewCustomTool 驗證範本並建立工具；命令範本經由 exec 的政策與隔離設定執行
+//
+// 參數：
+//   - m:    範本內容
+//   - exec: 執行命令範本的 exec 工具 (只有 HTTP 範本時可為 nil)
+//   - env:  HTTP Headers 中 ${VAR} 可以使用的環境變數名稱
+func NewCustomTool(m CustomToolManifest, exec *ExecTool, env []string) (*CustomTool, error) {
+	if !customToolName.MatchString(m.Name) {
+		return nil, fmt.Errorf("invalid tool name %q: use letters, digits, _ and -", m.Name)
+	}
+	if strings.TrimSpace(m.Description) == "" {
+		return nil, fmt.Errorf("tool %s: description is required", m.Name)
+	}
+	if (m.Command == "") == (m.HTTP == nil) {
+		return nil, fmt.Errorf("tool %s: exactly one of command and http is required", m.Name)
+	}
+	if m.Timeout < 0 || m.Timeout > customToolMaxTimeout {
+		return nil, fmt.Errorf("tool %s: timeout must be between 1 and %d seconds", m.Name, customToolMaxTimeout)
+	}
+
+	t := &CustomTool{manifest: m, exec: exec, env: make(map[string]bool, len(env))}
+	for _, name := range env {
+		t.env[name] = true
+	}
+	var used []string
+	if m.Command != "" {
+		if exec == nil || exec.Sandbox == nil {
+			return nil, fmt.Errorf("tool %s: command tools need the exec sandbox", m.Name)
+		}
+		if !isCommandSafe(m.Command) {
+			return nil, fmt.Errorf("tool %s: command matches a dangerous pattern", m.Name)
+		}
+		words, err := parseCommandLine(m.Command)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: invalid command template: %w", m.Name, err)
+		}
+		for _, stage := range words {
+			if placeholderRegex.MatchString(stage[0].text) {
+				return nil, fmt.Errorf("tool %s: the program name cannot contain a {{parameter}}", m.Name)
+			}
+		}
+		t.words = words
+		used = placeholderRegex.FindAllString(m.Command, -1)
+	} else {
+		if err := checkURLTemplate(m.HTTP.URL); err != nil {
+			return nil, fmt.Errorf("tool %s: %w", m.Name, err)
+		}
+		body, _ := json.Marshal(m.HTTP.Body)
+		used = placeholderRegex.FindAllString(m.HTTP.URL+string(body), -1)
+		for _, k := range sortedKeys(m.HTTP.Headers) {
+			v := m.HTTP.Headers[k]
+			used = append(used, placeholderRegex.FindAllString(v, -1)...)
+			var denied string
+			os.Expand(v, func(name string) string {
+				if !t.env[name] && denied == "" {
+					denied = name
+				}
+				return ""
+			})
+			if denied != "" {
+				return nil, fmt.Errorf("tool %s: header %s uses ${%s}, which is not listed in tools.custom.env", m.Name, k, denied)
+			}
+		}
+		t.client = &http.Client{}
+	}
+
+	names := placeholderNames(used)
+	if m.Parameters == nil {
+		t.manifest.Parameters = schemaForPlaceholders(names)
+	} else if props, _ := m.Parameters["properties"].(map[string]any); props != nil {
+		for _, name := range names {
+			if _, ok := props[name]; !ok {
+				return nil, fmt.Errorf("tool %s: {{%s}} is not a declared parameter", m.Name, name)
+			}
+		}
+	}
+	return t, nil
+}
+
+// LoadCustomTools 載入目錄中所有 *.json 範本；單一檔案的錯誤不影響其他工具
+func LoadCustomTools(dir string, exec *ExecTool, env []string) ([]*CustomTool, []error) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	sort.Strings(files)
+	var loaded []*CustomTool
+	var errs []error
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var m CustomToolManifest
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&m); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(file), err))
+			continue
+		}
+		tool, err := NewCustomTool(m, exec, env)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(file), err))
+			continue
+		}
+		loaded = append(loaded, tool)
+	}
+	return loaded, errs
+}
+
+func (t *CustomTool) Name() string               { return t.manifest.Name }
+func (t *CustomTool) Description() string        { return t.manifest.Description }
+func (t *CustomTool) Parameters() map[string]any { return t.manifest.Parameters }
+
+func (t *CustomTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	timeout := t.manifest.Timeout
+	if timeout == 0 {
+		timeout = customToolDefaultTimeout
+	}
+	if t.words != nil {
+		return t.runCommand(ctx, args, timeout)
+	}
+	return t.runHTTP(ctx, args, timeout)
+}
+
+// runCommand 代入參數後，以 exec 的政策與隔離設定執行命令
+func (t *CustomTool) runCommand(ctx context.Context, args map[string]any, timeout int) *ToolResult {
+	words := make([][]cmdWord, len(t.words))
+	for i, stage := range t.words {
+		for _, w := range stage {
+			if name, whole := wholePlaceholder(w.text); whole {
+				v, ok := args[name]
+				if !ok || v == nil {
+					continue // 未提供的選用參數不產生空字串參數
+				}
+				text := formatArgValue(v)
+				if strings.HasPrefix(text, "-") {
+					return &ToolResult{ForLLM: fmt.Sprintf("Error: value of %q cannot start with \"-\"", name), IsError: true}
+				}
+				words[i] = append(words[i], cmdWord{text: text})
+				continue
+			}
+			text := placeholderRegex.ReplaceAllStringFunc(w.text, func(m string) string {
+				return formatArgValue(args[placeholderRegex.FindStringSubmatch(m)[1]])
+			})
+			words[i] = append(words[i], cmdWord{text: text, glob: w.glob})
+		}
+	}
+	stages, err := t.exec.checkStages(ctx, words)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: command rejected by the exec sandbox: %v", err), IsError: true}
+	}
+	return t.exec.run(ctx, stages, float64(timeout))
+}
+
+// runHTTP 代入參數後送出 HTTP 請求
+func (t *CustomTool) runHTTP(ctx context.Context, args map[string]any, timeout int) *ToolResult {
+	tpl := t.manifest.HTTP
+	target := interpolateURL(tpl.URL, args)
+
+	var body io.Reader
+	contentType := ""
+	if tpl.Body != nil {
+		switch b := interpolateJSON(tpl.Body, args).(type) {
+		case string:
+			body = strings.NewReader(b)
+			contentType = "text/plain; charset=utf-8"
+		default:
+			data, err := json.Marshal(b)
+			if err != nil {
+				return &ToolResult{ForLLM: fmt.Sprintf("Error: %v", err), IsError: true}
+			}
+			body = strings.NewReader(string(data))
+			contentType = "application/json"
+		}
+	}
+	method := strings.ToUpper(tpl.Method)
+	if method == "" {
+		method = http.MethodGet
+		if body != nil {
+			method = http.MethodPost
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range tpl.Headers {
+		// 先展開環境變數再代入參數，參數值中的 ${VAR} 不會被展開
+		v = placeholderRegex.ReplaceAllStringFunc(os.Expand(v, t.getenv), func(m string) string {
+			return formatArgValue(args[placeholderRegex.FindStringSubmatch(m)[1]])
+		})
+		if strings.ContainsAny(v, "\r\n") {
+			return &ToolResult{ForLLM: fmt.Sprintf("Error: header %s contains a line break", k), IsError: true}
+		}
+		req.Header.Set(k, v)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: request failed: %v", err), IsError: true}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, customToolMaxBody))
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: failed to read response: %v", err), IsError: true}
+	}
+	return &ToolResult{
+		ForLLM:  fmt.Sprintf("HTTP %s\n%s", resp.Status, data),
+		IsError: resp.StatusCode >= 400,
+	}
+}
+
+// getenv 只回傳允許的環境變數
+func (t *CustomTool) getenv(name string) string {
+	if !t.env[name] {
+		return ""
+	}
+	return os.Getenv(name)
+}
+
+// checkURLTemplate 確認範本是 http(s) URL，且主機名稱不含參數
+func checkURLTemplate(tpl string) error {
+	rest, ok := strings.CutPrefix(tpl, "https://")
+	if !ok {
+		if rest, ok = strings.CutPrefix(tpl, "http://"); !ok {
+			return fmt.Errorf("http.url must start with http:// or https://")
+		}
+	}
+	host, _, _ := strings.Cut(rest, "/")
+	host, _, _ = strings.Cut(host, "?")
+	if host == "" || strings.Contains(host, "{{") {
+		return fmt.Errorf("http.url must have a fixed host")
+	}
+	return nil
+}
+
+// interpolateURL 代入參數：? 之前以路徑編碼，之後以查詢字串編碼
+func interpolateURL(tpl string, args map[string]any) string {
+	path, query, hasQuery := strings.Cut(tpl, "?")
+	replace := func(s string, escape func(string) string) string {
+		return placeholderRegex.ReplaceAllStringFunc(s, func(m string) string {
+			return escape(formatArgValue(args[placeholderRegex.FindStringSubmatch(m)[1]]))
+		})
+	}
+	out := replace(path, url.PathEscape)
+	if hasQuery {
+		out += "?" + replace(query, url.QueryEscape)
+	}
+	return out
+}
+
+// interpolateJSON 代入 JSON 範本中的參數；值剛好是 "{{x}}" 的字串會換成參數原本的值
+func interpolateJSON(v any, args map[string]any) any {
+	switch v := v.(type) {
+	case string:
+		if name, whole := wholePlaceholder(v); whole {
+			return args[name]
+		}
+		return placeholderRegex.ReplaceAllStringFunc(v, func(m string) string {
+			return formatArgValue(args[placeholderRegex.FindStringSubmatch(m)[1]])
+		})
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = interpolateJSON(item, args)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = interpolateJSON(item, args)
+		}
+		return out
+	}
+	return v
+}
+
+// wholePlaceholder 判斷字串是否只有一個 {{參數}}
+func wholePlaceholder(s string) (string, bool) {
+	m := placeholderRegex.FindStringSubmatch(s)
+	if m == nil || m[0] != s {
+		return "", false
+	}
+	return m[1], true
+}
+
+// formatArgValue 將參數值轉為字串：整數不帶小數點，其他非字串值使用 JSON
+func formatArgValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func placeholderNames(matches []string) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, m := range matches {
+		name := placeholderRegex.FindStringSubmatch(m)[1]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// schemaForPlaceholders 為未宣告 Schema 的範本產生參數：每個 {{參數}} 都是必填字串
+func schemaForPlaceholders(names []string) map[string]any {
+	props := map[string]any{}
+	for _, name := range names {
+		props[name] = map[string]any{"type": "string"}
+	}
+	required := append([]string{}, names...)
+	return map[string]any{"type": "object", "properties": props, "required": required}
+}
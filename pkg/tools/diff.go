@@ -0,0 +1,208 @@
+package tools
+
+// ============================================================================
+// 行差異比對 (Line Diff)
+// ============================================================================
+// 產生 unified diff 格式的摘要，讓 Agent 在修改檔案後能確認實際變更。
+//
+// 設計原理：
+//   - 先去除前後相同的行，只對中間變動區段做 LCS 比對
+//   - 變動區段過大時不做 LCS，直接視為整段刪除與新增，避免耗用大量記憶體
+// ============================================================================
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContextLines = 3
+	diffMaxLCSCells  = 4_000_000 // LCS 表格上限 (行數相乘)
+)
+
+type diffOp struct {
+	kind byte // ' ' 相同、'-' 刪除、'+' 新增
+	text string
+}
+
+// splitLines 依換行切割，最後的換行不產生空行
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines 計算兩組行之間的編輯序列
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, l := range a[:prefix] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
+
+func diffMiddle(a, b []string) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > diffMaxLCSCells || len(a) == 0 || len(b) == 0 {
+		for _, l := range a {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffOp{'+', l})
+		}
+		return ops
+	}
+
+	// lcs[i][j] = a[i:] 與 b[j:] 的最長共同子序列長度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// unifiedDiff 產生 unified diff；內容相同時回傳空字串
+//
+// 參數：
+//   - path:     顯示在檔頭的檔案路徑
+//   - oldText:  修改前內容
+//   - newText:  修改後內容
+//   - maxLines: 輸出的最大行數 (不含檔頭)，0 表示不限制
+func unifiedDiff(path, oldText, newText string, maxLines int) string {
+	if oldText == newText {
+		return ""
+	}
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", path, path)
+	written := 0
+	for _, h := range buildHunks(ops) {
+		for _, line := range h {
+			if maxLines > 0 && written >= maxLines {
+				fmt.Fprintf(&sb, "... (diff truncated)\n")
+				return sb.String()
+			}
+			sb.WriteString(line + "\n")
+			written++
+		}
+	}
+	return sb.String()
+}
+
+// buildHunks 將編輯序列分組為含上下文的 hunk，每個 hunk 以 "@@" 標頭開始
+func buildHunks(ops []diffOp) [][]string {
+	var hunks [][]string
+	i := 0
+	oldLine, newLine := 1, 1
+	// 記錄每個位置對應的舊/新行號
+	oldAt := make([]int, len(ops)+1)
+	newAt := make([]int, len(ops)+1)
+	for k, op := range ops {
+		oldAt[k], newAt[k] = oldLine, newLine
+		if op.kind != '+' {
+			oldLine++
+		}
+		if op.kind != '-' {
+			newLine++
+		}
+	}
+	oldAt[len(ops)], newAt[len(ops)] = oldLine, newLine
+
+	for i < len(ops) {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := max(i-diffContextLines, 0)
+		end := i
+		// 延伸 hunk：相鄰變更之間的相同行不超過 2 倍上下文時合併
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run < len(ops) && run-end <= 2*diffContextLines {
+				end = run
+				continue
+			}
+			end = min(end+diffContextLines, len(ops))
+			break
+		}
+
+		oldCount, newCount := 0, 0
+		lines := []string{""}
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+			lines = append(lines, string(op.kind)+op.text)
+		}
+		lines[0] = fmt.Sprintf("@@ -%s +%s @@", hunkRange(oldAt[start], oldCount), hunkRange(newAt[start], newCount))
+		hunks = append(hunks, lines)
+		i = end
+	}
+	return hunks
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	newText := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+
+	got := unifiedDiff("x.txt", oldText, newText, 0)
+	want := "--- a/x.txt\n+++ b/x.txt\n" +
+		"@@ -1,7 +1,7 @@\n a\n b\n c\n-d\n+D\n e\n f\n g\n" +
+		"@@ -10,3 +10,4 @@\n j\n k\n l\n+m\n"
+	if got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	if unifiedDiff("x.txt", "same\n", "same\n", 0) != "" {
+		t.Error("expected empty diff for identical content")
+	}
+
+	created := unifiedDiff("new.txt", "", "one\ntwo\n", 0)
+	if !strings.Contains(created, "@@ -0,0 +1,2 @@\n+one\n+two\n") {
+		t.Errorf("unexpected diff for new file:\n%s", created)
+	}
+
+	truncated := unifiedDiff("x.txt", "", strings.Repeat("line\n", 100), 5)
+	if !strings.HasSuffix(truncated, "... (diff truncated)\n") {
+		t.Errorf("expected truncated diff:\n%s", truncated)
+	}
+}
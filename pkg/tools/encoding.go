@@ -0,0 +1,113 @@
+package tools
+
+// ============================================================================
+// 文字編碼偵測 (Text Encoding Detection)
+// ============================================================================
+// read_file 使用的編碼判斷：
+//   - 有 BOM 的 UTF-16 / UTF-8 依 BOM 解碼
+//   - 含 NUL 字元視為二進位檔
+//   - 合法的 UTF-8 直接使用
+//   - 否則嘗試 Big5 (台灣常見的舊文件編碼)，解碼後必須沒有無效位元組且以中文字為主
+//   - 都不符合時視為未知編碼，由呼叫端改用 encoding 參數指定
+// ============================================================================
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/traditionalchinese"
+	textunicode "golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// textEncoding 是偵測結果
+type textEncoding struct {
+	Name     string            // 顯示用名稱，例如 "UTF-8"、"Big5"
+	Decoder  encoding.Encoding // nil 表示 UTF-8，不需要轉換
+	Binary   bool              // 二進位檔
+	Unknown  bool              // 非 UTF-8 且無法判斷編碼
+	Explicit bool              // 由呼叫端指定
+}
+
+// lookupEncoding 依名稱取得編碼 (使用 WHATWG 名稱，例如 big5、gbk、shift_jis、utf-16le)
+func lookupEncoding(name string) (textEncoding, error) {
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return textEncoding{}, fmt.Errorf("unsupported encoding %q", name)
+	}
+	canonical, _ := htmlindex.Name(enc)
+	if canonical == "utf-8" {
+		return textEncoding{Name: "UTF-8", Explicit: true}, nil
+	}
+	return textEncoding{Name: canonical, Decoder: enc, Explicit: true}, nil
+}
+
+// detectEncoding 依檔案開頭的取樣判斷編碼
+func detectEncoding(sample []byte) textEncoding {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return textEncoding{Name: "UTF-16LE", Decoder: textunicode.UTF16(textunicode.LittleEndian, textunicode.ExpectBOM)}
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return textEncoding{Name: "UTF-16BE", Decoder: textunicode.UTF16(textunicode.BigEndian, textunicode.ExpectBOM)}
+	case isBinary(sample):
+		return textEncoding{Binary: true}
+	case validUTF8Sample(sample):
+		return textEncoding{Name: "UTF-8"}
+	case looksLikeBig5(sample):
+		return textEncoding{Name: "Big5", Decoder: traditionalchinese.Big5}
+	}
+	return textEncoding{Unknown: true}
+}
+
+// validUTF8Sample 判斷取樣是否為合法 UTF-8；取樣可能切在多位元組字元的中間，結尾不完整的字元不算錯誤
+func validUTF8Sample(b []byte) bool {
+	if utf8.Valid(b) {
+		return true
+	}
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return utf8.Valid(b[:len(b)-i])
+			}
+			break
+		}
+	}
+	return false
+}
+
+// looksLikeBig5 判斷取樣以 Big5 解碼後是否合理：沒有無效位元組，且非 ASCII 字元以中文字與全形標點為主
+func looksLikeBig5(b []byte) bool {
+	decoded, _, err := transform.Bytes(traditionalchinese.Big5.NewDecoder(), b)
+	if err != nil {
+		return false
+	}
+	// 取樣可能切在雙位元組字元的中間
+	text := strings.TrimSuffix(string(decoded), "\uFFFD")
+
+	var cjk, other int
+	for _, r := range text {
+		switch {
+		case r == utf8.RuneError:
+			return false
+		case r < utf8.RuneSelf:
+		case isCJK(r):
+			cjk++
+		default:
+			other++
+		}
+	}
+	return cjk > 0 && cjk >= other*4
+}
+
+// isCJK 判斷是否為中文字、注音或全形標點
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Bopomofo, r) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK 標點
+		(r >= 0xFF00 && r <= 0xFFEF) // 全形字元
+}
@@ -0,0 +1,72 @@
+package tools
+
+// ============================================================================
+// ExecIsolation: exec 子行程的 OS 層級隔離
+// ============================================================================
+// ExecPolicy 決定「可以執行什麼」，ExecIsolation 則限制「執行後能做什麼」，
+// 讓字串檢查不再是唯一的防線：
+//   - 環境變數只保留 PATH、語系等基本項目，.env 中的 API 金鑰不會傳給子行程
+//   - HOME 指向每條管線專用的空暫存目錄，結束後刪除；工作區中 Agent 寫入的
+//     .gitconfig 等設定檔不會被當成使用者設定載入 (例如 core.fsmonitor 可以執行任意命令)
+//   - 每個管線階段都在新的行程群組中執行，逾時時整個群組一起被終止
+//   - Linux 上以 rlimit 限制 CPU 時間、記憶體、檔案大小與行程數
+//   - Linux 上可選擇使用 user/mount/network namespace：
+//     整個檔案系統唯讀、只有工作區可寫入 (工作區內的唯讀根目錄與內部目錄除外)，並且沒有網路
+//
+// rlimit 與 namespace 必須在目標程式啟動前設定，因此 Linux 上子行程會先以
+// 本程式自身 (os.Executable) 啟動，由 init 中的輔助程式完成設定後再 exec 目標程式。
+// ============================================================================
+
+import (
+	"os"
+	"strings"
+)
+
+// newHome 建立子行程專用的空 HOME 目錄 (權限 0700)，呼叫端負責刪除
+func newHome() (string, error) {
+	return os.MkdirTemp("", "minibot-home-")
+}
+
+// ExecIsolation 是 exec 子行程的資源限制與隔離設定，數值為 0 表示不限制
+type ExecIsolation struct {
+	CPUSeconds    uint64   // RLIMIT_CPU
+	MemoryBytes   uint64   // RLIMIT_AS
+	FileSizeBytes uint64   // RLIMIT_FSIZE
+	MaxProcesses  uint64   // RLIMIT_NPROC (以使用者為單位計算)
+	Namespaces    bool     // 使用 user/mount/network namespace
+	AllowNetwork  bool     // 使用 namespace 時仍保留網路
+	PassEnv       []string // 額外傳給子行程的環境變數名稱
+
+	readOnly []string // 使用 namespace 時重新掛載為唯讀的目錄，由 ExecTool 依 Sandbox 設定
+}
+
+// DefaultExecIsolation 回傳預設的資源限制 (不使用 namespace)
+func DefaultExecIsolation() *ExecIsolation {
+	return &ExecIsolation{
+		CPUSeconds:    60,
+		MemoryBytes:   2 << 30,
+		FileSizeBytes: 256 << 20,
+		MaxProcesses:  512,
+	}
+}
+
+// baseEnv 是一律傳給子行程的環境變數，其餘 (包含 .env 載入的金鑰) 都不會傳遞
+var baseEnv = []string{"PATH", "LANG", "LANGUAGE", "LC_ALL", "LC_CTYPE", "LC_MESSAGES", "TZ", "TERM"}
+
+// Environ 建立子行程的環境變數，HOME 指向 home；
+// 外掛等其他外部程式也使用同一份清理過的環境變數
+func (iso *ExecIsolation) Environ(home string) []string {
+	env := []string{"HOME=" + home}
+	seen := map[string]bool{"HOME": true}
+	for _, name := range append(append([]string{}, baseEnv...), iso.PassEnv...) {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] || strings.ContainsRune(name, '=') {
+			continue
+		}
+		seen[name] = true
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	return env
+}
@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// execHelperEnv 帶有輔助程式的設定；子行程看到它時會在 init 中接手，不會執行一般的 main
+const execHelperEnv = "MINIBOT_EXEC_ISOLATION"
+
+// helperSpec 是傳給輔助程式的設定
+type helperSpec struct {
+	Limits     map[int]uint64 `json:"limits,omitempty"`
+	Namespaces bool           `json:"namespaces,omitempty"`
+	Workspace  string         `json:"workspace"`
+	Home       string         `json:"home"`
+	ReadOnly   []string       `json:"readOnly,omitempty"`
+}
+
+// Linux 的 rlimit 編號 (syscall 套件沒有匯出 RLIMIT_NPROC)
+const (
+	rlimitCPU   = 0
+	rlimitFsize = 1
+	rlimitNproc = 6
+	rlimitAS    = 9
+)
+
+func init() {
+	if spec, ok := os.LookupEnv(execHelperEnv); ok {
+		runExecHelper(spec)
+	}
+}
+
+// command 建立一個管線階段的子行程：經由輔助程式套用限制後再執行 argv，HOME 指向 home
+func (iso *ExecIsolation) command(ctx context.Context, dir, home string, argv []string) (*exec.Cmd, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("exec sandbox unavailable: %w", err)
+	}
+	spec := helperSpec{
+		Limits: map[int]uint64{
+			rlimitCPU:   iso.CPUSeconds,
+			rlimitAS:    iso.MemoryBytes,
+			rlimitFsize: iso.FileSizeBytes,
+			rlimitNproc: iso.MaxProcesses,
+		},
+		Namespaces: iso.Namespaces,
+		Workspace:  dir,
+		Home:       home,
+		ReadOnly:   iso.readOnly,
+	}
+	data, _ := json.Marshal(spec)
+
+	c := exec.CommandContext(ctx, self)
+	c.Args = argv
+	c.Dir = dir
+	c.Env = append(iso.Environ(home), execHelperEnv+"="+string(data))
+	c.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+	}
+	if iso.Namespaces {
+		c.SysProcAttr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS
+		if !iso.AllowNetwork {
+			c.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+		}
+		c.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		c.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+		c.SysProcAttr.GidMappingsEnableSetgroups = false
+	}
+	// 逾時時終止整個行程群組，包含目標程式自行產生的子行程
+	c.Cancel = func() error {
+		return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+	}
+	c.WaitDelay = 2 * time.Second
+	return c, nil
+}
+
+// runExecHelper 在子行程中套用 namespace 與 rlimit，然後以 exec 取代自身為目標程式
+func runExecHelper(rawSpec string) {
+	fail := func(format string, a ...any) {
+		fmt.Fprintf(os.Stderr, "exec sandbox: "+format+"\n", a...)
+		os.Exit(126)
+	}
+
+	var spec helperSpec
+	if err := json.Unmarshal([]byte(rawSpec), &spec); err != nil {
+		fail("invalid configuration: %v", err)
+	}
+	os.Unsetenv(execHelperEnv)
+	if len(os.Args) == 0 {
+		fail("missing command")
+	}
+
+	if spec.Namespaces {
+		if err := restrictFilesystem(spec.Workspace, spec.Home, spec.ReadOnly); err != nil {
+			fail("%v", err)
+		}
+	}
+	for resource, limit := range spec.Limits {
+		if limit == 0 {
+			continue
+		}
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: limit, Max: limit}); err != nil {
+			fail("setrlimit %d: %v", resource, err)
+		}
+	}
+
+	path, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "exec sandbox: %v\n", err)
+		os.Exit(127)
+	}
+	err = syscall.Exec(path, os.Args, os.Environ())
+	fail("%s: %v", os.Args[0], err)
+}
+
+// mount_setattr(2) 相關常數 (Linux 5.12 起提供，所有架構使用相同的系統呼叫編號)
+const (
+	sysMountSetattr  = 442
+	atRecursive      = 0x8000
+	mountAttrRdonly  = 0x1
+	atFdcwd          = -100
+	mountAttrSizeVer = 32
+)
+
+type mountAttr struct {
+	attrSet     uint64
+	attrClr     uint64
+	propagation uint64
+	userns      uint64
+}
+
+func mountSetattr(path string, flags int, attr *mountAttr) error {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	fd := atFdcwd
+	_, _, errno := syscall.Syscall6(sysMountSetattr, uintptr(fd), uintptr(unsafe.Pointer(p)),
+		uintptr(flags), uintptr(unsafe.Pointer(attr)), mountAttrSizeVer, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// restrictFilesystem 在新的 mount namespace 中把所有掛載點設為唯讀，只保留工作區與 HOME 可寫入；
+// 工作區內的 readOnly 目錄 (唯讀根目錄與內部目錄) 再各自綁定為唯讀的掛載點
+func restrictFilesystem(workspace, home string, readOnly []string) error {
+	// 避免變更傳播回原本的 namespace
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+	// 工作區與 HOME 先成為獨立的掛載點，之後才能單獨恢復寫入權限
+	writable := []string{workspace, home}
+	for _, dir := range writable {
+		if err := syscall.Mount(dir, dir, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("bind %s: %w", dir, err)
+		}
+	}
+	if err := mountSetattr("/", atRecursive, &mountAttr{attrSet: mountAttrRdonly}); err != nil {
+		return fmt.Errorf("make filesystem read-only: %w", err)
+	}
+	for _, dir := range writable {
+		if err := mountSetattr(dir, 0, &mountAttr{attrClr: mountAttrRdonly}); err != nil {
+			return fmt.Errorf("make %s writable: %w", dir, err)
+		}
+	}
+	for _, dir := range readOnly {
+		if !within(dir, workspace) {
+			continue // 工作區外的目錄已經是唯讀
+		}
+		if err := syscall.Mount(dir, dir, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("bind read-only directory %s: %w", dir, err)
+		}
+		if err := mountSetattr(dir, atRecursive, &mountAttr{attrSet: mountAttrRdonly}); err != nil {
+			return fmt.Errorf("make %s read-only: %w", dir, err)
+		}
+	}
+	// 工作目錄仍指向綁定前 (唯讀) 的掛載點，必須重新進入
+	return syscall.Chdir(workspace)
+}
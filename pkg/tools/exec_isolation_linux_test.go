@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecIsolation_Environment(t *testing.T) {
+	ws := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	t.Setenv("MINIBOT_TEST_API_KEY", "secret-value")
+	t.Setenv("MINIBOT_TEST_PASSED", "visible")
+
+	tool := ExecTool{
+		Sandbox:   sandbox,
+		Policy:    DefaultExecPolicy().Merge(map[string]CommandRule{"env": {}}),
+		Isolation: &ExecIsolation{PassEnv: []string{"MINIBOT_TEST_PASSED"}},
+	}
+	result := tool.Execute(context.Background(), map[string]any{"command": "env"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	if strings.Contains(result.ForLLM, "secret-value") || strings.Contains(result.ForLLM, execHelperEnv) {
+		t.Errorf("environment was not scrubbed: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "MINIBOT_TEST_PASSED=visible") {
+		t.Errorf("expected passed variables, got %s", result.ForLLM)
+	}
+
+	// HOME 是每條管線專用的空目錄，不是工作區 (Agent 寫入的 .gitconfig 等設定檔不會被載入)
+	home := regexp.MustCompile(`(?m)(?:^|Output: )HOME=(.*)$`).FindStringSubmatch(result.ForLLM)
+	if home == nil || home[1] == ws || strings.HasPrefix(home[1], ws+string(filepath.Separator)) {
+		t.Fatalf("HOME must be a private directory outside the workspace, got %v", home)
+	}
+	if _, err := os.Stat(home[1]); !os.IsNotExist(err) {
+		t.Errorf("the private HOME %s should be removed after the command", home[1])
+	}
+	os.WriteFile(filepath.Join(ws, ".profile-marker"), []byte("x"), 0644)
+	tool.Policy = DefaultExecPolicy().Merge(map[string]CommandRule{"sh": {}})
+	result = tool.Execute(context.Background(), map[string]any{"command": `sh -c 'ls -A $HOME; touch $HOME/.gitconfig && echo writable'`})
+	if result.IsError || strings.Contains(result.ForLLM, ".profile-marker") || !strings.Contains(result.ForLLM, "writable") {
+		t.Errorf("HOME should be empty and writable, got %s", result.ForLLM)
+	}
+}
+
+func TestExecIsolation_Limits(t *testing.T) {
+	sandbox, _ := NewSandbox(t.TempDir())
+	tool := ExecTool{
+		Sandbox:   sandbox,
+		Policy:    DefaultExecPolicy().Merge(map[string]CommandRule{"sh": {}}),
+		Isolation: &ExecIsolation{CPUSeconds: 7, FileSizeBytes: 1 << 20},
+	}
+	result := tool.Execute(context.Background(), map[string]any{"command": `sh -c "cat /proc/self/limits"`})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	for _, want := range []string{`Max cpu time\s+7\s+7`, `Max file size\s+1048576\s+1048576`} {
+		if !regexp.MustCompile(want).MatchString(result.ForLLM) {
+			t.Errorf("limit %q not applied:\n%s", want, result.ForLLM)
+		}
+	}
+
+	// 缺少的程式回報錯誤，而不是執行輔助程式本身
+	tool.Policy = DefaultExecPolicy().Merge(map[string]CommandRule{"no-such-program": {}})
+	result = tool.Execute(context.Background(), map[string]any{"command": "no-such-program"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "executable file not found") {
+		t.Errorf("unexpected result: %s", result.ForLLM)
+	}
+}
+
+func TestExecIsolation_Namespaces(t *testing.T) {
+	ws := t.TempDir()
+	outside := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	tool := ExecTool{
+		Sandbox:   sandbox,
+		Policy:    DefaultExecPolicy().Merge(map[string]CommandRule{"sh": {}}),
+		Isolation: &ExecIsolation{Namespaces: true},
+	}
+	run := func(script string) *ToolResult {
+		return tool.Execute(context.Background(), map[string]any{"command": "sh -c '" + script + "'"})
+	}
+
+	result := run("echo inside > inside.txt")
+	if result.IsError && strings.Contains(result.ForLLM, "exec sandbox:") {
+		t.Skipf("namespaces unavailable: %s", result.ForLLM)
+	}
+	if result.IsError {
+		t.Fatalf("writing inside the workspace failed: %s", result.ForLLM)
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, "inside.txt")); string(data) != "inside\n" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	result = run("echo outside > " + filepath.Join(outside, "x.txt"))
+	if !result.IsError || !strings.Contains(result.ForLLM, "Read-only file system") {
+		t.Errorf("expected read-only error, got %s", result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "x.txt")); !os.IsNotExist(err) {
+		t.Error("file was written outside the workspace")
+	}
+
+	// 工作區內的唯讀根目錄與內部目錄以唯讀掛載保護
+	os.MkdirAll(filepath.Join(ws, "tools"), 0755)
+	if err := sandbox.AddRoot("tools", filepath.Join(ws, "tools"), true); err != nil {
+		t.Fatal(err)
+	}
+	sandbox.Protect(filepath.Join(ws, ".checkpoints"))
+	for _, target := range []string{"tools/x.json", ".checkpoints/manifest.json"} {
+		result = run("echo forged > " + target)
+		if !result.IsError || !strings.Contains(result.ForLLM, "Read-only file system") {
+			t.Errorf("%s: expected read-only error, got %s", target, result.ForLLM)
+		}
+		if _, err := os.Stat(filepath.Join(ws, target)); !os.IsNotExist(err) {
+			t.Errorf("%s was written inside a read-only directory", target)
+		}
+	}
+	if result = run("echo cache > $HOME/cache.txt"); result.IsError {
+		t.Errorf("the private HOME should stay writable: %s", result.ForLLM)
+	}
+	if result = run("echo still > inside.txt"); result.IsError {
+		t.Errorf("the rest of the workspace should stay writable: %s", result.ForLLM)
+	}
+
+	// 新的 network namespace 只有 loopback 介面
+	result = run("tail -n +3 /proc/net/dev")
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	for _, line := range strings.Split(strings.TrimPrefix(result.ForLLM, "Command exited successfully.\nOutput: "), "\n") {
+		if name, _, ok := strings.Cut(strings.TrimSpace(line), ":"); ok && name != "lo" {
+			t.Errorf("unexpected network interface %q", name)
+		}
+	}
+}
+
+func TestExecIsolation_TimeoutKillsProcessGroup(t *testing.T) {
+	ws := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	tool := ExecTool{Sandbox: sandbox, Policy: DefaultExecPolicy().Merge(map[string]CommandRule{"sh": {}})}
+
+	// 背景的孫行程也必須隨逾時一起被終止
+	result := tool.Execute(context.Background(), map[string]any{
+		"command": `sh -c "(sleep 1.5; echo late > late.txt) & sleep 30"`,
+		"timeout": float64(1),
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "Timeout") {
+		t.Fatalf("expected timeout, got %s", result.ForLLM)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(filepath.Join(ws, "late.txt")); !os.IsNotExist(err) {
+		t.Error("background process survived the timeout")
+	}
+}
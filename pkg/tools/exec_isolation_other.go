@@ -0,0 +1,22 @@
+//go:build !linux
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// command 建立一個管線階段的子行程；非 Linux 平台只清理環境變數 (HOME 指向 home)，不套用 rlimit 與 namespace
+func (iso *ExecIsolation) command(ctx context.Context, dir, home string, argv []string) (*exec.Cmd, error) {
+	if iso.Namespaces {
+		return nil, fmt.Errorf("namespace isolation is only supported on Linux")
+	}
+	c := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	c.Dir = dir
+	c.Env = iso.Environ(home)
+	c.WaitDelay = 2 * time.Second
+	return c, nil
+}
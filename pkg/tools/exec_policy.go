@@ -0,0 +1,276 @@
+package tools
+
+// ============================================================================
+// ExecPolicy: 命令執行政策
+// ============================================================================
+// 決定 exec 工具可以執行哪些程式、以及每個程式允許哪些參數。
+//
+// 規則 (以程式名稱為鍵)：
+//   - 未列出的程式一律拒絕
+//   - Deny：任一規則符合即拒絕。規則的各個詞依序出現在參數中即算符合 (中間可夾雜其他參數)，
+//     例如 "reset --hard" 會拒絕 git reset -q --hard；"*" 表示拒絕整個程式
+//   - Allow：空白表示允許任何參數；否則參數必須以其中一條規則開頭，
+//     例如 "status" 允許 git status -s，但不允許 git -c x status
+//   - 規則中的每個詞可使用 * 與 ? 萬用字元，例如 "--output=*"
+//   - 單一字母的短選項規則 (例如 "-o") 也會比對附加值 (-o/tmp/x) 與合併的短選項 (-uo x)
+//   - 長選項規則 (例如 "--output*") 也會比對 GNU getopt 接受的縮寫 (--o、--ou=x)
+//
+// 另外，所有指向工作區外的路徑參數 (絕對路徑、~、含 .. 的路徑，以及經過工作區內
+// 指向外部的符號連結的相對路徑) 都會被拒絕，包含 --file=/etc/passwd 與
+// -f/etc/passwd 這類附加在選項後的寫法。
+// ============================================================================
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CommandRule 是單一程式的參數規則
+type CommandRule struct {
+	Allow []string // 允許的參數開頭，空白表示不限制
+	Deny  []string // 拒絕的參數組合
+}
+
+// ExecPolicy 是 exec 工具的執行政策
+type ExecPolicy struct {
+	Commands map[string]CommandRule
+}
+
+// DefaultExecPolicy 回傳預設政策：只允許唯讀的檔案檢視與文字處理命令
+func DefaultExecPolicy() *ExecPolicy {
+	return &ExecPolicy{Commands: map[string]CommandRule{
+		"ls": {}, "pwd": {}, "stat": {}, "file": {},
+		"cat": {}, "head": {}, "tail": {}, "wc": {}, "diff": {},
+		"grep": {}, "egrep": {}, "fgrep": {},
+		"cut": {}, "tr": {}, "uniq": {}, "echo": {}, "printf": {},
+		"basename": {}, "dirname": {}, "md5sum": {}, "sha256sum": {}, "sleep": {},
+		"sort": {Deny: []string{"-o", "--output*"}},
+		"tree": {Deny: []string{"-o"}},
+		"date": {Deny: []string{"-s", "--set*"}},
+		"find": {Deny: []string{"-exec", "-execdir", "-ok", "-okdir", "-delete", "-fprint*", "-fls"}},
+	}}
+}
+
+// Merge 以 overrides 覆蓋同名程式的規則，回傳新的政策
+func (p *ExecPolicy) Merge(overrides map[string]CommandRule) *ExecPolicy {
+	merged := &ExecPolicy{Commands: make(map[string]CommandRule, len(p.Commands)+len(overrides))}
+	for name, rule := range p.Commands {
+		merged.Commands[name] = rule
+	}
+	for name, rule := range overrides {
+		merged.Commands[name] = rule
+	}
+	return merged
+}
+
+// Check 檢查一個 argv 是否符合政策
+func (p *ExecPolicy) Check(argv []string) error {
+	if len(argv) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	name, args := argv[0], argv[1:]
+	rule, ok := p.Commands[name]
+	if !ok {
+		return fmt.Errorf("%q is not an allowed command; allowed commands: %s", name, strings.Join(p.names(), ", "))
+	}
+
+	for _, deny := range rule.Deny {
+		if matchDenyRule(deny, args) {
+			return fmt.Errorf("%q is denied by the exec policy (rule %q)", strings.Join(argv, " "), name+" "+deny)
+		}
+	}
+	if len(rule.Allow) > 0 {
+		allowed := false
+		for _, allow := range rule.Allow {
+			if matchAllowRule(allow, args) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%q is not allowed by the exec policy; %s may only be run as: %s %s",
+				strings.Join(argv, " "), name, name, strings.Join(rule.Allow, " | "+name+" "))
+		}
+	}
+	return nil
+}
+
+func (p *ExecPolicy) names() []string {
+	names := make([]string, 0, len(p.Commands))
+	for name, rule := range p.Commands {
+		if !deniesAll(rule) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func deniesAll(rule CommandRule) bool {
+	for _, d := range rule.Deny {
+		if strings.TrimSpace(d) == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAllowRule 判斷參數是否以規則的各個詞開頭
+func matchAllowRule(rule string, args []string) bool {
+	words := strings.Fields(rule)
+	if len(words) == 1 && words[0] == "*" {
+		return true
+	}
+	if len(words) > len(args) {
+		return false
+	}
+	for i, w := range words {
+		if !matchWildcard(w, args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchDenyRule 判斷規則的各個詞是否依序出現在參數中
+func matchDenyRule(rule string, args []string) bool {
+	words := strings.Fields(rule)
+	if len(words) == 0 {
+		return false
+	}
+	if len(words) == 1 && words[0] == "*" {
+		return true
+	}
+	i := 0
+	for _, arg := range args {
+		if matchDenyWord(words[i], arg) {
+			if i++; i == len(words) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchDenyWord 比對拒絕規則的單一詞。單一字母的短選項 (例如 "-o") 依 getopt 的寫法
+// 也會比對 -o/tmp/x 與 -uo：合併的短選項中，第一個非字母字元之後是前一個選項的值
+func matchDenyWord(word, arg string) bool {
+	if matchWildcard(word, arg) {
+		return true
+	}
+	if isLongOption(word) {
+		return matchLongAbbrev(word, arg)
+	}
+	if !isShortOption(word) || !isShortOption(arg[:min(len(arg), 2)]) {
+		return false
+	}
+	for _, r := range arg[1:] {
+		if r == rune(word[1]) {
+			return true
+		}
+		if !isASCIILetter(r) {
+			return false
+		}
+	}
+	return false
+}
+
+// isLongOption 判斷 s 是否為長選項規則，例如 "--output" 或 "--output*"
+func isLongOption(s string) bool {
+	return strings.HasPrefix(s, "--") && len(s) > 2 && isASCIILetter(rune(s[2]))
+}
+
+// matchLongAbbrev 比對長選項的縮寫：getopt 接受任何不含糊的前綴，
+// 所以 --o、--ou、--out=x 都可能是 --output。規則名稱中第一個萬用字元之前的部分視為完整名稱
+func matchLongAbbrev(word, arg string) bool {
+	if !strings.HasPrefix(arg, "--") || len(arg) <= 2 {
+		return false
+	}
+	name := word
+	if i := strings.IndexAny(name, "*?="); i >= 0 {
+		name = name[:i]
+	}
+	given, _, _ := strings.Cut(arg, "=")
+	return strings.HasPrefix(name, given)
+}
+
+// isShortOption 判斷 s 是否為單一字母的短選項，例如 "-o"
+func isShortOption(s string) bool {
+	return len(s) == 2 && s[0] == '-' && isASCIILetter(rune(s[1]))
+}
+
+func isASCIILetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// matchWildcard 比對 * (任意字元) 與 ? (單一字元)，與 path.Match 不同，* 也會比對 "/"
+func matchWildcard(pattern, s string) bool {
+	p, t := []rune(pattern), []rune(s)
+	star, match := -1, 0
+	i, j := 0, 0
+	for j < len(t) {
+		switch {
+		case i < len(p) && (p[i] == '?' || p[i] == t[j]):
+			i++
+			j++
+		case i < len(p) && p[i] == '*':
+			star, match = i, j
+			i++
+		case star >= 0:
+			i = star + 1
+			match++
+			j = match
+		default:
+			return false
+		}
+	}
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+	return i == len(p)
+}
+
+// checkPathArgs 拒絕指向工作區外的路徑參數 (包含 --file=/etc/passwd 與 -f/etc/passwd 這類寫法)。
+// guardReadOnly 為 true 時，指向唯讀根目錄或內部目錄的參數也會被拒絕：
+// 子行程沒有唯讀掛載保護時，無法得知程式是否會寫入這些參數
+func checkPathArgs(sandbox *Sandbox, argv []string, guardReadOnly bool) error {
+	for _, arg := range argv[1:] {
+		candidates := []string{arg}
+		if strings.HasPrefix(arg, "-") {
+			if _, value, ok := strings.Cut(arg, "="); ok {
+				candidates = []string{value}
+			} else if value, ok := attachedValue(arg); ok {
+				candidates = []string{value}
+			} else {
+				continue
+			}
+		}
+		for _, c := range candidates {
+			// 相對路徑也要解析符號連結，工作區內的連結可能指向外部
+			abs, err := sandbox.CheckPath(c)
+			if strings.HasPrefix(c, "~") || err != nil {
+				return fmt.Errorf("argument %q refers to a path outside the workspace", arg)
+			}
+			if guardReadOnly {
+				if err := sandbox.checkWritable(abs, c); err != nil {
+					return fmt.Errorf("argument %q: %v; use the file tools to read it", arg, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// attachedValue 取出附加在短選項後的值，例如 -o/tmp/x、-uo../x 的 "/tmp/x"、"../x"。
+// 不知道哪些選項需要值，所以從第一個可能是路徑開頭的字元起視為值
+func attachedValue(arg string) (string, bool) {
+	if len(arg) <= 2 || strings.HasPrefix(arg, "--") {
+		return "", false
+	}
+	i := strings.IndexAny(arg[2:], `/\~.`)
+	if i < 0 {
+		return "", false
+	}
+	return arg[2+i:], true
+}
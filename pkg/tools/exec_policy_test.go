@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecPolicy_Check(t *testing.T) {
+	policy := DefaultExecPolicy().Merge(map[string]CommandRule{
+		"git":   {Allow: []string{"status", "log", "diff"}, Deny: []string{"push", "--output=*", "log -p"}},
+		"go":    {Allow: []string{"test ./...", "vet"}},
+		"sleep": {Deny: []string{"*"}},
+	})
+
+	allowed := [][]string{
+		{"ls", "-la"},
+		{"git", "status"},
+		{"git", "status", "-s"},
+		{"git", "log", "--oneline", "-n", "5"},
+		{"go", "test", "./..."},
+		{"go", "vet", "./pkg/..."},
+		{"find", ".", "-name", "*.go"},
+		{"sort", "-r"},
+		{"sort", "-rn", "a.txt"},
+		{"date", "+%s"},
+	}
+	for _, argv := range allowed {
+		if err := policy.Check(argv); err != nil {
+			t.Errorf("%v: unexpected error %v", argv, err)
+		}
+	}
+
+	denied := map[string][]string{
+		"not an allowed command": {"rm", "-rf", "x"},
+		"push":                   {"git", "push", "origin", "main"},
+		"may only be run as":     {"git", "-c", "core.pager=sh", "status"},
+		"--output=*":             {"git", "diff", "--output=/tmp/x"},
+		"log -p":                 {"git", "log", "--stat", "-p"},
+		"go may only":            {"go", "test", "./pkg"},
+		"-exec":                  {"find", ".", "-exec", "rm", "{}", ";"},
+		"-delete":                {"find", ".", "-name", "*.tmp", "-delete"},
+		"rule \"sort -o\"":       {"sort", "-o", "out.txt", "in.txt"},
+		"rule \"sleep *\"":       {"sleep", "1"},
+	}
+	// 附加值與合併的短選項也符合 "-o"
+	for _, argv := range [][]string{
+		{"sort", "-o/outside/pwned", "a.txt"},
+		{"sort", "-oout.txt", "a.txt"},
+		{"sort", "-uo", "out.txt", "a.txt"},
+	} {
+		if err := policy.Check(argv); err == nil || !strings.Contains(err.Error(), `rule "sort -o"`) {
+			t.Errorf("%v: got %v, want sort -o rule", argv, err)
+		}
+	}
+	// GNU 長選項縮寫也符合 "--output*"
+	for _, argv := range [][]string{
+		{"sort", "--o=out.txt", "a.txt"},
+		{"sort", "--outp", "out.txt", "a.txt"},
+		{"sort", "--output=out.txt", "a.txt"},
+	} {
+		if err := policy.Check(argv); err == nil || !strings.Contains(err.Error(), `rule "sort --output*"`) {
+			t.Errorf("%v: got %v, want sort --output rule", argv, err)
+		}
+	}
+	if err := policy.Check([]string{"tree", "-o", "out.txt"}); err == nil || !strings.Contains(err.Error(), `rule "tree -o"`) {
+		t.Errorf("tree -o: got %v", err)
+	}
+	if err := policy.Check([]string{"git", "log", "--oneline"}); err != nil {
+		t.Errorf("--oneline is not an abbreviation of --output: %v", err)
+	}
+	for want, argv := range denied {
+		err := policy.Check(argv)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%v: got %v, want error containing %q", argv, err, want)
+		}
+	}
+
+	// 被 deny ["*"] 移除的程式不會出現在允許清單中
+	if err := policy.Check([]string{"rm"}); strings.Contains(err.Error(), "sleep") {
+		t.Errorf("removed program listed as allowed: %v", err)
+	}
+}
+
+func TestMatchWildcard(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"--output=*", "--output=/tmp/x", true},
+		{"--output=*", "--output", false},
+		{"-fprint*", "-fprintf", true},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"*.go", "dir/main.go", true},
+		{"push", "pushd", false},
+	}
+	for _, tt := range tests {
+		if got := matchWildcard(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchWildcard(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPathArgs(t *testing.T) {
+	sandbox, _ := NewSandbox(t.TempDir())
+	ok := [][]string{
+		{"cat", "notes.txt"},
+		{"cat", "v1..2.txt"},
+		{"grep", "-n", "TODO", "src/"},
+		{"cat", sandbox.Workspace + "/a.txt"},
+		{"ls", "--color=auto"},
+		{"head", "-n5", "notes.txt"},
+		{"grep", "-rn", "TODO", "."},
+		{"sort", "-o./sorted.txt", "a.txt"},
+	}
+	for _, argv := range ok {
+		if err := checkPathArgs(sandbox, argv, true); err != nil {
+			t.Errorf("%v: unexpected error %v", argv, err)
+		}
+	}
+	bad := [][]string{
+		{"cat", "/etc/passwd"},
+		{"cat", "../secret"},
+		{"cat", "sub/../../secret"},
+		{"ls", "~"},
+		{"cat", "~/.ssh/id_rsa"},
+		{"grep", "--file=/etc/shadow", "x"},
+		{"sort", "-o/outside/pwned", "a.txt"},
+		{"grep", "-f/outside/secret", "a.txt"},
+		{"sort", "-o../x", "a.txt"},
+		{"sort", "-uo/outside/x", "a.txt"},
+		{"cat", "-A~/.ssh/id_rsa"},
+	}
+	for _, argv := range bad {
+		if err := checkPathArgs(sandbox, argv, true); err == nil {
+			t.Errorf("%v: expected error", argv)
+		}
+	}
+
+	// 相對路徑經過指向工作區外的符號連結
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+	if err := os.Symlink(outside, filepath.Join(sandbox.Workspace, "link")); err == nil {
+		for _, argv := range [][]string{
+			{"cat", "link/secret.txt"},
+			{"cat", "link"},
+			{"grep", "--file=link/secret.txt", "x"},
+			{"cat", "./link/../link/secret.txt"},
+		} {
+			if err := checkPathArgs(sandbox, argv, true); err == nil || !strings.Contains(err.Error(), "outside the workspace") {
+				t.Errorf("%v: got %v, want outside error", argv, err)
+			}
+		}
+	}
+
+	// 唯讀根目錄與內部目錄：沒有唯讀掛載保護時拒絕，有時交給掛載處理
+	readOnly := filepath.Join(sandbox.Workspace, "tools")
+	os.MkdirAll(readOnly, 0755)
+	sandbox.AddRoot("tools", readOnly, true)
+	sandbox.Protect(filepath.Join(sandbox.Workspace, ".checkpoints"))
+	for _, argv := range [][]string{
+		{"touch", "tools/x.json"},
+		{"touch", "./tools"},
+		{"cp", "a.txt", ".checkpoints/manifest.json"},
+		{"sort", "--output=tools/x.json", "a.txt"},
+		{"cp", "a.txt", "tools:x.json"},
+	} {
+		if err := checkPathArgs(sandbox, argv, true); err == nil || !strings.Contains(err.Error(), "read-only root") && !strings.Contains(err.Error(), "internal directory") {
+			t.Errorf("%v: got %v, want read-only error", argv, err)
+		}
+		if err := checkPathArgs(sandbox, argv, false); err != nil {
+			t.Errorf("%v: unexpected error with read-only mounts %v", argv, err)
+		}
+	}
+}
@@ -0,0 +1,529 @@
+package tools
+
+// ============================================================================
+// 檔案管理工具 (File Management Tools)
+// ============================================================================
+// 讓 Agent 可以整理工作區內的檔案，而不需要透過 Shell 指令：
+//   1. MovePathTool   (move_path)   : 移動或重新命名檔案/目錄
+//   2. CopyPathTool   (copy_path)   : 複製檔案/目錄
+//   3. DeletePathTool (delete_path) : 刪除檔案/目錄 (預設移到垃圾桶，可復原)
+//   4. MakeDirTool    (make_dir)    : 建立目錄 (含上層目錄)
+//
+// 安全機制：
+//   - 來源與目的地都經過 Sandbox 檢查
+//   - 移動與刪除作用在符號連結本身，不會影響連結指向的檔案
+//   - 拒絕移動或刪除工作區根目錄，也拒絕把目錄移動/複製到自己底下
+//   - 覆蓋既有的目的地必須明確指定 overwrite
+//   - 來源與目的地位於不同的檔案系統時 (例如其他根目錄)，移動改為複製後刪除來源
+// ============================================================================
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chiisen/mini_bot/pkg/i18n"
+)
+
+// copyMaxEntries 是 copy_path 單次最多複製的項目數，避免意外複製整個大型目錄
+const copyMaxEntries = 10000
+
+// pathArgs 驗證並取得來源與目的地路徑
+//
+// 來源使用 entryPath (不跟隨最後一層的符號連結)，目的地使用 CheckWritePath。
+// 移動時呼叫端還需要以 checkRemovable 確認來源可移除。
+func pathArgs(sandbox *Sandbox, args map[string]any) (src, dst string, result *ToolResult) {
+	tr := i18n.GetInstance()
+	source, _ := args["source"].(string)
+	destination, _ := args["destination"].(string)
+	for _, p := range []string{source, destination} {
+		if err := validatePathName(p); err != nil {
+			return "", "", &ToolResult{ForLLM: fmt.Sprintf(tr.T("errors.path_invalid"), err), IsError: true}
+		}
+	}
+
+	src, err := sandbox.entryPath(source)
+	if err != nil {
+		return "", "", &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+	if sandbox.isRoot(src) {
+		return "", "", &ToolResult{ForLLM: "Error: the workspace root or another sandbox root cannot be moved or copied", IsError: true}
+	}
+	if _, err := os.Lstat(src); err != nil {
+		return "", "", &ToolResult{ForLLM: fmt.Sprintf("Error: source %s does not exist", source), IsError: true}
+	}
+
+	dst, err = sandbox.CheckWritePath(destination)
+	if err != nil {
+		return "", "", &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+	// 目的地是既有目錄時，放到該目錄底下 (與 mv/cp 相同)；
+	// 新的路徑可能是符號連結或唯讀根目錄，必須重新檢查
+	if info, err := os.Stat(dst); err == nil && info.IsDir() {
+		dst, err = sandbox.CheckWritePath(filepath.Join(dst, filepath.Base(src)))
+		if err != nil {
+			return "", "", &ToolResult{ForLLM: err.Error(), IsError: true}
+		}
+	}
+	switch {
+	case dst == src:
+		return "", "", &ToolResult{ForLLM: "Error: source and destination are the same", IsError: true}
+	case strings.HasPrefix(dst, src+string(filepath.Separator)):
+		return "", "", &ToolResult{ForLLM: "Error: cannot move or copy a directory into itself", IsError: true}
+	}
+	return src, dst, nil
+}
+
+// prepareDestination 處理既有的目的地並建立上層目錄
+func prepareDestination(dst string, overwrite bool, rel string) *ToolResult {
+	if info, err := os.Lstat(dst); err == nil {
+		if !overwrite {
+			return &ToolResult{ForLLM: fmt.Sprintf("Error: destination %s already exists (set overwrite to replace it)", rel), IsError: true}
+		}
+		if info.IsDir() {
+			return &ToolResult{ForLLM: fmt.Sprintf("Error: destination %s is a directory and cannot be overwritten", rel), IsError: true}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.write_failed"), err), IsError: true}
+	}
+	return nil
+}
+
+func pathParameters(tr *i18n.I18n) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"source": map[string]any{
+				"type":        "string",
+				"description": tr.T("tool_params.source"),
+			},
+			"destination": map[string]any{
+				"type":        "string",
+				"description": tr.T("tool_params.destination"),
+			},
+			"overwrite": map[string]any{
+				"type":        "boolean",
+				"description": tr.T("tool_params.overwrite"),
+			},
+		},
+		"required": []string{"source", "destination"},
+	}
+}
+
+// ============================================================================
+// MovePathTool: 移動檔案工具
+// ============================================================================
+// 功能：移動或重新命名檔案/目錄，目的地的上層目錄會自動建立
+// 工具名稱：move_path
+type MovePathTool struct {
+	Sandbox     *Sandbox
+	Checkpoints *CheckpointStore // 修改前保存檢查點 (可為 nil，只涵蓋一般檔案)
+}
+
+func (t *MovePathTool) Name() string               { return "move_path" }
+func (t *MovePathTool) Description() string        { return i18n.GetInstance().T("tools.move_path") }
+func (t *MovePathTool) Parameters() map[string]any { return pathParameters(i18n.GetInstance()) }
+func (t *MovePathTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	src, dst, res := pathArgs(t.Sandbox, args)
+	if res != nil {
+		return res
+	}
+	if err := t.Sandbox.checkRemovable(src, stringArg(args, "source")); err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+	if res := prepareDestination(dst, boolArg(args, "overwrite"), t.Sandbox.relPath(dst)); res != nil {
+		return res
+	}
+
+	// 從其他根目錄移入工作區時，等同新增檔案，需要檢查配額
+	if !t.Sandbox.inWorkspace(src) && t.Sandbox.inWorkspace(dst) {
+		moved, err := treeChanges(src, dst)
+		if err == nil {
+			err = t.Sandbox.CheckQuota(moved...)
+		}
+		if err != nil {
+			return &ToolResult{ForLLM: err.Error(), IsError: true}
+		}
+	}
+
+	// 檢查點只能還原一般檔案，移動目錄與連結不建立檢查點
+	if info, err := os.Lstat(src); err == nil && info.Mode().IsRegular() {
+		if err := t.Checkpoints.Snapshot(SessionKeyFrom(ctx), t.Name(), src, dst); err != nil {
+			return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.checkpoint_failed"), err), IsError: true}
+		}
+	}
+	if err := renamePath(src, dst); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: move failed: %v", err), IsError: true}
+	}
+	return &ToolResult{ForLLM: fmt.Sprintf("Moved %s -> %s", t.Sandbox.relPath(src), t.Sandbox.relPath(dst))}
+}
+
+// ============================================================================
+// CopyPathTool: 複製檔案工具
+// ============================================================================
+// 功能：複製檔案或整個目錄，保留檔案權限
+// 工具名稱：copy_path
+//
+// 目錄內指向工作區外的符號連結會被略過，其他連結會複製為連結指向的內容。
+type CopyPathTool struct {
+	Sandbox     *Sandbox
+	Checkpoints *CheckpointStore // 修改前保存檢查點 (可為 nil，只涵蓋一般檔案)
+}
+
+func (t *CopyPathTool) Name() string               { return "copy_path" }
+func (t *CopyPathTool) Description() string        { return i18n.GetInstance().T("tools.copy_path") }
+func (t *CopyPathTool) Parameters() map[string]any { return pathParameters(i18n.GetInstance()) }
+func (t *CopyPathTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	src, dst, res := pathArgs(t.Sandbox, args)
+	if res != nil {
+		return res
+	}
+	// 來源是符號連結時複製它指向的內容，該內容也必須在工作區內
+	src, err := t.Sandbox.CheckPath(src)
+	if err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.read_failed"), err), IsError: true}
+	}
+	if info.IsDir() && strings.HasPrefix(dst, src+string(filepath.Separator)) {
+		return &ToolResult{ForLLM: "Error: cannot move or copy a directory into itself", IsError: true}
+	}
+	if res := prepareDestination(dst, boolArg(args, "overwrite"), t.Sandbox.relPath(dst)); res != nil {
+		return res
+	}
+
+	if !info.IsDir() {
+		if err := t.Sandbox.CheckQuota(FileChange{Path: dst, Size: info.Size()}); err != nil {
+			return &ToolResult{ForLLM: err.Error(), IsError: true}
+		}
+		if err := t.Checkpoints.Snapshot(SessionKeyFrom(ctx), t.Name(), dst); err != nil {
+			return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.checkpoint_failed"), err), IsError: true}
+		}
+		if err := copyFile(src, dst, info.Mode().Perm()); err != nil {
+			return &ToolResult{ForLLM: fmt.Sprintf("Error: copy failed: %v", err), IsError: true}
+		}
+		return &ToolResult{ForLLM: fmt.Sprintf("Copied %s -> %s", t.Sandbox.relPath(src), t.Sandbox.relPath(dst))}
+	}
+
+	files, skipped, err := t.copyTree(ctx, src, dst)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: copy failed: %v", err), IsError: true}
+	}
+	msg := fmt.Sprintf("Copied %s -> %s (%d files)", t.Sandbox.relPath(src), t.Sandbox.relPath(dst), files)
+	if skipped > 0 {
+		msg += fmt.Sprintf("; skipped %d symbolic links pointing outside the workspace", skipped)
+	}
+	return &ToolResult{ForLLM: msg}
+}
+
+// copyTree 複製整個目錄；先計算項目數量，超過 copyMaxEntries 時不複製任何東西
+func (t *CopyPathTool) copyTree(ctx context.Context, src, dst string) (files, skipped int, err error) {
+	type entry struct {
+		src, rel string
+		info     fs.FileInfo
+	}
+	var entries []entry
+	err = filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if len(entries) >= copyMaxEntries {
+			return fmt.Errorf("directory has more than %d entries", copyMaxEntries)
+		}
+		rel, _ := filepath.Rel(src, p)
+		resolved := p
+		if d.Type()&fs.ModeSymlink != 0 {
+			if resolved, err = t.Sandbox.CheckPath(p); err != nil {
+				skipped++
+				return nil
+			}
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			skipped++
+			return nil
+		}
+		if info.IsDir() && resolved != p {
+			// 不跟隨指向目錄的連結，避免迴圈
+			skipped++
+			return nil
+		}
+		entries = append(entries, entry{src: resolved, rel: rel, info: info})
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// 檢查配額後才開始複製，避免複製到一半
+	var copies []FileChange
+	for _, e := range entries {
+		if !e.info.IsDir() {
+			copies = append(copies, FileChange{Path: filepath.Join(dst, e.rel), Size: e.info.Size()})
+		}
+	}
+	if err := t.Sandbox.CheckQuota(copies...); err != nil {
+		return 0, 0, err
+	}
+
+	for _, e := range entries {
+		target := filepath.Join(dst, e.rel)
+		if e.info.IsDir() {
+			if err := os.MkdirAll(target, e.info.Mode().Perm()|0700); err != nil {
+				return files, skipped, err
+			}
+			continue
+		}
+		if err := copyFile(e.src, target, e.info.Mode().Perm()); err != nil {
+			return files, skipped, err
+		}
+		files++
+	}
+	return files, skipped, nil
+}
+
+// renamePath 以 os.Rename 移動 src；兩者位於不同的檔案系統時改為複製後刪除來源。
+// 先複製到目的地旁的暫存路徑再改名，複製失敗時不會留下一半的目的地，也不會動到來源
+func renamePath(src, dst string) error {
+	err := os.Rename(src, dst)
+	if !isCrossDevice(err) {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(dst), fmt.Sprintf(".%s.moving-%d", filepath.Base(dst), time.Now().UnixNano()))
+	if err := copyEntry(src, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// isCrossDevice 判斷 os.Rename 的錯誤是否因為來源與目的地位於不同的檔案系統
+func isCrossDevice(err error) bool {
+	if err == nil {
+		return false
+	}
+	// Windows 回傳 ERROR_NOT_SAME_DEVICE (17)
+	return errors.Is(err, syscall.EXDEV) || (runtime.GOOS == "windows" && errors.Is(err, syscall.Errno(17)))
+}
+
+// copyEntry 複製檔案、目錄或符號連結 (連結本身，不跟隨)，保留權限
+func copyEntry(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, p)
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(p, target, info.Mode().Perm())
+		default:
+			return fmt.Errorf("cannot move special file %s across file systems", p)
+		}
+	})
+}
+
+// copyFile 複製單一檔案內容
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// ============================================================================
+// DeletePathTool: 刪除檔案工具
+// ============================================================================
+// 功能：刪除檔案或目錄
+// 工具名稱：delete_path
+//
+// 設定 TrashDir 時，刪除的項目會移到 {TrashDir}/{時間}/{原路徑}，
+// 可以用 move_path 搬回原處；刪除垃圾桶內的項目則是永久刪除。
+// 非空目錄必須指定 recursive，工作區根目錄永遠不能刪除。
+type DeletePathTool struct {
+	Sandbox     *Sandbox
+	TrashDir    string           // 垃圾桶目錄，空字串表示直接永久刪除
+	Checkpoints *CheckpointStore // 修改前保存檢查點 (可為 nil，只涵蓋一般檔案)
+}
+
+func (t *DeletePathTool) Name() string        { return "delete_path" }
+func (t *DeletePathTool) Description() string { return i18n.GetInstance().T("tools.delete_path") }
+func (t *DeletePathTool) Parameters() map[string]any {
+	tr := i18n.GetInstance()
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": tr.T("tool_params.path"),
+			},
+			"recursive": map[string]any{
+				"type":        "boolean",
+				"description": tr.T("tool_params.recursive"),
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *DeletePathTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	path, _ := args["path"].(string)
+	if err := validatePathName(path); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.path_invalid"), err), IsError: true}
+	}
+	abs, err := t.Sandbox.entryPath(path)
+	if err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+	if t.Sandbox.isRoot(abs) {
+		return &ToolResult{ForLLM: "Error: refusing to delete the workspace root or another sandbox root", IsError: true}
+	}
+	if err := t.Sandbox.checkRemovable(abs, path); err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+	info, err := os.Lstat(abs)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: %s does not exist", path), IsError: true}
+	}
+	if info.IsDir() && !boolArg(args, "recursive") {
+		if entries, _ := os.ReadDir(abs); len(entries) > 0 {
+			return &ToolResult{ForLLM: fmt.Sprintf("Error: %s is a non-empty directory (set recursive to delete it)", path), IsError: true}
+		}
+	}
+
+	rel := t.Sandbox.relPath(abs)
+	if info.Mode().IsRegular() {
+		if err := t.Checkpoints.Snapshot(SessionKeyFrom(ctx), t.Name(), abs); err != nil {
+			return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.checkpoint_failed"), err), IsError: true}
+		}
+	}
+
+	trash := t.trashDir()
+	if trash == "" || abs == trash || strings.HasPrefix(abs, trash+string(filepath.Separator)) {
+		if err := os.RemoveAll(abs); err != nil {
+			return &ToolResult{ForLLM: fmt.Sprintf("Error: delete failed: %v", err), IsError: true}
+		}
+		return &ToolResult{ForLLM: fmt.Sprintf("Permanently deleted %s", rel)}
+	}
+
+	// 其他根目錄的項目 ("notes:a.md") 放在垃圾桶中以根目錄名稱命名的子目錄
+	trashRel := rel
+	if name, rest, ok := strings.Cut(rel, ":"); ok && t.Sandbox.root(name) != nil {
+		trashRel = name + "/" + rest
+	}
+	dst := filepath.Join(trash, time.Now().Format("20060102-150405.000000"), filepath.FromSlash(trashRel))
+	// 其他根目錄的項目移入工作區的垃圾桶，等同新增檔案，需要檢查配額
+	if !t.Sandbox.inWorkspace(abs) {
+		moved, err := treeChanges(abs, dst)
+		if err == nil {
+			err = t.Sandbox.CheckQuota(moved...)
+		}
+		if err != nil {
+			return &ToolResult{ForLLM: err.Error(), IsError: true}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: delete failed: %v", err), IsError: true}
+	}
+	if err := renamePath(abs, dst); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: delete failed: %v", err), IsError: true}
+	}
+	return &ToolResult{ForLLM: fmt.Sprintf("Moved %s to the trash: %s (use move_path to restore it)", rel, t.Sandbox.relPath(dst))}
+}
+
+// trashDir 回傳經過 Sandbox 檢查的垃圾桶絕對路徑；未設定或不在工作區內時回傳空字串
+func (t *DeletePathTool) trashDir() string {
+	if t.TrashDir == "" {
+		return ""
+	}
+	abs, err := t.Sandbox.CheckPath(t.TrashDir)
+	if err != nil {
+		return ""
+	}
+	return abs
+}
+
+// ============================================================================
+// MakeDirTool: 建立目錄工具
+// ============================================================================
+// 功能：建立目錄，上層目錄不存在時一併建立
+// 工具名稱：make_dir
+type MakeDirTool struct {
+	Sandbox *Sandbox
+}
+
+func (t *MakeDirTool) Name() string        { return "make_dir" }
+func (t *MakeDirTool) Description() string { return i18n.GetInstance().T("tools.make_dir") }
+func (t *MakeDirTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": i18n.GetInstance().T("tool_params.path"),
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *MakeDirTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	path, _ := args["path"].(string)
+	if err := validatePathName(path); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.path_invalid"), err), IsError: true}
+	}
+	abs, err := t.Sandbox.CheckWritePath(path)
+	if err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+	if info, err := os.Stat(abs); err == nil {
+		if !info.IsDir() {
+			return &ToolResult{ForLLM: fmt.Sprintf("Error: %s already exists and is not a directory", path), IsError: true}
+		}
+		return &ToolResult{ForLLM: fmt.Sprintf("Directory %s already exists", t.Sandbox.relPath(abs))}
+	}
+	if err := os.MkdirAll(abs, 0755); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.write_failed"), err), IsError: true}
+	}
+	return &ToolResult{ForLLM: fmt.Sprintf("Created directory %s", t.Sandbox.relPath(abs))}
+}
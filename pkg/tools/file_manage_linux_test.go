@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestRenamePath_CrossDevice(t *testing.T) {
+	other, err := os.MkdirTemp("/dev/shm", "minibot-test-")
+	if err != nil {
+		t.Skip("/dev/shm unavailable")
+	}
+	defer os.RemoveAll(other)
+	ws := t.TempDir()
+	var a, b syscall.Stat_t
+	if syscall.Stat(ws, &a) != nil || syscall.Stat(other, &b) != nil || a.Dev == b.Dev {
+		t.Skip("no second file system available")
+	}
+
+	// 其他檔案系統上的根目錄：刪除時移入工作區的垃圾桶
+	os.MkdirAll(filepath.Join(other, "docs", "sub"), 0755)
+	os.WriteFile(filepath.Join(other, "docs", "sub", "note.md"), []byte("note"), 0640)
+	os.Symlink("sub/note.md", filepath.Join(other, "docs", "link"))
+	sandbox, _ := NewSandbox(ws)
+	if err := sandbox.AddRoot("shm", other, false); err != nil {
+		t.Fatal(err)
+	}
+	res := (&DeletePathTool{Sandbox: sandbox, TrashDir: ".trash"}).Execute(context.Background(), map[string]any{"path": "shm:docs", "recursive": true})
+	if res.IsError {
+		t.Fatalf("delete across file systems failed: %s", res.ForLLM)
+	}
+	if _, err := os.Lstat(filepath.Join(other, "docs")); !os.IsNotExist(err) {
+		t.Error("the source should be removed")
+	}
+	trashed := filepath.Join(ws, filepath.FromSlash(strings.Fields(res.ForLLM)[5]))
+	if data, _ := os.ReadFile(filepath.Join(trashed, "sub", "note.md")); string(data) != "note" {
+		t.Errorf("trashed content = %q", data)
+	}
+	if info, err := os.Stat(filepath.Join(trashed, "sub", "note.md")); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("mode was not preserved: %v", info)
+	}
+	if link, _ := os.Readlink(filepath.Join(trashed, "link")); link != "sub/note.md" {
+		t.Errorf("symlink = %q", link)
+	}
+
+	// 移回原本的根目錄
+	res = (&MovePathTool{Sandbox: sandbox}).Execute(context.Background(), map[string]any{"source": strings.Fields(res.ForLLM)[5], "destination": "shm:docs"})
+	if res.IsError {
+		t.Fatalf("move across file systems failed: %s", res.ForLLM)
+	}
+	if data, _ := os.ReadFile(filepath.Join(other, "docs", "sub", "note.md")); string(data) != "note" {
+		t.Errorf("restored content = %q", data)
+	}
+}
@@ -0,0 +1,293 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMovePathTool(t *testing.T) {
+	ws := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	store := NewCheckpointStore(ws, filepath.Join(ws, ".checkpoints"))
+	os.WriteFile(filepath.Join(ws, "a.txt"), []byte("A"), 0644)
+	os.WriteFile(filepath.Join(ws, "b.txt"), []byte("B"), 0644)
+	os.MkdirAll(filepath.Join(ws, "dir", "sub"), 0755)
+	tool := &MovePathTool{Sandbox: sandbox, Checkpoints: store}
+	ctx := context.Background()
+
+	res := tool.Execute(ctx, map[string]any{"source": "a.txt", "destination": "新資料夾/a.txt"})
+	if res.IsError || res.ForLLM != "Moved a.txt -> 新資料夾/a.txt" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, "新資料夾", "a.txt")); string(data) != "A" {
+		t.Error("file was not moved")
+	}
+
+	// 目的地已存在
+	if res := tool.Execute(ctx, map[string]any{"source": "b.txt", "destination": "新資料夾/a.txt"}); !res.IsError || !strings.Contains(res.ForLLM, "already exists") {
+		t.Errorf("expected overwrite error, got %+v", res)
+	}
+	res = tool.Execute(ctx, map[string]any{"source": "b.txt", "destination": "新資料夾/a.txt", "overwrite": true})
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+
+	// undo_last_change 可以還原被覆蓋的檔案
+	(&UndoLastChangeTool{Checkpoints: store}).Execute(ctx, nil)
+	if data, _ := os.ReadFile(filepath.Join(ws, "新資料夾", "a.txt")); string(data) != "A" {
+		t.Errorf("expected undo to restore overwritten file, got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, "b.txt")); string(data) != "B" {
+		t.Errorf("expected undo to restore moved file, got %q", data)
+	}
+
+	// 移動到既有目錄時放到目錄底下
+	if res := tool.Execute(ctx, map[string]any{"source": "b.txt", "destination": "dir"}); res.IsError || res.ForLLM != "Moved b.txt -> dir/b.txt" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	errorCases := []map[string]any{
+		{"source": "dir", "destination": "dir/sub/inner"},
+		{"source": ".", "destination": "elsewhere"},
+		{"source": "dir/..", "destination": "elsewhere"},
+		{"source": "missing.txt", "destination": "x.txt"},
+		{"source": "dir/b.txt", "destination": "../outside.txt"},
+		{"source": "../etc/passwd", "destination": "passwd"},
+		{"source": "dir/b.txt", "destination": "dir/b.txt"},
+	}
+	for _, args := range errorCases {
+		if res := tool.Execute(ctx, args); !res.IsError {
+			t.Errorf("%v: expected error, got %s", args, res.ForLLM)
+		}
+	}
+}
+
+func TestMovePathTool_Symlink(t *testing.T) {
+	ws := t.TempDir()
+	outside := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("s"), 0644)
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(ws, "link")); err != nil {
+		t.Skip("symlinks not supported")
+	}
+
+	// 移動連結本身，不會動到工作區外的檔案
+	res := (&MovePathTool{Sandbox: sandbox}).Execute(context.Background(), map[string]any{"source": "link", "destination": "renamed"})
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "secret.txt")); err != nil {
+		t.Error("link target must not be moved")
+	}
+
+	// 複製指向工作區外的連結會被拒絕
+	res = (&CopyPathTool{Sandbox: sandbox}).Execute(context.Background(), map[string]any{"source": "renamed", "destination": "copy.txt"})
+	if !res.IsError {
+		t.Errorf("expected copy through escaping link to fail, got %s", res.ForLLM)
+	}
+}
+
+func TestCopyPathTool(t *testing.T) {
+	ws := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	os.MkdirAll(filepath.Join(ws, "src", "nested"), 0755)
+	os.WriteFile(filepath.Join(ws, "src", "a.txt"), []byte("A"), 0644)
+	os.WriteFile(filepath.Join(ws, "src", "nested", "b.sh"), []byte("#!/bin/sh"), 0755)
+	os.Symlink(t.TempDir(), filepath.Join(ws, "src", "escape"))
+	tool := &CopyPathTool{Sandbox: sandbox}
+	ctx := context.Background()
+
+	res := tool.Execute(ctx, map[string]any{"source": "src", "destination": "backup"})
+	if res.IsError || !strings.Contains(res.ForLLM, "(2 files)") || !strings.Contains(res.ForLLM, "skipped 1") {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	info, err := os.Stat(filepath.Join(ws, "backup", "nested", "b.sh"))
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("expected executable copy, got %v %v", info, err)
+	}
+	if _, err := os.Lstat(filepath.Join(ws, "backup", "escape")); !os.IsNotExist(err) {
+		t.Error("escaping symlink must not be copied")
+	}
+
+	res = tool.Execute(ctx, map[string]any{"source": "src/a.txt", "destination": "copy.txt"})
+	if res.IsError || res.ForLLM != "Copied src/a.txt -> copy.txt" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if res := tool.Execute(ctx, map[string]any{"source": "src", "destination": "src/nested"}); !res.IsError {
+		t.Errorf("expected error copying a directory into itself, got %s", res.ForLLM)
+	}
+}
+
+func TestDeletePathTool(t *testing.T) {
+	ws := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	os.MkdirAll(filepath.Join(ws, "docs", "old"), 0755)
+	os.WriteFile(filepath.Join(ws, "docs", "old", "note.md"), []byte("note"), 0644)
+	os.MkdirAll(filepath.Join(ws, "empty"), 0755)
+	tool := &DeletePathTool{Sandbox: sandbox, TrashDir: ".trash"}
+	ctx := context.Background()
+
+	for _, p := range []string{".", "", "docs/..", ws, "../"} {
+		if res := tool.Execute(ctx, map[string]any{"path": p}); !res.IsError {
+			t.Errorf("deleting %q must be refused, got %s", p, res.ForLLM)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(ws, "docs")); err != nil {
+		t.Fatal("workspace content was deleted")
+	}
+
+	if res := tool.Execute(ctx, map[string]any{"path": "docs"}); !res.IsError || !strings.Contains(res.ForLLM, "recursive") {
+		t.Errorf("expected recursive error, got %+v", res)
+	}
+	if res := tool.Execute(ctx, map[string]any{"path": "empty"}); res.IsError {
+		t.Errorf("empty directory should not need recursive: %s", res.ForLLM)
+	}
+
+	res := tool.Execute(ctx, map[string]any{"path": "docs", "recursive": true})
+	if res.IsError || !strings.Contains(res.ForLLM, "Moved docs to the trash: .trash/") {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "docs")); !os.IsNotExist(err) {
+		t.Error("docs should be gone")
+	}
+
+	// 從垃圾桶還原
+	trashed := strings.Fields(res.ForLLM)[5]
+	restore := (&MovePathTool{Sandbox: sandbox}).Execute(ctx, map[string]any{"source": trashed, "destination": "docs"})
+	if restore.IsError {
+		t.Fatalf("restore failed: %s", restore.ForLLM)
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, "docs", "old", "note.md")); string(data) != "note" {
+		t.Error("restored content mismatch")
+	}
+
+	// 刪除垃圾桶內的項目是永久刪除
+	res = tool.Execute(ctx, map[string]any{"path": ".trash", "recursive": true})
+	if res.IsError || !strings.HasPrefix(res.ForLLM, "Permanently deleted") {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	// 未設定垃圾桶時直接刪除
+	permanent := &DeletePathTool{Sandbox: sandbox}
+	if res := permanent.Execute(ctx, map[string]any{"path": "docs/old/note.md"}); res.IsError || !strings.HasPrefix(res.ForLLM, "Permanently deleted") {
+		t.Errorf("unexpected result: %+v", res)
+	}
+}
+
+func TestMakeDirTool(t *testing.T) {
+	ws := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	tool := &MakeDirTool{Sandbox: sandbox}
+	ctx := context.Background()
+
+	if res := tool.Execute(ctx, map[string]any{"path": "專案/文件/2024"}); res.IsError || res.ForLLM != "Created directory 專案/文件/2024" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if res := tool.Execute(ctx, map[string]any{"path": "專案"}); res.IsError || !strings.Contains(res.ForLLM, "already exists") {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	os.WriteFile(filepath.Join(ws, "file"), nil, 0644)
+	if res := tool.Execute(ctx, map[string]any{"path": "file"}); !res.IsError {
+		t.Error("expected error when a file is in the way")
+	}
+
+	// 透過指向工作區外的連結建立目錄必須被拒絕
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(ws, "out")); err == nil {
+		if res := tool.Execute(ctx, map[string]any{"path": "out/new/deep"}); !res.IsError {
+			t.Errorf("expected escaping mkdir to fail, got %s", res.ForLLM)
+		}
+		if _, err := os.Stat(filepath.Join(outside, "new")); !os.IsNotExist(err) {
+			t.Error("directory was created outside the workspace")
+		}
+	}
+}
+
+func TestFileTools_ReadOnlyRoot(t *testing.T) {
+	ws := t.TempDir()
+	docs := t.TempDir()
+	os.WriteFile(filepath.Join(docs, "guide.md"), []byte("guide"), 0644)
+	sandbox, _ := NewSandbox(ws)
+	sandbox.AddRoot("docs", docs, true)
+	ctx := context.Background()
+
+	refused := map[string]*ToolResult{
+		"delete": (&DeletePathTool{Sandbox: sandbox, TrashDir: ".trash"}).Execute(ctx, map[string]any{"path": "docs:guide.md"}),
+		"move":   (&MovePathTool{Sandbox: sandbox}).Execute(ctx, map[string]any{"source": "docs:guide.md", "destination": "guide.md"}),
+		"write":  (&WriteFileTool{Sandbox: sandbox}).Execute(ctx, map[string]any{"path": "docs:new.md", "content": "x"}),
+		"mkdir":  (&MakeDirTool{Sandbox: sandbox}).Execute(ctx, map[string]any{"path": "docs:sub"}),
+	}
+	for op, res := range refused {
+		if !res.IsError || !strings.Contains(res.ForLLM, "read-only root docs") {
+			t.Errorf("%s in a read-only root should be refused, got %+v", op, res)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(docs, "guide.md")); err != nil {
+		t.Fatal("read-only content was modified")
+	}
+
+	// copying out of a read-only root is a read
+	res := (&CopyPathTool{Sandbox: sandbox}).Execute(ctx, map[string]any{"source": "docs:guide.md", "destination": "guide.md"})
+	if res.IsError || res.ForLLM != "Copied docs:guide.md -> guide.md" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestFileTools_NestedReadOnlyRoot(t *testing.T) {
+	ws := t.TempDir()
+	nested := filepath.Join(ws, "a", "b")
+	os.MkdirAll(nested, 0755)
+	os.WriteFile(filepath.Join(nested, "tool.json"), []byte("{}"), 0644)
+	sandbox, _ := NewSandbox(ws)
+	if err := sandbox.AddRoot("locked", nested, true); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// 移動或刪除上層目錄會連帶移走唯讀的根目錄
+	refused := map[string]*ToolResult{
+		"delete": (&DeletePathTool{Sandbox: sandbox, TrashDir: ".trash"}).Execute(ctx, map[string]any{"path": "a", "recursive": true}),
+		"move":   (&MovePathTool{Sandbox: sandbox}).Execute(ctx, map[string]any{"source": "a", "destination": "c"}),
+	}
+	for op, res := range refused {
+		if !res.IsError || !strings.Contains(res.ForLLM, "contains read-only root locked") {
+			t.Errorf("%s of a parent of a read-only root should be refused, got %+v", op, res)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(nested, "tool.json")); err != nil {
+		t.Fatal("read-only content was moved or deleted")
+	}
+
+	// 旁邊的檔案不受影響
+	os.WriteFile(filepath.Join(ws, "a", "other.txt"), []byte("x"), 0644)
+	if res := (&MovePathTool{Sandbox: sandbox}).Execute(ctx, map[string]any{"source": "a/other.txt", "destination": "other.txt"}); res.IsError {
+		t.Errorf("moving a sibling file should work: %s", res.ForLLM)
+	}
+}
+
+func TestFileTools_DestinationDirRechecked(t *testing.T) {
+	ws := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(outside, []byte("secret"), 0644)
+	os.MkdirAll(filepath.Join(ws, "dir"), 0755)
+	os.WriteFile(filepath.Join(ws, "a.txt"), []byte("pwned"), 0644)
+	// 目的地目錄中與來源同名的項目是指向工作區外的符號連結
+	if err := os.Symlink(outside, filepath.Join(ws, "dir", "a.txt")); err != nil {
+		t.Skip("symlinks unavailable")
+	}
+	sandbox, _ := NewSandbox(ws)
+	ctx := context.Background()
+
+	for name, tool := range map[string]Tool{"copy": &CopyPathTool{Sandbox: sandbox}, "move": &MovePathTool{Sandbox: sandbox}} {
+		res := tool.Execute(ctx, map[string]any{"source": "a.txt", "destination": "dir", "overwrite": true})
+		if !res.IsError || !strings.Contains(res.ForLLM, "escapes workspace") {
+			t.Errorf("%s: expected the rebased destination to be rejected, got %+v", name, res)
+		}
+	}
+	if data, _ := os.ReadFile(outside); string(data) != "secret" {
+		t.Errorf("file outside the workspace was overwritten: %q", data)
+	}
+}
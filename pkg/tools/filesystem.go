@@ -7,7 +7,7 @@ package tools
 // 所有工具都受到沙盒 (Sandbox) 的保護，確保操作限制在工作區目錄內。
 //
 // 提供的工具列表：
-//   1. ReadFileTool   (read_file)   : 讀取檔案內容
+//   1. ReadFileTool   (read_file)   : 讀取檔案內容 (支援分段、大小上限與編碼偵測)
 //   2. WriteFileTool  (write_file)  : 覆寫或建立檔案
 //   3. AppendFileTool (append_file) : 追加內容到檔案
 //   4. EditFileTool   (edit_file)   : 編輯特定行範圍的內容
@@ -15,56 +15,103 @@ package tools
 //
 // 安全機制：
 //   - 所有路徑都會經過 Sandbox 檢查，防止目錄穿越攻擊
-//   - 路徑必須符合安全字元規則 (只能包含字母、數字、底線、斜線等)
-//   - 不允許使用 ".." 進行目錄回溯
+//   - 路徑不可包含控制字元或裝置名稱 (支援中文等 Unicode 檔名)
 // ============================================================================
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
-	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/chiisen/mini_bot/pkg/i18n"
+	"golang.org/x/text/transform"
 )
 
 // ============================================================================
 // 安全驗證常數和函數
 // ============================================================================
 
-// safePathChars 定義允許在路徑中使用的字元
-// 這是一個正則表達式，確保路徑只包含安全字元
-var safePathChars = regexp.MustCompile(`^[a-zA-Z0-9_/\\.:\-]+$`)
+// windowsDeviceNames 是 Windows 保留的裝置名稱，不論副檔名 (例如 nul.txt) 都會被當成裝置開啟
+var windowsDeviceNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true, "CONIN$": true, "CONOUT$": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
 
-// isValidPathChars 驗證路徑字串是否安全
+// validatePathName 驗證路徑字串本身是否安全
+//
+// 是否位於工作區內由 Sandbox.CheckPath 判斷，這裡只拒絕真正危險的寫法，
+// 因此中文等 Unicode 檔名 (例如 筆記/會議記錄.md) 與 v1..2.txt 這類名稱都可以使用。
 //
-// 安全規則：
-//  1. 不能包含 ".." (防止目錄穿越)
-//  2. 只能包含允許的字元 (字母、數字、底線、斜線、點、冒號、連字符)
+// 拒絕規則：
+//  1. 空字串或不合法的 UTF-8
+//  2. NUL 與其他控制字元 (包含換行、DEL 與 C1 控制字元)
+//  3. Unicode 雙向文字控制字元 (可用來讓檔名顯示成另一個名稱)
+//  4. Windows 裝置名稱 (CON、NUL、COM1、LPT1 等，含副檔名的形式)
 //
 // 參數：
 //   - path: 要驗證的路徑字串
 //
 // 回傳：
-//   - bool: 是否安全
-func isValidPathChars(path string) bool {
-	// 檢查是否包含目錄回溯
-	if strings.Contains(path, "..") {
-		return false
-	}
-	// 檢查是否只包含允許的字元
-	return safePathChars.MatchString(path)
+//   - error: 不安全時說明原因
+func validatePathName(path string) error {
+	if path == "" {
+		return fmt.Errorf("path is empty")
+	}
+	if !utf8.ValidString(path) {
+		return fmt.Errorf("path is not valid UTF-8")
+	}
+	for _, r := range path {
+		switch {
+		case r == 0:
+			return fmt.Errorf("path contains a NUL character")
+		case unicode.IsControl(r):
+			return fmt.Errorf("path contains control character %U", r)
+		case unicode.Is(unicode.Bidi_Control, r):
+			return fmt.Errorf("path contains bidirectional control character %U", r)
+		}
+	}
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		// Windows 會忽略結尾的空白與句點，且只看第一個句點之前的部分
+		name := strings.TrimRight(segment, " .")
+		if i := strings.IndexByte(name, '.'); i >= 0 {
+			name = name[:i]
+		}
+		if windowsDeviceNames[strings.ToUpper(strings.TrimSpace(name))] {
+			return fmt.Errorf("%q is a reserved device name", segment)
+		}
+	}
+	return nil
 }
 
 // ============================================================================
 // ReadFileTool: 讀取檔案工具
-// 功能：讀取指定檔案的完整內容
+// 功能：讀取檔案內容並加上行號，可用 offset/limit 分段讀取
 // 工具名稱：read_file
+//
+// 大型檔案與特殊內容：
+//   - 預設最多回傳 readFileDefaultLimit 行、readFileMaxBytes 位元組，超過時附上續讀提示
+//   - 過長的單行 (例如壓縮過的 JS) 會被截斷
+//   - 二進位檔只回傳摘要，不輸出內容
+//   - 非 UTF-8 的文字 (例如 Big5) 會自動偵測並轉換，也可用 encoding 參數指定
 type ReadFileTool struct {
 	Sandbox *Sandbox // 沙盒實例，用於路徑安全檢查
 }
 
+const (
+	readFileDefaultLimit = 2000       // 預設最多回傳的行數
+	readFileMaxBytes     = 100 * 1024 // 單次回傳的輸出上限
+	readFileMaxLineRunes = 2000       // 單行最多顯示的字元數
+	readFileSampleSize   = 8000       // 用於判斷編碼的取樣大小
+)
+
 // Name 返回工具名稱
 func (t *ReadFileTool) Name() string { return "read_file" }
 
@@ -73,12 +120,27 @@ func (t *ReadFileTool) Description() string { return i18n.GetInstance().T("tools
 
 // Parameters 返回工具參數定義 (JSON Schema 格式)
 func (t *ReadFileTool) Parameters() map[string]any {
+	tr := i18n.GetInstance()
 	return map[string]any{
 		"type": "object",
 		"properties": map[string]any{
 			"path": map[string]any{
 				"type":        "string",
-				"description": i18n.GetInstance().T("tool_params.path"),
+				"description": tr.T("tool_params.path"),
+			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"description": tr.T("tool_params.line_offset"),
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"description": tr.T("tool_params.line_limit"),
+			},
+			"encoding": map[string]any{
+				"type":        "string",
+				"description": tr.T("tool_params.encoding"),
 			},
 		},
 		"required": []string{"path"}, // path 是必填參數
@@ -90,15 +152,20 @@ func (t *ReadFileTool) Parameters() map[string]any {
 // 執行步驟：
 //  1. 驗證路徑安全性
 //  2. 透過沙盒檢查轉換為絕對路徑
-//  3. 讀取檔案內容
-//  4. 為每行添加行號 (方便 AI 定位和替換)
+//  3. 以檔案開頭判斷編碼，二進位檔或無法判斷的編碼只回傳摘要
+//  4. 逐行讀取並為每行添加行號 (方便 AI 定位和替換)，到達行數或位元組上限時停止輸出
 func (t *ReadFileTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	// 取得參數
 	path, _ := args["path"].(string)
+	offset := max(intArg(args, "offset", 1), 1)
+	limit := intArg(args, "limit", readFileDefaultLimit)
+	if limit <= 0 {
+		limit = readFileDefaultLimit
+	}
 
 	// 驗證路徑安全性
-	if !isValidPathChars(path) {
-		return &ToolResult{ForLLM: i18n.GetInstance().T("errors.path_invalid"), IsError: true}
+	if err := validatePathName(path); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.path_invalid"), err), IsError: true}
 	}
 
 	// 透過沙盒取得安全路徑
@@ -107,26 +174,132 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		return &ToolResult{ForLLM: err.Error(), IsError: true}
 	}
 
-	// 讀取檔案
-	content, err := os.ReadFile(safePath)
+	// 開啟檔案
+	f, err := os.Open(safePath)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.read_failed"), err), IsError: true}
+	}
+	defer f.Close()
+	info, err := f.Stat()
 	if err != nil {
 		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.read_failed"), err), IsError: true}
 	}
+	if info.IsDir() {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: %s is a directory, use list_dir instead", path), IsError: true}
+	}
+
+	// 取樣判斷編碼
+	sample := make([]byte, readFileSampleSize)
+	n, err := io.ReadFull(f, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.read_failed"), err), IsError: true}
+	}
+	sample = sample[:n]
 
-	// 為輸出添加行號
-	// 這對於 AI 進行精確的替換操作非常重要
-	lines := strings.Split(string(content), "\n")
-	var numbered strings.Builder
-	for i, line := range lines {
-		// 處理最後一個空行的特殊情況
-		if i == len(lines)-1 && line == "" && len(lines) > 1 {
+	enc := detectEncoding(sample)
+	if name := stringArg(args, "encoding"); name != "" {
+		if enc, err = lookupEncoding(name); err != nil {
+			return &ToolResult{ForLLM: "Error: " + err.Error(), IsError: true}
+		}
+	}
+	switch {
+	case enc.Binary:
+		return &ToolResult{ForLLM: fmt.Sprintf(
+			"%s is a binary file (%s, %d bytes); its contents are not shown.",
+			path, http.DetectContentType(sample), info.Size())}
+	case enc.Unknown:
+		return &ToolResult{ForLLM: fmt.Sprintf(
+			"%s is not valid UTF-8 and its encoding could not be detected (%d bytes). "+
+				"Call read_file again with encoding set, for example big5, gbk, shift_jis or windows-1252.",
+			path, info.Size())}
+	}
+
+	var r io.Reader = io.MultiReader(bytes.NewReader(sample), f)
+	if enc.Decoder != nil {
+		r = transform.NewReader(r, enc.Decoder.NewDecoder())
+	}
+	return &ToolResult{ForLLM: readNumberedLines(ctx, bufio.NewReader(r), enc, offset, limit)}
+}
+
+// readNumberedLines 輸出 offset 起最多 limit 行 (加上行號)，並繼續計算總行數以提供續讀提示
+func readNumberedLines(ctx context.Context, br *bufio.Reader, enc textEncoding, offset, limit int) string {
+	var out strings.Builder
+	if enc.Decoder != nil {
+		fmt.Fprintf(&out, "[Decoded from %s]\n", enc.Name)
+	}
+
+	total, first, last := 0, 0, 0
+	full := false // 已達行數或位元組上限
+	for ctx.Err() == nil {
+		line, cut, ok := readLine(br, readFileMaxLineRunes*utf8.UTFMax)
+		if !ok {
 			break
 		}
-		// 格式: "行號: 內容"
-		numbered.WriteString(fmt.Sprintf("%d: %s\n", i+1, line))
+		total++
+		if total < offset || full {
+			continue
+		}
+		if last-first+1 >= limit && first > 0 {
+			full = true
+			continue
+		}
+
+		text := strings.ToValidUTF8(strings.TrimSuffix(string(line), "\r"), "\uFFFD")
+		if total == 1 {
+			text = strings.TrimPrefix(text, "\uFEFF")
+		}
+		if runes := []rune(text); len(runes) > readFileMaxLineRunes {
+			text, cut = string(runes[:readFileMaxLineRunes]), true
+		}
+		if cut {
+			text += " … [line truncated]"
+		}
+		entry := fmt.Sprintf("%d: %s\n", total, text)
+		if first > 0 && out.Len()+len(entry) > readFileMaxBytes {
+			full = true
+			continue
+		}
+		out.WriteString(entry)
+		if first == 0 {
+			first = total
+		}
+		last = total
 	}
 
-	return &ToolResult{ForLLM: numbered.String(), IsError: false}
+	switch {
+	case total == 0:
+		out.WriteString("(empty file)")
+	case first == 0:
+		fmt.Fprintf(&out, "(offset %d is past the end of the file, which has %d lines)", offset, total)
+	case first > 1 || last < total:
+		fmt.Fprintf(&out, "\n[Showing lines %d-%d of %d.", first, last, total)
+		if last < total {
+			fmt.Fprintf(&out, " Call read_file again with offset=%d to continue.", last+1)
+		}
+		out.WriteString("]")
+	}
+	return out.String()
+}
+
+// readLine 讀取一行 (不含換行字元)，超過 maxBytes 的部分會被捨棄並回傳 cut=true
+func readLine(br *bufio.Reader, maxBytes int) (line []byte, cut, ok bool) {
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if len(chunk) > 0 {
+			ok = true
+		}
+		if err == nil {
+			chunk = chunk[:len(chunk)-1]
+		}
+		if room := maxBytes - len(line); len(chunk) > room {
+			line, cut = append(line, chunk[:room]...), true
+		} else {
+			line = append(line, chunk...)
+		}
+		if err != bufio.ErrBufferFull {
+			return line, cut, ok
+		}
+	}
 }
 
 // ============================================================================
@@ -136,7 +309,8 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 // 工具名稱：write_file
 // 注意：這會覆蓋現有檔案，使用時要小心
 type WriteFileTool struct {
-	Sandbox *Sandbox
+	Sandbox     *Sandbox
+	Checkpoints *CheckpointStore // 修改前保存檢查點 (可為 nil)
 }
 
 func (t *WriteFileTool) Name() string        { return "write_file" }
@@ -164,16 +338,26 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]any) *ToolR
 	content, _ := args["content"].(string)
 
 	// 驗證路徑安全性
-	if !isValidPathChars(path) {
-		return &ToolResult{ForLLM: i18n.GetInstance().T("errors.path_invalid"), IsError: true}
+	if err := validatePathName(path); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.path_invalid"), err), IsError: true}
 	}
 
 	// 取得安全路徑
-	safePath, err := t.Sandbox.CheckPath(path)
+	safePath, err := t.Sandbox.CheckWritePath(path)
 	if err != nil {
 		return &ToolResult{ForLLM: err.Error(), IsError: true}
 	}
 
+	// 檢查配額 (單一檔案大小、工作區總量與檔案數)
+	if err := t.Sandbox.CheckQuota(FileChange{Path: safePath, Size: int64(len(content))}); err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+
+	// 保存修改前的狀態
+	if err := t.Checkpoints.Snapshot(SessionKeyFrom(ctx), t.Name(), safePath); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.checkpoint_failed"), err), IsError: true}
+	}
+
 	// 寫入檔案
 	// 權限 0600: rw------- (只有所有者可讀寫)
 	if err := os.WriteFile(safePath, []byte(content), 0600); err != nil {
@@ -189,7 +373,8 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]any) *ToolR
 // 工具名稱：append_file
 // 適用場景：日誌記錄、對話歷史追加等
 type AppendFileTool struct {
-	Sandbox *Sandbox
+	Sandbox     *Sandbox
+	Checkpoints *CheckpointStore // 修改前保存檢查點 (可為 nil)
 }
 
 func (t *AppendFileTool) Name() string        { return "append_file" }
@@ -217,16 +402,30 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]any) *Tool
 	content, _ := args["content"].(string)
 
 	// 驗證路徑安全性
-	if !isValidPathChars(path) {
-		return &ToolResult{ForLLM: i18n.GetInstance().T("errors.path_invalid"), IsError: true}
+	if err := validatePathName(path); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.path_invalid"), err), IsError: true}
 	}
 
 	// 取得安全路徑
-	safePath, err := t.Sandbox.CheckPath(path)
+	safePath, err := t.Sandbox.CheckWritePath(path)
 	if err != nil {
 		return &ToolResult{ForLLM: err.Error(), IsError: true}
 	}
 
+	// 檢查配額：追加後的大小是現有大小加上新內容
+	size := int64(len(content))
+	if info, err := os.Stat(safePath); err == nil {
+		size += info.Size()
+	}
+	if err := t.Sandbox.CheckQuota(FileChange{Path: safePath, Size: size}); err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+
+	// 保存修改前的狀態
+	if err := t.Checkpoints.Snapshot(SessionKeyFrom(ctx), t.Name(), safePath); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.checkpoint_failed"), err), IsError: true}
+	}
+
 	// 以追加模式開啟檔案
 	// os.O_APPEND: 追加模式
 	// os.O_CREATE: 如果檔案不存在則建立
@@ -255,7 +454,8 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]any) *Tool
 //   - end_line:    結束行號 (1-indexed，包含)
 //   - new_content: 要替換的新內容
 type EditFileTool struct {
-	Sandbox *Sandbox
+	Sandbox     *Sandbox
+	Checkpoints *CheckpointStore // 修改前保存檢查點 (可為 nil)
 }
 
 func (t *EditFileTool) Name() string { return "edit_file" }
@@ -301,8 +501,8 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 	newContent, _ := args["new_content"].(string)
 
 	// 驗證路徑安全性
-	if !isValidPathChars(path) {
-		return &ToolResult{ForLLM: i18n.GetInstance().T("errors.path_invalid"), IsError: true}
+	if err := validatePathName(path); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.path_invalid"), err), IsError: true}
 	}
 
 	// 轉換為整數
@@ -310,7 +510,7 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 	endLine := int(endLineFl)
 
 	// 取得安全路徑
-	safePath, err := t.Sandbox.CheckPath(path)
+	safePath, err := t.Sandbox.CheckWritePath(path)
 	if err != nil {
 		return &ToolResult{ForLLM: err.Error(), IsError: true}
 	}
@@ -340,9 +540,20 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		newLines = append(newLines, newContent) // 新內容
 	}
 	newLines = append(newLines, lines[endLine:]...) // 結尾部分 (從 end_line+1 開始)
+	updated := strings.Join(newLines, "\n")
+
+	// 檢查配額
+	if err := t.Sandbox.CheckQuota(FileChange{Path: safePath, Size: int64(len(updated))}); err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+
+	// 保存修改前的狀態
+	if err := t.Checkpoints.Snapshot(SessionKeyFrom(ctx), t.Name(), safePath); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.checkpoint_failed"), err), IsError: true}
+	}
 
 	// 寫回檔案
-	if err := os.WriteFile(safePath, []byte(strings.Join(newLines, "\n")), 0600); err != nil {
+	if err := os.WriteFile(safePath, []byte(updated), 0600); err != nil {
 		return &ToolResult{ForLLM: fmt.Sprintf("Failed to write changes: %v", err), IsError: true}
 	}
 
@@ -357,6 +568,8 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 // 輸出格式：每行顯示 [類型] 名稱 (大小)
 //   - [D] 表示目錄 (Directory)
 //   - [F] 表示檔案 (File)
+//
+// tree 模式：以縮排遞迴列出子目錄 (預設深度 3)，略過隱藏檔與 .gitignore 忽略的檔案
 type ListDirTool struct {
 	Sandbox *Sandbox
 }
@@ -371,6 +584,16 @@ func (t *ListDirTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": i18n.GetInstance().T("tool_params.directory_path"),
 			},
+			"tree": map[string]any{
+				"type":        "boolean",
+				"description": i18n.GetInstance().T("tool_params.tree"),
+			},
+			"max_depth": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"maximum":     10,
+				"description": i18n.GetInstance().T("tool_params.tree_depth"),
+			},
 		},
 		"required": []string{"path"},
 	}
@@ -381,8 +604,8 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]any) *ToolRes
 	path, _ := args["path"].(string)
 
 	// 驗證路徑安全性
-	if !isValidPathChars(path) {
-		return &ToolResult{ForLLM: i18n.GetInstance().T("errors.path_invalid"), IsError: true}
+	if err := validatePathName(path); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.path_invalid"), err), IsError: true}
 	}
 
 	// 取得安全路徑
@@ -391,6 +614,10 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]any) *ToolRes
 		return &ToolResult{ForLLM: err.Error(), IsError: true}
 	}
 
+	if boolArg(args, "tree") {
+		return t.tree(ctx, safePath, intArg(args, "max_depth", listTreeDefaultDepth))
+	}
+
 	// 讀取目錄內容
 	entries, err := os.ReadDir(safePath)
 	if err != nil {
@@ -411,3 +638,52 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]any) *ToolRes
 
 	return &ToolResult{ForLLM: sb.String(), IsError: false}
 }
+
+const (
+	listTreeDefaultDepth = 3
+	listTreeMaxEntries   = 500
+)
+
+// tree 以縮排遞迴列出目錄，目錄名稱以 "/" 結尾
+func (t *ListDirTool) tree(ctx context.Context, root string, depth int) *ToolResult {
+	info, err := os.Stat(root)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Failed to read dir: %v", err), IsError: true}
+	}
+	if !info.IsDir() {
+		return &ToolResult{ForLLM: fmt.Sprintf("Failed to read dir: %s is not a directory", t.Sandbox.relPath(root)), IsError: true}
+	}
+
+	var sb strings.Builder
+	count := 0
+	truncated := false
+	opts := walkOptions{SkipHidden: true, SkipIgnored: true, MaxDepth: depth, IncludeDirs: true}
+	err = walkWorkspace(ctx, t.Sandbox, root, opts, func(e walkEntry) error {
+		if count >= listTreeMaxEntries {
+			truncated = true
+			return errStopWalk
+		}
+		count++
+		indent := strings.Repeat("  ", e.Depth-1)
+		if e.Entry.IsDir() {
+			sb.WriteString(indent + e.Entry.Name() + "/\n")
+			return nil
+		}
+		size := int64(0)
+		if fi, err := e.Entry.Info(); err == nil {
+			size = fi.Size()
+		}
+		sb.WriteString(fmt.Sprintf("%s%s (%d bytes)\n", indent, e.Entry.Name(), size))
+		return nil
+	})
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Failed to read dir: %v", err), IsError: true}
+	}
+	if count == 0 {
+		return &ToolResult{ForLLM: "(empty directory)"}
+	}
+	if truncated {
+		sb.WriteString(fmt.Sprintf("\n[Listing truncated at %d entries. List a subdirectory or lower max_depth.]\n", listTreeMaxEntries))
+	}
+	return &ToolResult{ForLLM: sb.String()}
+}
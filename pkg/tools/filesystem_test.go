@@ -2,9 +2,14 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
 )
 
 func TestReadFileTool(t *testing.T) {
@@ -48,6 +53,199 @@ func TestReadFileTool_Errors(t *testing.T) {
 	}
 }
 
+func TestReadFileTool_Ranges(t *testing.T) {
+	tmpDir := t.TempDir()
+	sandbox, _ := NewSandbox(tmpDir)
+	var sb strings.Builder
+	for i := 1; i <= 5000; i++ {
+		fmt.Fprintf(&sb, "line %d\r\n", i)
+	}
+	os.WriteFile(filepath.Join(tmpDir, "big.log"), []byte(sb.String()), 0644)
+	tool := ReadFileTool{Sandbox: sandbox}
+	ctx := context.Background()
+
+	// 預設行數上限
+	res := tool.Execute(ctx, map[string]any{"path": "big.log"})
+	if !strings.HasPrefix(res.ForLLM, "1: line 1\n") || !strings.Contains(res.ForLLM, "2000: line 2000\n") ||
+		strings.Contains(res.ForLLM, "2001:") || !strings.Contains(res.ForLLM, "[Showing lines 1-2000 of 5000. Call read_file again with offset=2001") {
+		t.Errorf("unexpected default page:\n%s", res.ForLLM[len(res.ForLLM)-200:])
+	}
+
+	res = tool.Execute(ctx, map[string]any{"path": "big.log", "offset": float64(4999), "limit": float64(10)})
+	if res.ForLLM != "4999: line 4999\n5000: line 5000\n\n[Showing lines 4999-5000 of 5000.]" {
+		t.Errorf("unexpected tail page: %q", res.ForLLM)
+	}
+
+	res = tool.Execute(ctx, map[string]any{"path": "big.log", "offset": float64(6000)})
+	if !strings.Contains(res.ForLLM, "past the end of the file, which has 5000 lines") {
+		t.Errorf("unexpected result: %q", res.ForLLM)
+	}
+
+	// 位元組上限與過長的單行
+	long := strings.Repeat("x", readFileMaxLineRunes+10)
+	os.WriteFile(filepath.Join(tmpDir, "wide.txt"), []byte(strings.Repeat(long+"\n", 100)), 0644)
+	res = tool.Execute(ctx, map[string]any{"path": "wide.txt"})
+	if len(res.ForLLM) > readFileMaxBytes+200 || !strings.Contains(res.ForLLM, "… [line truncated]") ||
+		!strings.Contains(res.ForLLM, "Call read_file again with offset=") {
+		t.Errorf("expected byte cap and truncated lines, got %d bytes", len(res.ForLLM))
+	}
+}
+
+func TestReadFileTool_Encodings(t *testing.T) {
+	tmpDir := t.TempDir()
+	sandbox, _ := NewSandbox(tmpDir)
+	tool := ReadFileTool{Sandbox: sandbox}
+	ctx := context.Background()
+
+	big5, _ := traditionalchinese.Big5.NewEncoder().String("會議紀錄：\n台北市信義區，下午三點。\n")
+	utf16, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String("héllo\nwörld\n")
+	files := map[string]string{
+		"notes.txt":  big5,
+		"utf16.txt":  utf16,
+		"bom.txt":    "\uFEFF第一行\n",
+		"latin1.txt": "caf\xe9 r\xe9sum\xe9\n",
+		"image.png":  "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		"empty.txt":  "",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644)
+	}
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"path": "notes.txt"}, "[Decoded from Big5]\n1: 會議紀錄：\n2: 台北市信義區，下午三點。\n"},
+		{map[string]any{"path": "utf16.txt"}, "[Decoded from UTF-16LE]\n1: héllo\n2: wörld\n"},
+		{map[string]any{"path": "bom.txt"}, "1: 第一行\n"},
+		{map[string]any{"path": "latin1.txt", "encoding": "latin1"}, "[Decoded from windows-1252]\n1: café résumé\n"},
+		{map[string]any{"path": "empty.txt"}, "(empty file)"},
+	}
+	for _, tt := range tests {
+		res := tool.Execute(ctx, tt.args)
+		if res.IsError || res.ForLLM != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.args, res.ForLLM, tt.want)
+		}
+	}
+
+	res := tool.Execute(ctx, map[string]any{"path": "latin1.txt"})
+	if !strings.Contains(res.ForLLM, "encoding could not be detected") {
+		t.Errorf("expected unknown encoding summary, got %q", res.ForLLM)
+	}
+	res = tool.Execute(ctx, map[string]any{"path": "image.png"})
+	if !strings.Contains(res.ForLLM, "binary file (image/png, 16 bytes)") {
+		t.Errorf("expected binary summary, got %q", res.ForLLM)
+	}
+	res = tool.Execute(ctx, map[string]any{"path": "notes.txt", "encoding": "klingon"})
+	if !res.IsError {
+		t.Error("expected error for unsupported encoding")
+	}
+}
+
+func TestValidatePathName(t *testing.T) {
+	valid := []string{
+		".",
+		"notes.txt",
+		"筆記/會議記錄.md",
+		"資料夾 含空白/報告 (最終版).docx",
+		"日本語/ファイル.txt",
+		"한국어/파일.txt",
+		"emoji/🚀 launch.md",
+		"Ünïcödé/naïve café.txt",
+		"v1..2.txt",
+		"a/b..c/d",
+		"..hidden",
+		"../outside.txt", // 是否在工作區內由 CheckPath 判斷
+		"con_notes.txt",
+		"console.log",
+		"com10.txt",
+		"lpt.txt",
+		"subdir\\file.txt",
+		"C:\\work\\a.txt",
+		"/abs/path/file.go",
+		"name-with-dash_and_underscore.txt",
+		"file\u200bwith-zero-width.txt", // 格式字元 (非控制字元) 允許
+	}
+	for _, p := range valid {
+		if err := validatePathName(p); err != nil {
+			t.Errorf("validatePathName(%q) = %v, want nil", p, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"":                     "empty",
+		"a\x00b.txt":           "NUL",
+		"line\nbreak.txt":      "control character",
+		"tab\there.txt":        "control character",
+		"carriage\rreturn":     "control character",
+		"del\x7f.txt":          "control character",
+		"c1\u0085.txt":         "control character",
+		"escape\x1b[31m.txt":   "control character",
+		"invalid\xff\xfeutf8":  "UTF-8",
+		"evil\u202etxt.exe":    "bidirectional",
+		"isolate\u2066x\u2069": "bidirectional",
+		"CON":                  "device name",
+		"nul":                  "device name",
+		"nul.txt":              "device name",
+		"dir/aux.tar.gz":       "device name",
+		"COM1":                 "device name",
+		"lpt9.log":             "device name",
+		"prn ":                 "device name",
+		"con.":                 "device name",
+		"CONOUT$":              "device name",
+		"sub\\Con\\file.txt":   "device name",
+	}
+	for p, want := range invalid {
+		err := validatePathName(p)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validatePathName(%q) = %v, want error containing %q", p, err, want)
+		}
+	}
+}
+
+func TestFilesystemTools_UnicodePaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	sandbox, _ := NewSandbox(tmpDir)
+	ctx := context.Background()
+
+	os.Mkdir(filepath.Join(tmpDir, "筆記"), 0755)
+	write := WriteFileTool{Sandbox: sandbox}
+	if res := write.Execute(ctx, map[string]any{"path": "筆記/會議記錄.md", "content": "# 週會\n討論事項"}); res.IsError {
+		t.Fatalf("write failed: %s", res.ForLLM)
+	}
+	if res := write.Execute(ctx, map[string]any{"path": "v1..2.txt", "content": "version"}); res.IsError {
+		t.Fatalf("write failed: %s", res.ForLLM)
+	}
+
+	read := ReadFileTool{Sandbox: sandbox}
+	if res := read.Execute(ctx, map[string]any{"path": "筆記/會議記錄.md"}); res.IsError || res.ForLLM != "1: # 週會\n2: 討論事項\n" {
+		t.Errorf("unexpected read result: %+v", res)
+	}
+	if res := read.Execute(ctx, map[string]any{"path": "v1..2.txt"}); res.IsError {
+		t.Errorf("unexpected error: %s", res.ForLLM)
+	}
+
+	list := ListDirTool{Sandbox: sandbox}
+	if res := list.Execute(ctx, map[string]any{"path": "筆記"}); res.IsError || !strings.Contains(res.ForLLM, "會議記錄.md") {
+		t.Errorf("unexpected list result: %+v", res)
+	}
+
+	// 目錄穿越仍由 CheckPath 阻擋
+	for _, p := range []string{"../escape.txt", "筆記/../../escape.txt", "v1..2.txt/../../escape.txt"} {
+		if res := write.Execute(ctx, map[string]any{"path": p, "content": "x"}); !res.IsError {
+			t.Errorf("expected %q to be rejected", p)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(tmpDir), "escape.txt")); !os.IsNotExist(err) {
+		t.Error("file was written outside the workspace")
+	}
+
+	// 危險名稱在進入 CheckPath 之前就被拒絕
+	if res := write.Execute(ctx, map[string]any{"path": "nul.txt", "content": "x"}); !res.IsError || !strings.Contains(res.ForLLM, "errors.path_invalid") {
+		t.Errorf("expected device name to be rejected, got %+v", res)
+	}
+}
+
 func TestWriteFileTool(t *testing.T) {
 	tmpDir := t.TempDir()
 	sandbox, _ := NewSandbox(tmpDir)
@@ -200,3 +398,23 @@ func TestListDirTool_Errors(t *testing.T) {
 		t.Error("expected error for non-existent directory")
 	}
 }
+
+func TestListDirTool_Tree(t *testing.T) {
+	_, sandbox := setupSearchWorkspace(t)
+	tool := ListDirTool{Sandbox: sandbox}
+
+	result := tool.Execute(context.Background(), map[string]any{"path": ".", "tree": true, "max_depth": float64(2)})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	for _, want := range []string{"docs/\n  guide.md (", "pkg/\n  util/\n", "main.go ("} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("expected %q in tree:\n%s", want, result.ForLLM)
+		}
+	}
+	for _, unwanted := range []string{"util.go", "build", ".hidden", ".gitignore"} {
+		if strings.Contains(result.ForLLM, unwanted) {
+			t.Errorf("did not expect %q in tree:\n%s", unwanted, result.ForLLM)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package tools
+
+// ============================================================================
+// FindFilesTool: 依 Glob 尋找檔案工具
+// ============================================================================
+// 功能：在工作區內以 Glob 樣式尋找檔案或目錄
+// 工具名稱：find_files
+// 樣式規則 (相對於搜尋起點)：
+//   - "*.go"      只比對起點目錄下的檔案
+//   - "**/*.go"   比對任意深度的 .go 檔案
+//   - "cmd/**"    比對 cmd 目錄下的所有項目
+//
+// 預設略過隱藏檔與 .gitignore 忽略的檔案，可依名稱或修改時間排序。
+// ============================================================================
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chiisen/mini_bot/pkg/i18n"
+)
+
+const (
+	findDefaultMaxResults = 200
+	findMaxResultsLimit   = 2000
+	findMaxScanned        = 100000 // 走訪項目上限，避免在巨大目錄中耗時過久
+)
+
+type FindFilesTool struct {
+	Sandbox *Sandbox
+}
+
+func (t *FindFilesTool) Name() string        { return "find_files" }
+func (t *FindFilesTool) Description() string { return i18n.GetInstance().T("tools.find_files") }
+func (t *FindFilesTool) Parameters() map[string]any {
+	tr := i18n.GetInstance()
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"pattern": map[string]any{"type": "string", "description": tr.T("tool_params.glob_pattern")},
+			"path":    map[string]any{"type": "string", "description": tr.T("tool_params.search_path")},
+			"type": map[string]any{
+				"type":        "string",
+				"enum":        []string{"file", "dir", "any"},
+				"description": tr.T("tool_params.entry_type"),
+			},
+			"max_depth": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"description": tr.T("tool_params.max_depth"),
+			},
+			"sort": map[string]any{
+				"type":        "string",
+				"enum":        []string{"name", "mtime"},
+				"description": tr.T("tool_params.sort"),
+			},
+			"max_results": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"maximum":     findMaxResultsLimit,
+				"description": tr.T("tool_params.max_files"),
+			},
+			"include_hidden":  map[string]any{"type": "boolean", "description": tr.T("tool_params.include_hidden")},
+			"include_ignored": map[string]any{"type": "boolean", "description": tr.T("tool_params.include_ignored")},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+type foundFile struct {
+	rel   string // 相對於工作區
+	isDir bool
+	mtime time.Time
+}
+
+// Execute 執行尋找
+//
+// 執行步驟：
+//  1. 透過沙盒取得搜尋起點
+//  2. 走訪目錄 (受 max_depth、隱藏檔與 .gitignore 設定限制) 並比對 Glob
+//  3. 依名稱或修改時間 (新到舊) 排序，只回傳前 max_results 筆
+func (t *FindFilesTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	pattern := strings.TrimPrefix(strings.TrimSpace(stringArg(args, "pattern")), "./")
+	path := stringArg(args, "path")
+	if path == "" {
+		path = "."
+	}
+	kind := stringArg(args, "type")
+	if kind == "" {
+		kind = "file"
+	}
+	sortBy := stringArg(args, "sort")
+	maxResults := intArg(args, "max_results", findDefaultMaxResults)
+	if maxResults <= 0 || maxResults > findMaxResultsLimit {
+		maxResults = findDefaultMaxResults
+	}
+
+	if pattern == "" {
+		return &ToolResult{ForLLM: "Error: pattern is required", IsError: true}
+	}
+
+	// 驗證路徑安全性
+	if err := validatePathName(path); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.path_invalid"), err), IsError: true}
+	}
+	root, err := t.Sandbox.CheckPath(path)
+	if err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+
+	opts := walkOptions{
+		SkipHidden:  !boolArg(args, "include_hidden"),
+		SkipIgnored: !boolArg(args, "include_ignored"),
+		MaxDepth:    intArg(args, "max_depth", 0),
+		IncludeDirs: kind != "file",
+	}
+
+	var found []foundFile
+	scanned := 0
+	incomplete := false
+	err = walkWorkspace(ctx, t.Sandbox, root, opts, func(e walkEntry) error {
+		scanned++
+		if scanned > findMaxScanned {
+			incomplete = true
+			return errStopWalk
+		}
+		isDir := e.Entry.IsDir()
+		if kind == "dir" && !isDir {
+			return nil
+		}
+		if !matchGlob(pattern, e.Rel) {
+			return nil
+		}
+		f := foundFile{rel: t.Sandbox.relPath(e.Abs), isDir: isDir}
+		if sortBy == "mtime" {
+			if info, err := e.Entry.Info(); err == nil {
+				f.mtime = info.ModTime()
+			}
+		}
+		found = append(found, f)
+		return nil
+	})
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: search interrupted: %v", err), IsError: true}
+	}
+
+	if len(found) == 0 {
+		return &ToolResult{ForLLM: "No files found."}
+	}
+
+	if sortBy == "mtime" {
+		sort.SliceStable(found, func(i, j int) bool { return found[i].mtime.After(found[j].mtime) })
+	} else {
+		sort.Slice(found, func(i, j int) bool { return found[i].rel < found[j].rel })
+	}
+
+	total := len(found)
+	if total > maxResults {
+		found = found[:maxResults]
+	}
+
+	var sb strings.Builder
+	for _, f := range found {
+		name := f.rel
+		if f.isDir {
+			name += "/"
+		}
+		if sortBy == "mtime" {
+			fmt.Fprintf(&sb, "%s  (%s)\n", name, f.mtime.Format("2006-01-02 15:04:05"))
+		} else {
+			sb.WriteString(name + "\n")
+		}
+	}
+	if total > maxResults {
+		fmt.Fprintf(&sb, "\n[Showing %d of %d matches. Use a more specific pattern or path to narrow the results.]\n", maxResults, total)
+	}
+	if incomplete {
+		fmt.Fprintf(&sb, "\n[Stopped after scanning %d entries; results may be incomplete.]\n", findMaxScanned)
+	}
+	return &ToolResult{ForLLM: sb.String()}
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindFilesTool(t *testing.T) {
+	ws, sandbox := setupSearchWorkspace(t)
+	tool := &FindFilesTool{Sandbox: sandbox}
+	ctx := context.Background()
+
+	res := tool.Execute(ctx, map[string]any{"pattern": "**/*.go"})
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+	if res.ForLLM != "main.go\npkg/util/util.go\n" {
+		t.Errorf("unexpected result:\n%s", res.ForLLM)
+	}
+
+	t.Run("single level pattern", func(t *testing.T) {
+		res := tool.Execute(ctx, map[string]any{"pattern": "*.go"})
+		if res.ForLLM != "main.go\n" {
+			t.Errorf("unexpected result:\n%s", res.ForLLM)
+		}
+	})
+
+	t.Run("path and directories", func(t *testing.T) {
+		res := tool.Execute(ctx, map[string]any{"pattern": "**", "path": "pkg", "type": "dir"})
+		if res.ForLLM != "pkg/util/\n" {
+			t.Errorf("unexpected result:\n%s", res.ForLLM)
+		}
+	})
+
+	t.Run("depth limit", func(t *testing.T) {
+		res := tool.Execute(ctx, map[string]any{"pattern": "**/*", "max_depth": float64(1)})
+		if strings.Contains(res.ForLLM, "util.go") || !strings.Contains(res.ForLLM, "main.go") {
+			t.Errorf("unexpected result:\n%s", res.ForLLM)
+		}
+	})
+
+	t.Run("hidden and ignored", func(t *testing.T) {
+		res := tool.Execute(ctx, map[string]any{"pattern": "**/*.go", "include_ignored": true})
+		if !strings.Contains(res.ForLLM, "build/out.go") {
+			t.Errorf("expected ignored file:\n%s", res.ForLLM)
+		}
+		res = tool.Execute(ctx, map[string]any{"pattern": "**/*.txt", "include_hidden": true})
+		if !strings.Contains(res.ForLLM, ".hidden/secret.txt") {
+			t.Errorf("expected hidden file:\n%s", res.ForLLM)
+		}
+	})
+
+	t.Run("sort by mtime and cap", func(t *testing.T) {
+		old := time.Now().Add(-time.Hour)
+		os.Chtimes(filepath.Join(ws, "main.go"), old, old)
+		res := tool.Execute(ctx, map[string]any{"pattern": "**/*.go", "sort": "mtime", "max_results": float64(1)})
+		if !strings.HasPrefix(res.ForLLM, "pkg/util/util.go  (") || !strings.Contains(res.ForLLM, "Showing 1 of 2") {
+			t.Errorf("unexpected result:\n%s", res.ForLLM)
+		}
+	})
+
+	t.Run("confined to workspace", func(t *testing.T) {
+		if res := tool.Execute(ctx, map[string]any{"pattern": "*", "path": "../"}); !res.IsError {
+			t.Errorf("expected error, got %s", res.ForLLM)
+		}
+	})
+}
@@ -0,0 +1,510 @@
+package tools
+
+// ============================================================================
+// HTML 可讀內容擷取 (Readable HTML Extraction)
+// ============================================================================
+// 將 HTML 頁面轉換為類 Markdown 的純文字，供 web_fetch 工具使用。
+//
+// 設計原理：
+//   - 使用輕量的手寫 Tokenizer，不引入外部 HTML 解析套件
+//   - 優先擷取 <main> / <article> / role="main" 的內容，找不到時退回 <body>
+//   - 略過導覽列、頁尾、側欄、表單、腳本與樣式等雜訊區塊
+//   - 保留標題 (# 標題)、連結 ([文字](網址))、清單 (- 項目) 與 <pre> 程式碼區塊
+// ============================================================================
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type htmlTokenKind int
+
+const (
+	htmlText htmlTokenKind = iota
+	htmlStartTag
+	htmlEndTag
+)
+
+type htmlToken struct {
+	kind        htmlTokenKind
+	name        string            // 小寫標籤名稱
+	attrs       map[string]string // 屬性 (名稱為小寫)
+	text        string            // 文字內容 (已解碼 HTML Entity)
+	selfClosing bool
+}
+
+// rawTextTags 的內容不是 HTML，直接跳到對應的結束標籤
+var rawTextTags = map[string]bool{"script": true, "style": true, "textarea": true, "xmp": true}
+
+// voidTags 沒有結束標籤
+var voidTags = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// skipTags 的整個子樹都不屬於主要內容
+var skipTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true, "canvas": true,
+	"iframe": true, "object": true, "nav": true, "footer": true, "aside": true, "form": true,
+	"button": true, "select": true, "textarea": true, "head": true, "dialog": true,
+}
+
+// blockTags 前後需要換行
+var blockTags = map[string]bool{
+	"div": true, "section": true, "article": true, "main": true, "header": true, "table": true,
+	"ul": true, "ol": true, "dl": true, "dt": true, "dd": true, "tr": true, "figure": true,
+	"figcaption": true, "address": true, "details": true, "summary": true, "caption": true,
+}
+
+// tokenizeHTML 將 HTML 切成標籤與文字
+func tokenizeHTML(doc string) []htmlToken {
+	var tokens []htmlToken
+	i := 0
+	for i < len(doc) {
+		lt := strings.IndexByte(doc[i:], '<')
+		if lt < 0 {
+			tokens = append(tokens, htmlToken{kind: htmlText, text: html.UnescapeString(doc[i:])})
+			break
+		}
+		if lt > 0 {
+			tokens = append(tokens, htmlToken{kind: htmlText, text: html.UnescapeString(doc[i : i+lt])})
+		}
+		i += lt
+		rest := doc[i:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return tokens
+			}
+			i += 4 + end + 3
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+		case strings.HasPrefix(rest, "</"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return tokens
+			}
+			name := strings.ToLower(strings.TrimSpace(rest[2:end]))
+			if sp := strings.IndexAny(name, " \t\r\n"); sp >= 0 {
+				name = name[:sp]
+			}
+			tokens = append(tokens, htmlToken{kind: htmlEndTag, name: name})
+			i += end + 1
+		default:
+			tok, n, ok := parseStartTag(rest)
+			if !ok {
+				// 不是合法的標籤，當作文字處理
+				tokens = append(tokens, htmlToken{kind: htmlText, text: "<"})
+				i++
+				continue
+			}
+			tokens = append(tokens, tok)
+			i += n
+
+			if rawTextTags[tok.name] && !tok.selfClosing {
+				closing := "</" + tok.name
+				end := strings.Index(strings.ToLower(doc[i:]), closing)
+				if end < 0 {
+					return tokens
+				}
+				if tok.name == "textarea" || tok.name == "xmp" {
+					tokens = append(tokens, htmlToken{kind: htmlText, text: html.UnescapeString(doc[i : i+end])})
+				}
+				i += end
+			}
+		}
+	}
+	return tokens
+}
+
+// parseStartTag 解析 "<name attr=value ...>"，回傳 Token 與消耗的位元組數
+func parseStartTag(s string) (htmlToken, int, bool) {
+	i := 1
+	start := i
+	for i < len(s) && isTagNameChar(s[i]) {
+		i++
+	}
+	if i == start {
+		return htmlToken{}, 0, false
+	}
+	tok := htmlToken{kind: htmlStartTag, name: strings.ToLower(s[start:i]), attrs: map[string]string{}}
+
+	for i < len(s) {
+		// 略過空白
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return htmlToken{}, 0, false
+		}
+		if s[i] == '>' {
+			return tok, i + 1, true
+		}
+		if strings.HasPrefix(s[i:], "/>") {
+			tok.selfClosing = true
+			return tok, i + 2, true
+		}
+		if s[i] == '/' {
+			i++
+			continue
+		}
+
+		// 屬性名稱
+		nameStart := i
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '=' && s[i] != '>' && !strings.HasPrefix(s[i:], "/>") {
+			i++
+		}
+		attrName := strings.ToLower(s[nameStart:i])
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		value := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isHTMLSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				q := s[i]
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					return htmlToken{}, 0, false
+				}
+				value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				valStart := i
+				for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				value = s[valStart:i]
+			}
+		}
+		if attrName != "" {
+			tok.attrs[attrName] = html.UnescapeString(value)
+		}
+	}
+	return htmlToken{}, 0, false
+}
+
+func isTagNameChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == ':'
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// extractReadable 擷取頁面標題與主要內容
+//
+// 參數：
+//   - doc:  HTML 原始內容
+//   - base: 頁面網址，用於將相對連結轉為絕對網址
+//
+// 回傳：
+//   - title: <title> 內容
+//   - text:  類 Markdown 的可讀文字
+func extractReadable(doc string, base *url.URL) (title, text string) {
+	tokens := tokenizeHTML(doc)
+	title = extractTitle(tokens)
+
+	start, end := findMainContent(tokens)
+	r := &markdownRenderer{base: base}
+	r.render(tokens[start:end])
+	return title, r.String()
+}
+
+func extractTitle(tokens []htmlToken) string {
+	for i, tok := range tokens {
+		if tok.kind == htmlStartTag && tok.name == "title" {
+			var sb strings.Builder
+			for _, t := range tokens[i+1:] {
+				if t.kind != htmlText {
+					break
+				}
+				sb.WriteString(t.text)
+			}
+			return collapseSpaces(sb.String())
+		}
+	}
+	return ""
+}
+
+// findMainContent 找出主要內容的 Token 範圍：<main>、<article>、role="main"，否則為 <body>
+func findMainContent(tokens []htmlToken) (int, int) {
+	for _, match := range []func(htmlToken) bool{
+		func(t htmlToken) bool { return t.name == "main" || t.attrs["role"] == "main" },
+		func(t htmlToken) bool { return t.name == "article" },
+		func(t htmlToken) bool { return t.name == "body" },
+	} {
+		for i, tok := range tokens {
+			if tok.kind == htmlStartTag && match(tok) {
+				return i + 1, findClosing(tokens, i)
+			}
+		}
+	}
+	return 0, len(tokens)
+}
+
+// findClosing 回傳與 tokens[open] 對應的結束標籤位置 (找不到時為結尾)
+func findClosing(tokens []htmlToken, open int) int {
+	name := tokens[open].name
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.name != name {
+			continue
+		}
+		if tok.kind == htmlStartTag && !tok.selfClosing {
+			depth++
+		} else if tok.kind == htmlEndTag {
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens)
+}
+
+// markdownRenderer 將 Token 轉為類 Markdown 文字
+type markdownRenderer struct {
+	buf       bytes.Buffer
+	base      *url.URL
+	skipDepth int      // 目前位於幾層 skipTags 之內
+	skipStack []string // 略過中的標籤名稱
+	preDepth  int      // 位於 <pre> 內時保留原始空白
+	listDepth int
+	links     []linkState
+}
+
+type linkState struct {
+	href  string
+	start int // "[" 在緩衝區中的位置
+}
+
+func (r *markdownRenderer) render(tokens []htmlToken) {
+	for _, tok := range tokens {
+		if r.skipDepth > 0 {
+			if tok.kind == htmlStartTag && tok.name == r.skipStack[len(r.skipStack)-1] && !tok.selfClosing {
+				r.skipDepth++
+			} else if tok.kind == htmlEndTag && tok.name == r.skipStack[len(r.skipStack)-1] {
+				r.skipDepth--
+				if r.skipDepth == 0 {
+					r.skipStack = r.skipStack[:len(r.skipStack)-1]
+				}
+			}
+			continue
+		}
+
+		switch tok.kind {
+		case htmlText:
+			r.text(tok.text)
+		case htmlStartTag:
+			if skipTags[tok.name] || tok.attrs["aria-hidden"] == "true" || hasHiddenAttr(tok) {
+				if !tok.selfClosing && !voidTags[tok.name] {
+					r.skipDepth = 1
+					r.skipStack = append(r.skipStack, tok.name)
+				}
+				continue
+			}
+			r.start(tok)
+		case htmlEndTag:
+			r.end(tok.name)
+		}
+	}
+	// 關閉未結束的連結
+	for len(r.links) > 0 {
+		r.end("a")
+	}
+}
+
+func hasHiddenAttr(tok htmlToken) bool {
+	_, ok := tok.attrs["hidden"]
+	return ok
+}
+
+func (r *markdownRenderer) start(tok htmlToken) {
+	switch tok.name {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.newline(2)
+		r.buf.WriteString(strings.Repeat("#", int(tok.name[1]-'0')) + " ")
+	case "p", "blockquote":
+		r.newline(2)
+	case "br":
+		r.newline(1)
+	case "hr":
+		r.newline(2)
+		r.buf.WriteString("---")
+		r.newline(2)
+	case "ul", "ol":
+		r.listDepth++
+		r.newline(1)
+	case "li":
+		r.newline(1)
+		indent := r.listDepth - 1
+		if indent < 0 {
+			indent = 0
+		}
+		r.buf.WriteString(strings.Repeat("  ", indent) + "- ")
+	case "pre":
+		r.newline(2)
+		r.buf.WriteString("```\n")
+		r.preDepth++
+	case "code":
+		if r.preDepth == 0 {
+			r.space()
+			r.buf.WriteByte('`')
+		}
+	case "td", "th":
+		r.space()
+	case "a":
+		href := r.resolve(tok.attrs["href"])
+		r.space()
+		r.links = append(r.links, linkState{href: href, start: r.buf.Len()})
+		r.buf.WriteByte('[')
+	default:
+		if blockTags[tok.name] {
+			r.newline(1)
+		}
+	}
+}
+
+func (r *markdownRenderer) end(name string) {
+	switch name {
+	case "h1", "h2", "h3", "h4", "h5", "h6", "p", "blockquote":
+		r.newline(2)
+	case "ul", "ol":
+		if r.listDepth > 0 {
+			r.listDepth--
+		}
+		r.newline(1)
+	case "pre":
+		if r.preDepth > 0 {
+			r.preDepth--
+			r.newline(1)
+			r.buf.WriteString("```")
+			r.newline(2)
+		}
+	case "code":
+		if r.preDepth == 0 {
+			r.buf.WriteByte('`')
+		}
+	case "a":
+		if len(r.links) == 0 {
+			return
+		}
+		link := r.links[len(r.links)-1]
+		r.links = r.links[:len(r.links)-1]
+		label := strings.TrimSpace(r.buf.String()[link.start+1:])
+		switch {
+		case label == "":
+			// 沒有文字的連結 (例如只有圖片) 直接移除
+			r.buf.Truncate(link.start)
+		case link.href == "" || strings.HasPrefix(link.href, "javascript:") || strings.HasPrefix(link.href, "#"):
+			// 不是可開啟的連結，只保留文字
+			r.buf.Truncate(link.start)
+			r.buf.WriteString(label)
+		default:
+			r.buf.Truncate(link.start)
+			r.buf.WriteString("[" + label + "](" + link.href + ")")
+		}
+	default:
+		if blockTags[name] || name == "li" {
+			r.newline(1)
+		}
+	}
+}
+
+// text 寫入文字；<pre> 之外的連續空白會被合併
+func (r *markdownRenderer) text(s string) {
+	if r.preDepth > 0 {
+		r.buf.WriteString(s)
+		return
+	}
+	collapsed := collapseSpaces(s)
+	if collapsed == "" {
+		if s != "" {
+			r.space()
+		}
+		return
+	}
+	if isHTMLSpace(firstByte(s)) {
+		r.space()
+	}
+	r.buf.WriteString(collapsed)
+	if isHTMLSpace(s[len(s)-1]) {
+		r.space()
+	}
+}
+
+// space 在行中加入單一空白 (行首或已有空白時不加)
+func (r *markdownRenderer) space() {
+	b := r.buf.Bytes()
+	if len(b) == 0 {
+		return
+	}
+	switch b[len(b)-1] {
+	case ' ', '\n', '[', '`':
+		return
+	}
+	r.buf.WriteByte(' ')
+}
+
+// newline 確保緩衝區以至少 n 個換行結尾 (並移除行尾空白)
+func (r *markdownRenderer) newline(n int) {
+	b := r.buf.Bytes()
+	end := len(b)
+	for end > 0 && (b[end-1] == ' ' || b[end-1] == '\t') {
+		end--
+	}
+	r.buf.Truncate(end)
+	if end == 0 {
+		return
+	}
+	have := 0
+	for i := end - 1; i >= 0 && b[i] == '\n'; i-- {
+		have++
+	}
+	for ; have < n; have++ {
+		r.buf.WriteByte('\n')
+	}
+}
+
+func (r *markdownRenderer) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || r.base == nil {
+		return href
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return r.base.ResolveReference(u).String()
+}
+
+var excessNewlines = regexp.MustCompile(`\n{3,}`)
+
+func (r *markdownRenderer) String() string {
+	return strings.TrimSpace(excessNewlines.ReplaceAllString(r.buf.String(), "\n\n"))
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func firstByte(s string) byte {
+	if s == "" {
+		return 0
+	}
+	return s[0]
+}
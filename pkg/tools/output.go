@@ -0,0 +1,196 @@
+package tools
+
+// ============================================================================
+// 工具輸出上限 (Tool Output Policy)
+// ============================================================================
+// exec、read_file 或 MCP 工具的結果可能有數 MB，原封不動放進對話會塞滿上下文。
+// 註冊表在工具執行後套用 OutputPolicy：
+//   - 估計的 Token 數超過 MaxTokens 時，只保留開頭與結尾
+//   - 完整輸出保存到 workspace/.tool-output/<id>.txt，LLM 可以用 read_file 分頁讀取
+//   - 目錄只保留最近 MaxFiles 個檔案
+// ============================================================================
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultOutputMaxTokens = 8000
+	defaultOutputMaxFiles  = 100
+)
+
+// OutputPolicy 限制單一工具結果的大小
+type OutputPolicy struct {
+	MaxTokens int    // 超過時截斷，0 使用預設值
+	MaxFiles  int    // Dir 中保留的檔案數，0 使用預設值
+	Dir       string // 完整輸出的保存目錄，空白表示只截斷不保存
+	Workspace string // 用於在提示中顯示相對路徑
+
+	seq atomic.Int64
+}
+
+// NewOutputPolicy 建立將完整輸出保存到 workspace/.tool-output 的政策
+func NewOutputPolicy(workspace string, maxTokens int) *OutputPolicy {
+	return &OutputPolicy{
+		MaxTokens: maxTokens,
+		Dir:       filepath.Join(workspace, ".tool-output"),
+		Workspace: workspace,
+	}
+}
+
+// Apply 在結果過長時截斷並保存完整輸出；未超過上限時原樣回傳
+func (p *OutputPolicy) Apply(toolName string, res *ToolResult) *ToolResult {
+	if p == nil || res == nil {
+		return res
+	}
+	limit := p.MaxTokens
+	if limit <= 0 {
+		limit = defaultOutputMaxTokens
+	}
+	total := estimateTokens(res.ForLLM)
+	if total <= limit {
+		return res
+	}
+
+	head := cutHeadTokens(res.ForLLM, limit/2)
+	tail := cutTailTokens(res.ForLLM[len(head):], limit/2)
+	omitted := res.ForLLM[len(head) : len(res.ForLLM)-len(tail)]
+	lines := strings.Count(res.ForLLM, "\n") + 1
+
+	var notice string
+	if saved, err := p.save(toolName, res.ForLLM); err == nil {
+		// 保存後的檔案第一行就是輸出的第一行，省略的部分從 head 的下一行開始
+		nextLine := strings.Count(head, "\n") + 1
+		notice = fmt.Sprintf("[Output truncated: about %d tokens, %d lines in total; %d lines omitted here. The full output was saved to %s. Read it with read_file path=%q offset=%d limit=200.]",
+			total, lines, strings.Count(omitted, "\n"), saved, saved, nextLine)
+	} else {
+		notice = fmt.Sprintf("[Output truncated: about %d tokens, %d lines in total; %d lines omitted here. The full output could not be saved: %v]",
+			total, lines, strings.Count(omitted, "\n"), err)
+	}
+	return &ToolResult{
+		ForLLM:  strings.TrimRight(head, "\n") + "\n\n... " + notice + " ...\n\n" + strings.TrimLeft(tail, "\n"),
+		IsError: res.IsError,
+	}
+}
+
+// save 將完整輸出寫入 Dir，回傳相對於工作區的路徑
+func (p *OutputPolicy) save(toolName, output string) (string, error) {
+	if p.Dir == "" {
+		return "", fmt.Errorf("no output directory configured")
+	}
+	if err := os.MkdirAll(p.Dir, 0755); err != nil {
+		return "", err
+	}
+	id := fmt.Sprintf("%s-%s-%d", time.Now().Format("20060102-150405"), sanitizeOutputName(toolName), p.seq.Add(1))
+	path := filepath.Join(p.Dir, id+".txt")
+	if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+		return "", err
+	}
+	p.prune()
+
+	if rel, err := filepath.Rel(p.Workspace, path); err == nil && p.Workspace != "" && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel), nil
+	}
+	return path, nil
+}
+
+// prune 只保留最近 MaxFiles 個輸出檔
+func (p *OutputPolicy) prune() {
+	keep := p.MaxFiles
+	if keep <= 0 {
+		keep = defaultOutputMaxFiles
+	}
+	entries, err := os.ReadDir(p.Dir)
+	if err != nil || len(entries) <= keep {
+		return
+	}
+	type file struct {
+		name string
+		mod  time.Time
+	}
+	var files []file
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && info.Mode().IsRegular() {
+			files = append(files, file{e.Name(), info.ModTime()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].mod.Equal(files[j].mod) {
+			return files[i].mod.Before(files[j].mod)
+		}
+		return files[i].name < files[j].name
+	})
+	for i := 0; i < len(files)-keep; i++ {
+		os.Remove(filepath.Join(p.Dir, files[i].name))
+	}
+}
+
+func sanitizeOutputName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && (r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// estimateTokens 粗略估計 Token 數：ASCII 約 4 個字元一個 Token，其他字元 (例如中文) 約一個字一個 Token
+func estimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// runeTokens 是單一字元的 Token 估計值 (以四分之一 Token 為單位)
+func runeTokens(r rune) int {
+	if r < utf8.RuneSelf {
+		return 1
+	}
+	return 4
+}
+
+// cutHeadTokens 回傳開頭約 budget 個 Token 的內容，盡量在換行處切斷
+func cutHeadTokens(s string, budget int) string {
+	quarters := budget * 4
+	end := 0
+	for i, r := range s {
+		if quarters -= runeTokens(r); quarters < 0 {
+			break
+		}
+		end = i + utf8.RuneLen(r)
+	}
+	if nl := strings.LastIndexByte(s[:end], '\n'); nl > end/2 {
+		end = nl + 1
+	}
+	return s[:end]
+}
+
+// cutTailTokens 回傳結尾約 budget 個 Token 的內容，盡量在換行處切斷
+func cutTailTokens(s string, budget int) string {
+	quarters := budget * 4
+	start := len(s)
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(s[:start])
+		if quarters -= runeTokens(r); quarters < 0 {
+			break
+		}
+		start -= size
+	}
+	if nl := strings.IndexByte(s[start:], '\n'); nl >= 0 && nl < (len(s)-start)/2 {
+		start += nl + 1
+	}
+	return s[start:]
+}
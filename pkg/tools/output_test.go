@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestOutputPolicy_SpillsLongResults(t *testing.T) {
+	ws := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	var sb strings.Builder
+	for i := 1; i <= 5000; i++ {
+		fmt.Fprintf(&sb, "line %d of the build log\n", i)
+	}
+	full := sb.String()
+
+	r := NewRegistry()
+	r.Output = NewOutputPolicy(ws, 1000)
+	r.Register(&mockTool{name: "exec", executeFunc: func(ctx context.Context, args map[string]any) *ToolResult {
+		return &ToolResult{ForLLM: full, IsError: true}
+	}})
+	r.Register(&mockTool{name: "short", executeFunc: func(ctx context.Context, args map[string]any) *ToolResult {
+		return &ToolResult{ForLLM: "ok"}
+	}})
+
+	if res := r.Execute(context.Background(), "short", map[string]any{}); res.ForLLM != "ok" {
+		t.Errorf("short output must not change, got %q", res.ForLLM)
+	}
+
+	res := r.Execute(context.Background(), "exec", map[string]any{})
+	if !res.IsError {
+		t.Error("error flag must be preserved")
+	}
+	if got := estimateTokens(res.ForLLM); got > 1200 {
+		t.Errorf("truncated output is still %d tokens", got)
+	}
+	if !strings.HasPrefix(res.ForLLM, "line 1 of the build log\n") || !strings.HasSuffix(res.ForLLM, "line 5000 of the build log\n") {
+		t.Errorf("expected head and tail to be kept:\n%s", res.ForLLM)
+	}
+
+	m := regexp.MustCompile(`read_file path="([^"]+)" offset=(\d+)`).FindStringSubmatch(res.ForLLM)
+	if m == nil || !strings.HasPrefix(m[1], ".tool-output/") || !strings.Contains(m[1], "-exec-") {
+		t.Fatalf("missing read_file hint:\n%s", res.ForLLM)
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, m[1])); string(data) != full {
+		t.Error("saved output does not match the full result")
+	}
+
+	// 提示中的 offset 指向第一個被省略的行
+	offset, _ := strconv.Atoi(m[2])
+	page := (&ReadFileTool{Sandbox: sandbox}).Execute(context.Background(), map[string]any{"path": m[1], "offset": float64(offset), "limit": float64(1)})
+	if !strings.Contains(page.ForLLM, fmt.Sprintf("line %d of the build log", offset)) || strings.Contains(res.ForLLM, fmt.Sprintf("line %d of", offset)) {
+		t.Errorf("offset %d does not point at the first omitted line: %s", offset, page.ForLLM)
+	}
+}
+
+func TestOutputPolicy_KeepsRecentFiles(t *testing.T) {
+	ws := t.TempDir()
+	p := NewOutputPolicy(ws, 10)
+	p.MaxFiles = 3
+	for i := 0; i < 5; i++ {
+		p.Apply("exec", &ToolResult{ForLLM: strings.Repeat("x", 400)})
+	}
+	entries, _ := os.ReadDir(filepath.Join(ws, ".tool-output"))
+	if len(entries) != 3 {
+		t.Errorf("expected 3 files, got %d", len(entries))
+	}
+
+	// 未設定目錄時只截斷
+	res := (&OutputPolicy{MaxTokens: 10}).Apply("exec", &ToolResult{ForLLM: strings.Repeat("y", 400)})
+	if !strings.Contains(res.ForLLM, "could not be saved") || len(res.ForLLM) > 300 {
+		t.Errorf("unexpected result: %s", res.ForLLM)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := estimateTokens("abcdefgh"); got != 2 {
+		t.Errorf("ascii: got %d", got)
+	}
+	if got := estimateTokens("繁體中文"); got != 4 {
+		t.Errorf("cjk: got %d", got)
+	}
+	head := cutHeadTokens(strings.Repeat("中", 100), 10)
+	if head != strings.Repeat("中", 10) {
+		t.Errorf("cut must respect rune boundaries, got %q", head)
+	}
+}
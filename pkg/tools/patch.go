@@ -0,0 +1,551 @@
+package tools
+
+// ============================================================================
+// 修補檔解析與套用 (Patch Parsing and Application)
+// ============================================================================
+// 支援兩種格式：
+//   1. Unified diff (git diff / diff -u 的輸出)，可包含多個檔案，
+//      以 /dev/null 表示新增或刪除檔案
+//   2. 多檔案修補封包：
+//        *** Begin Patch
+//        *** Update File: path      (可接 *** Move to: new/path)
+//        @@ 定位用的上下文行 (選填)
+//         上下文 / -刪除 / +新增
+//        *** Add File: path         (內容每行以 + 開頭)
+//        *** Delete File: path
+//        *** End Patch
+//
+// 模糊比對 (依序嘗試)：
+//   - 在預期行號附近精確比對，找不到時搜尋整個檔案
+//   - 忽略行尾空白、忽略前後空白
+//   - 去掉 hunk 頭尾各最多 2 行上下文 (與 GNU patch 的 fuzz 相同)
+// ============================================================================
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type patchOp int
+
+const (
+	patchUpdate patchOp = iota
+	patchAdd
+	patchDelete
+)
+
+// filePatch 是單一檔案的變更
+type filePatch struct {
+	op      patchOp
+	oldPath string
+	newPath string // 與 oldPath 不同時表示更名
+	hunks   []patchHunk
+}
+
+// path 回傳用於顯示的路徑
+func (fp *filePatch) path() string {
+	if fp.op == patchAdd {
+		return fp.newPath
+	}
+	return fp.oldPath
+}
+
+type patchLine struct {
+	kind byte // ' '、'-'、'+'
+	text string
+}
+
+type patchHunk struct {
+	header    string // 原始 @@ 標頭，用於錯誤訊息
+	oldStart  int    // 預期的起始行 (1 起算，0 表示未知)
+	anchor    string // 封包格式 "@@ xxx" 的定位文字
+	lines     []patchLine
+	newNoEOL  bool // 新內容最後一行沒有換行
+	oldNoEOL  bool // 舊內容最後一行沒有換行
+	endOfFile bool // 封包格式 "*** End of File"：hunk 必須位於檔案結尾
+}
+
+func (h *patchHunk) oldLines() []string {
+	var out []string
+	for _, l := range h.lines {
+		if l.kind != '+' {
+			out = append(out, l.text)
+		}
+	}
+	return out
+}
+
+func (h *patchHunk) newLines() []string {
+	var out []string
+	for _, l := range h.lines {
+		if l.kind != '-' {
+			out = append(out, l.text)
+		}
+	}
+	return out
+}
+
+// ============================================================================
+// 解析
+// ============================================================================
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parsePatch 解析修補檔，自動判斷格式
+func parsePatch(text string) ([]*filePatch, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	// 去掉模型常加的 Markdown 程式碼區塊
+	for len(lines) > 0 && (strings.TrimSpace(lines[0]) == "" || strings.HasPrefix(lines[0], "```")) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && (strings.TrimSpace(lines[len(lines)-1]) == "" || strings.HasPrefix(lines[len(lines)-1], "```")) {
+		lines = lines[:len(lines)-1]
+	}
+
+	var patches []*filePatch
+	var err error
+	if len(lines) > 0 && strings.HasPrefix(strings.TrimSpace(lines[0]), "*** Begin Patch") {
+		patches, err = parseEnvelope(lines)
+	} else {
+		patches, err = parseUnified(lines)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no file changes found in patch")
+	}
+	return patches, nil
+}
+
+// parseUnified 解析 unified diff
+func parseUnified(lines []string) ([]*filePatch, error) {
+	var patches []*filePatch
+	var cur *filePatch
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			cur = nil
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			oldPath := diffPath(line[4:])
+			newPath := diffPath(lines[i+1][4:])
+			i++
+			cur = &filePatch{op: patchUpdate, oldPath: oldPath, newPath: newPath}
+			switch {
+			case oldPath == "" && newPath == "":
+				return nil, fmt.Errorf("line %d: missing file names", i)
+			case oldPath == "":
+				cur.op = patchAdd
+			case newPath == "":
+				cur.op, cur.newPath = patchDelete, oldPath
+			}
+			patches = append(patches, cur)
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: hunk without a preceding ---/+++ file header", i+1)
+			}
+			hunk, next, err := parseUnifiedHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			cur.hunks = append(cur.hunks, hunk)
+			i = next - 1
+		}
+		// 其他行 (index、mode、說明文字等) 直接略過
+	}
+
+	for _, p := range patches {
+		if p.op != patchDelete && len(p.hunks) == 0 {
+			return nil, fmt.Errorf("%s: no hunks", p.path())
+		}
+	}
+	return patches, nil
+}
+
+// parseUnifiedHunk 從 lines[start] (@@ 標頭) 開始解析一個 hunk，回傳下一個未處理的行號
+func parseUnifiedHunk(lines []string, start int) (patchHunk, int, error) {
+	header := lines[start]
+	hunk := patchHunk{header: header}
+	oldLeft, newLeft := -1, -1
+	if m := hunkHeaderRe.FindStringSubmatch(header); m != nil {
+		hunk.oldStart, _ = strconv.Atoi(m[1])
+		oldLeft, newLeft = 1, 1
+		if m[2] != "" {
+			oldLeft, _ = strconv.Atoi(m[2])
+		}
+		if m[4] != "" {
+			newLeft, _ = strconv.Atoi(m[4])
+		}
+	}
+
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		countsLeft := oldLeft > 0 || newLeft > 0
+		if isPatchHeader(lines, i) && (!countsLeft || strings.HasPrefix(line, "@@ ") || strings.HasPrefix(line, "diff --git ")) {
+			break
+		}
+		if oldLeft == 0 && newLeft == 0 && !strings.HasPrefix(line, `\`) {
+			// 行數已用完：只在內容明顯屬於 hunk 時繼續 (模型常算錯行數)
+			if line == "" || (line[0] != ' ' && line[0] != '+' && line[0] != '-') {
+				break
+			}
+		}
+
+		var pl patchLine
+		switch {
+		case line == "":
+			// 模型常把空白上下文行的前置空格省略
+			pl = patchLine{' ', ""}
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			pl = patchLine{line[0], line[1:]}
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" 修飾前一行
+			if n := len(hunk.lines); n > 0 {
+				if hunk.lines[n-1].kind == '+' {
+					hunk.newNoEOL = true
+				} else if hunk.lines[n-1].kind == '-' {
+					hunk.oldNoEOL = true
+				} else {
+					hunk.newNoEOL, hunk.oldNoEOL = true, true
+				}
+			}
+			continue
+		default:
+			return hunk, i, fmt.Errorf("line %d: unexpected line in hunk %q: %q", i+1, header, line)
+		}
+		hunk.lines = append(hunk.lines, pl)
+		if pl.kind != '+' && oldLeft > 0 {
+			oldLeft--
+		}
+		if pl.kind != '-' && newLeft > 0 {
+			newLeft--
+		}
+	}
+
+	if len(hunk.lines) == 0 {
+		return hunk, i, fmt.Errorf("hunk %q is empty", header)
+	}
+	return hunk, i, nil
+}
+
+// isPatchHeader 判斷 lines[i] 是否為檔案或 hunk 標頭
+func isPatchHeader(lines []string, i int) bool {
+	line := lines[i]
+	return strings.HasPrefix(line, "@@") || strings.HasPrefix(line, "diff --git ") ||
+		(strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "))
+}
+
+// diffPath 從 ---/+++ 行取出路徑：去掉時間戳記與 a/ b/ 前綴，/dev/null 回傳空字串
+func diffPath(s string) string {
+	if tab := strings.IndexByte(s, '\t'); tab >= 0 {
+		s = s[:tab]
+	}
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(s, `"`) {
+		if unq, err := strconv.Unquote(s); err == nil {
+			s = unq
+		}
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
+
+// parseEnvelope 解析 "*** Begin Patch" 封包格式
+func parseEnvelope(lines []string) ([]*filePatch, error) {
+	var patches []*filePatch
+	var cur *filePatch
+	var hunk *patchHunk
+
+	flush := func() {
+		if cur != nil && hunk != nil && len(hunk.lines) > 0 {
+			cur.hunks = append(cur.hunks, *hunk)
+		}
+		hunk = nil
+	}
+
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "*** End Patch"):
+			flush()
+			return patches, nil
+		case strings.HasPrefix(line, "*** Update File:"):
+			flush()
+			p := strings.TrimSpace(strings.TrimPrefix(line, "*** Update File:"))
+			cur = &filePatch{op: patchUpdate, oldPath: p, newPath: p}
+			patches = append(patches, cur)
+		case strings.HasPrefix(line, "*** Add File:"):
+			flush()
+			cur = &filePatch{op: patchAdd, newPath: strings.TrimSpace(strings.TrimPrefix(line, "*** Add File:"))}
+			patches = append(patches, cur)
+			hunk = &patchHunk{header: "*** Add File"}
+		case strings.HasPrefix(line, "*** Delete File:"):
+			flush()
+			p := strings.TrimSpace(strings.TrimPrefix(line, "*** Delete File:"))
+			cur = &filePatch{op: patchDelete, oldPath: p, newPath: p}
+			patches = append(patches, cur)
+		case strings.HasPrefix(line, "*** Move to:"):
+			if cur == nil || cur.op != patchUpdate {
+				return nil, fmt.Errorf("line %d: *** Move to must follow *** Update File", i+1)
+			}
+			cur.newPath = strings.TrimSpace(strings.TrimPrefix(line, "*** Move to:"))
+		case strings.HasPrefix(trimmed, "*** End of File"):
+			if hunk != nil {
+				hunk.endOfFile = true
+			}
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil || cur.op != patchUpdate {
+				return nil, fmt.Errorf("line %d: @@ outside of an *** Update File section", i+1)
+			}
+			flush()
+			hunk = &patchHunk{header: line, anchor: strings.TrimSpace(strings.Trim(line, "@ "))}
+			if m := hunkHeaderRe.FindStringSubmatch(line); m != nil {
+				hunk.oldStart, _ = strconv.Atoi(m[1])
+				hunk.anchor = ""
+			}
+		default:
+			if cur == nil {
+				if trimmed == "" {
+					continue
+				}
+				return nil, fmt.Errorf("line %d: expected a *** file header, got %q", i+1, line)
+			}
+			if cur.op == patchDelete {
+				if trimmed == "" {
+					continue
+				}
+				return nil, fmt.Errorf("line %d: unexpected content after *** Delete File", i+1)
+			}
+			if hunk == nil {
+				hunk = &patchHunk{header: "@@"}
+			}
+			switch {
+			case line == "":
+				hunk.lines = append(hunk.lines, patchLine{' ', ""})
+			case cur.op == patchAdd && line[0] != '+':
+				return nil, fmt.Errorf("line %d: lines in *** Add File must start with '+'", i+1)
+			case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+				hunk.lines = append(hunk.lines, patchLine{line[0], line[1:]})
+			default:
+				return nil, fmt.Errorf("line %d: unexpected line %q", i+1, line)
+			}
+		}
+	}
+	return nil, fmt.Errorf("missing *** End Patch")
+}
+
+// ============================================================================
+// 套用
+// ============================================================================
+
+// hunkResult 記錄 hunk 的套用方式，用於回報
+type hunkResult struct {
+	line   int    // 實際套用的起始行 (1 起算)
+	offset int    // 與預期行號的差距
+	fuzz   string // 使用的模糊比對方式，精確比對時為空
+}
+
+// hunkReject 描述無法套用的 hunk
+type hunkReject struct {
+	index  int
+	header string
+	reason string
+}
+
+// applyHunks 將 hunks 套用到內容上
+//
+// 回傳：
+//   - string:       新內容
+//   - []hunkResult: 每個成功的 hunk 的套用資訊
+//   - []hunkReject: 無法套用的 hunk (非空時新內容無效)
+func applyHunks(content string, hunks []patchHunk) (string, []hunkResult, []hunkReject) {
+	crlf := strings.Contains(content, "\r\n")
+	if crlf {
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+	}
+	endsNL := content == "" || strings.HasSuffix(content, "\n")
+	lines := splitLines(content)
+
+	var results []hunkResult
+	var rejects []hunkReject
+	delta := 0 // 先前 hunk 造成的行數位移
+	minPos := 0
+
+	for idx, h := range hunks {
+		old := h.oldLines()
+		expected := max(h.oldStart-1+delta, 0)
+		if len(old) == 0 && h.oldStart > 0 {
+			// "@@ -5,0 +6,2 @@" 表示插入在第 5 行之後
+			expected = h.oldStart + delta
+		}
+		if h.oldStart == 0 {
+			expected = minPos
+			if h.anchor != "" {
+				if a := findAnchor(lines, h.anchor, minPos); a >= 0 {
+					expected = a + 1
+				}
+			}
+		}
+
+		pos, trimStart, trimEnd, fuzz := locateHunk(lines, &h, expected, minPos)
+		if pos < 0 {
+			reason := "context lines not found"
+			if len(old) == 0 {
+				reason = "nothing to anchor the insertion to"
+			}
+			rejects = append(rejects, hunkReject{index: idx + 1, header: h.header, reason: reason})
+			continue
+		}
+
+		// 去掉的上下文不參與取代
+		newLines := h.newLines()
+		newLines = newLines[trimStart : len(newLines)-trimEnd]
+		oldLen := len(old) - trimStart - trimEnd
+
+		replaced := make([]string, 0, len(lines)-oldLen+len(newLines))
+		replaced = append(replaced, lines[:pos]...)
+		replaced = append(replaced, newLines...)
+		replaced = append(replaced, lines[pos+oldLen:]...)
+		atEnd := pos+oldLen == len(lines)
+		lines = replaced
+
+		if atEnd {
+			if h.newNoEOL {
+				endsNL = false
+			} else if h.oldNoEOL {
+				endsNL = true
+			}
+		}
+
+		start := pos - trimStart // 含被去掉的上下文時 hunk 的起點
+		results = append(results, hunkResult{line: start + 1, offset: start - expected, fuzz: fuzz})
+		if h.oldStart > 0 && len(old) > 0 {
+			delta = start - (h.oldStart - 1) + len(h.newLines()) - len(old)
+		} else if h.oldStart > 0 {
+			delta = start - h.oldStart + len(newLines)
+		}
+		minPos = pos + len(newLines)
+	}
+
+	out := strings.Join(lines, "\n")
+	if endsNL && len(lines) > 0 {
+		out += "\n"
+	}
+	if crlf {
+		out = strings.ReplaceAll(out, "\n", "\r\n")
+	}
+	return out, results, rejects
+}
+
+// lineMatchers 依嚴格程度排列的行比對方式
+var lineMatchers = []struct {
+	name  string
+	equal func(a, b string) bool
+}{
+	{"", func(a, b string) bool { return a == b }},
+	{"ignoring trailing whitespace", func(a, b string) bool {
+		return strings.TrimRight(a, " \t") == strings.TrimRight(b, " \t")
+	}},
+	{"ignoring indentation", func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) }},
+}
+
+// locateHunk 找出 old 在 lines 中的位置
+//
+// 回傳：
+//   - pos:       起始索引 (-1 表示找不到)
+//   - trimStart: 從 hunk 開頭去掉的上下文行數
+//   - trimEnd:   從 hunk 結尾去掉的上下文行數
+//   - fuzz:      使用的模糊比對描述
+func locateHunk(lines []string, h *patchHunk, expected, minPos int) (pos, trimStart, trimEnd int, fuzz string) {
+	old := h.oldLines()
+	if len(old) == 0 {
+		// 純新增：插入在預期位置 (新檔案或附加在結尾)
+		return min(max(expected, minPos), len(lines)), 0, 0, ""
+	}
+
+	for fuzzLevel := 0; fuzzLevel <= 2; fuzzLevel++ {
+		ts, te := h.contextRun(fuzzLevel)
+		if fuzzLevel > 0 && ts == 0 && te == 0 {
+			continue
+		}
+		target := old[ts : len(old)-te]
+		if len(target) == 0 {
+			continue
+		}
+		for _, m := range lineMatchers {
+			if p := searchLines(lines, target, expected+ts, minPos, h.endOfFile && te == 0, m.equal); p >= 0 {
+				desc := m.name
+				if fuzzLevel > 0 {
+					if desc != "" {
+						desc += ", "
+					}
+					desc += fmt.Sprintf("fuzz %d", fuzzLevel)
+				}
+				return p, ts, te, desc
+			}
+		}
+	}
+	return -1, 0, 0, ""
+}
+
+// contextRun 回傳模糊比對時可從開頭與結尾去掉的上下文行數 (各最多 n 行)
+func (h *patchHunk) contextRun(n int) (lead, trail int) {
+	for lead < n && lead < len(h.lines) && h.lines[lead].kind == ' ' {
+		lead++
+	}
+	for trail < n && trail < len(h.lines)-lead && h.lines[len(h.lines)-1-trail].kind == ' ' {
+		trail++
+	}
+	return lead, trail
+}
+
+// searchLines 從 expected 開始向兩側搜尋 target，回傳最近的符合位置
+func searchLines(lines, target []string, expected, minPos int, mustEnd bool, equal func(a, b string) bool) int {
+	last := len(lines) - len(target)
+	if last < minPos {
+		return -1
+	}
+	matchAt := func(p int) bool {
+		if mustEnd && p != last {
+			return false
+		}
+		for k, t := range target {
+			if !equal(lines[p+k], t) {
+				return false
+			}
+		}
+		return true
+	}
+	expected = min(max(expected, minPos), last)
+	for d := 0; expected-d >= minPos || expected+d <= last; d++ {
+		if p := expected - d; p >= minPos && matchAt(p) {
+			return p
+		}
+		if p := expected + d; d > 0 && p <= last && matchAt(p) {
+			return p
+		}
+	}
+	return -1
+}
+
+// findAnchor 找出包含 anchor 文字的第一行 (從 from 開始)
+func findAnchor(lines []string, anchor string, from int) int {
+	for i := from; i < len(lines); i++ {
+		if strings.Contains(lines[i], anchor) || strings.TrimSpace(lines[i]) == anchor {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,256 @@
+package tools
+
+// ============================================================================
+// PermissionPolicy: 工具權限政策
+// ============================================================================
+// 依呼叫者 (頻道、聊天室、使用者) 決定一次執行可以使用哪些工具。
+//
+// 規則依序比對，第一條符合呼叫者的規則生效；沒有任何規則符合時不限制：
+//   - Channel / ChatID / UserID：空白表示任意值，可使用 * 與 ? 萬用字元
+//   - Allow：允許的工具名稱，空白表示全部允許，例如 "web_search"、"issues_*"
+//   - Deny：拒絕的工具名稱，優先於 Allow，"*" 表示拒絕所有工具
+//   - Args：依工具名稱限制參數值，參數值必須符合其中一個樣式，
+//     例如 exec 的 command 只允許 "git status"；含有 .. 路徑元件的值一律拒絕，
+//     避免 "notes/*" 允許 "notes/../secret.md"
+//
+// exec 與 process_start 的 command 規則是命令規則：不比對整個命令字串，而是命令解析後的
+// 每個管線階段 (argv 以空白連接) 都必須符合其中一個樣式，避免 "git status | cat /etc/passwd"
+// 這類寫法。exec 的命令規則套用到所有經由 exec 沙盒執行的命令，包含 process_start
+// 與自訂命令工具；process_start 的命令規則只額外限制 process_start。
+//
+// 不允許的工具不會出現在提供給 LLM 的工具列表中，即使 LLM 仍然呼叫也會被拒絕。
+// ============================================================================
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/chiisen/mini_bot/pkg/i18n"
+)
+
+// Caller 描述一次執行的來源
+type Caller struct {
+	Channel string // 例如 "telegram"、"cli"
+	ChatID  string
+	UserID  string
+}
+
+type callerCtx struct{}
+
+// WithCaller 回傳帶有呼叫者資訊的 context，供權限政策判斷
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerCtx{}, caller)
+}
+
+// CallerFrom 取得 ctx 中的呼叫者，沒有時回傳空的 Caller
+func CallerFrom(ctx context.Context) Caller {
+	caller, _ := ctx.Value(callerCtx{}).(Caller)
+	return caller
+}
+
+// PermissionRule 是單一呼叫者範圍的工具權限
+type PermissionRule struct {
+	Channel string
+	ChatID  string
+	UserID  string
+
+	Allow []string                       // 允許的工具名稱樣式，空白表示全部
+	Deny  []string                       // 拒絕的工具名稱樣式
+	Args  map[string]map[string][]string // 工具名稱 -> 參數名稱 -> 允許的值樣式
+}
+
+// PermissionPolicy 是依序比對的權限規則
+type PermissionPolicy struct {
+	Rules []PermissionRule
+}
+
+// ruleFor 回傳第一條符合呼叫者的規則，沒有時回傳 nil (不限制)
+func (p *PermissionPolicy) ruleFor(caller Caller) *PermissionRule {
+	if p == nil {
+		return nil
+	}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if matchField(rule.Channel, caller.Channel) &&
+			matchField(rule.ChatID, caller.ChatID) &&
+			matchField(rule.UserID, caller.UserID) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// AllowsTool 判斷呼叫者是否可以使用指定工具 (不檢查參數)
+func (p *PermissionPolicy) AllowsTool(caller Caller, name string) bool {
+	rule := p.ruleFor(caller)
+	if rule == nil {
+		return true
+	}
+	if matchAnyWildcard(rule.Deny, name) {
+		return false
+	}
+	return len(rule.Allow) == 0 || matchAnyWildcard(rule.Allow, name)
+}
+
+// Check 檢查呼叫者是否可以用這組參數呼叫工具
+//
+// 回傳：
+//   - error: 不允許時的原因，會直接交給 LLM
+func (p *PermissionPolicy) Check(caller Caller, name string, args map[string]any) error {
+	if !p.AllowsTool(caller, name) {
+		return fmt.Errorf(i18n.GetInstance().T("errors.tool_not_permitted"), name)
+	}
+	rule := p.ruleFor(caller)
+	if rule == nil {
+		return nil
+	}
+
+	// exec 類工具的命令先逐一檢查管線階段；執行時 ExecTool 會再以展開後的 argv 檢查一次
+	if sets := commandPatterns(rule, name); sets != nil && execCommandTools[name] {
+		command, _ := args[execCommandArg].(string)
+		words, err := parseCommandLine(command)
+		if err != nil || len(words) == 0 {
+			return fmt.Errorf(i18n.GetInstance().T("errors.tool_arg_not_permitted"), name, execCommandArg, command)
+		}
+		for _, stage := range words {
+			argv := make([]string, len(stage))
+			for i, w := range stage {
+				argv[i] = w.text
+			}
+			if err := checkCommandPatterns(sets, name, argv); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 依名稱排序後檢查，讓錯誤訊息穩定
+	for _, toolPattern := range sortedKeys(rule.Args) {
+		if !matchWildcard(toolPattern, name) {
+			continue
+		}
+		argRules := rule.Args[toolPattern]
+		for _, argName := range sortedKeys(argRules) {
+			if isCommandRule(toolPattern, argName) {
+				continue // 命令規則已依管線階段檢查
+			}
+			value := ""
+			if v, ok := args[argName]; ok && v != nil {
+				value = fmt.Sprint(v)
+			}
+			if hasParentSegment(value) || !matchAnyWildcard(argRules[argName], value) {
+				return fmt.Errorf(i18n.GetInstance().T("errors.tool_arg_not_permitted"), name, argName, value)
+			}
+		}
+	}
+	return nil
+}
+
+// execCommandArg 是命令規則的參數名稱 (Args["exec"]["command"])
+const execCommandArg = "command"
+
+// execCommandTools 是以 command 參數執行命令的工具
+var execCommandTools = map[string]bool{"exec": true, "process_start": true}
+
+// isCommandRule 判斷 Args 中的項目是否為命令規則 (工具樣式符合任一 execCommandTools)
+func isCommandRule(toolPattern, argName string) bool {
+	if argName != execCommandArg {
+		return false
+	}
+	for tool := range execCommandTools {
+		if matchWildcard(toolPattern, tool) {
+			return true
+		}
+	}
+	return false
+}
+
+// commandPatterns 回傳套用到工具 name 的命令規則：符合 exec 的規則套用到所有經由
+// exec 沙盒執行的工具，其餘只套用到符合 name 的工具。每組樣式都必須符合；
+// 沒有命令規則時回傳 nil (不限制)
+func commandPatterns(rule *PermissionRule, name string) [][]string {
+	var sets [][]string
+	for _, toolPattern := range sortedKeys(rule.Args) {
+		if !matchWildcard(toolPattern, "exec") && !matchWildcard(toolPattern, name) {
+			continue
+		}
+		for argName, values := range rule.Args[toolPattern] {
+			if isCommandRule(toolPattern, argName) {
+				sets = append(sets, values) // 空白列表表示不允許任何命令
+			}
+		}
+	}
+	return sets
+}
+
+// CheckCommand 檢查呼叫者是否可以用工具 name 執行單一管線階段，由 ExecTool 在執行前呼叫
+func (p *PermissionPolicy) CheckCommand(caller Caller, name string, argv []string) error {
+	rule := p.ruleFor(caller)
+	if rule == nil {
+		return nil
+	}
+	if sets := commandPatterns(rule, name); sets != nil {
+		return checkCommandPatterns(sets, name, argv)
+	}
+	return nil
+}
+
+func checkCommandPatterns(sets [][]string, name string, argv []string) error {
+	stage := strings.Join(argv, " ")
+	for _, patterns := range sets {
+		if slices.ContainsFunc(argv, hasParentSegment) || !matchAnyWildcard(patterns, stage) {
+			return fmt.Errorf(i18n.GetInstance().T("errors.tool_arg_not_permitted"), name, execCommandArg, stage)
+		}
+	}
+	return nil
+}
+
+// hasParentSegment 判斷值是否含有 .. 路徑元件；萬用字元的 * 也會比對 / 與 ..，
+// 所以這類值無法以樣式限制在某個目錄內
+func hasParentSegment(value string) bool {
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+type commandCheckCtx struct{}
+
+// withCommandCheck 回傳帶有命令檢查的 context，讓經由 exec 沙盒執行的工具
+// (exec、process_start、自訂命令工具) 在執行前檢查每個管線階段
+func withCommandCheck(ctx context.Context, check func(argv []string) error) context.Context {
+	return context.WithValue(ctx, commandCheckCtx{}, check)
+}
+
+// commandCheckFrom 取得 ctx 中的命令檢查，沒有時回傳 nil
+func commandCheckFrom(ctx context.Context) func(argv []string) error {
+	check, _ := ctx.Value(commandCheckCtx{}).(func(argv []string) error)
+	return check
+}
+
+// matchField 比對規則欄位，空白表示任意值
+func matchField(pattern, value string) bool {
+	return pattern == "" || matchWildcard(pattern, value)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func matchAnyWildcard(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if matchWildcard(pattern, s) {
+			return true
+		}
+	}
+	return false
+}
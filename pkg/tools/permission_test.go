@@ -0,0 +1,208 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func familyPolicy() *PermissionPolicy {
+	return &PermissionPolicy{Rules: []PermissionRule{
+		{Channel: "telegram", UserID: "1001", Deny: []string{"process_*"}},
+		{Channel: "telegram", ChatID: "-100*", Allow: []string{"web_search"}},
+		{Channel: "telegram", Allow: []string{"read_file", "exec"}, Args: map[string]map[string][]string{
+			"exec": {"arg": {"git status*", "git log*"}},
+		}},
+	}}
+}
+
+func TestPermissionPolicy_AllowsTool(t *testing.T) {
+	p := familyPolicy()
+	tests := []struct {
+		caller Caller
+		tool   string
+		want   bool
+	}{
+		{Caller{Channel: "telegram", ChatID: "-100200", UserID: "1001"}, "exec", true}, // owner rule matches first
+		{Caller{Channel: "telegram", ChatID: "1001", UserID: "1001"}, "process_start", false},
+		{Caller{Channel: "telegram", ChatID: "-100200", UserID: "2002"}, "web_search", true},
+		{Caller{Channel: "telegram", ChatID: "-100200", UserID: "2002"}, "exec", false},
+		{Caller{Channel: "telegram", ChatID: "2002", UserID: "2002"}, "read_file", true},
+		{Caller{Channel: "telegram", ChatID: "2002", UserID: "2002"}, "write_file", false},
+		{Caller{Channel: "cli"}, "write_file", true}, // no rule matches
+		{Caller{}, "write_file", true},
+	}
+	for _, tt := range tests {
+		if got := p.AllowsTool(tt.caller, tt.tool); got != tt.want {
+			t.Errorf("AllowsTool(%+v, %s) = %v, want %v", tt.caller, tt.tool, got, tt.want)
+		}
+	}
+
+	var nilPolicy *PermissionPolicy
+	if !nilPolicy.AllowsTool(Caller{Channel: "telegram"}, "exec") {
+		t.Error("nil policy should allow everything")
+	}
+}
+
+func TestPermissionPolicy_CheckArgs(t *testing.T) {
+	p := familyPolicy()
+	caller := Caller{Channel: "telegram", ChatID: "2002", UserID: "2002"}
+
+	if err := p.Check(caller, "exec", map[string]any{"arg": "git status -s"}); err != nil {
+		t.Errorf("git status should be allowed: %v", err)
+	}
+	if err := p.Check(caller, "exec", map[string]any{"arg": "git push"}); err == nil {
+		t.Error("git push should be rejected")
+	}
+	if err := p.Check(caller, "exec", map[string]any{}); err == nil {
+		t.Error("a missing restricted argument should be rejected")
+	}
+	if err := p.Check(caller, "write_file", nil); err == nil {
+		t.Error("write_file is not in the allow list")
+	}
+}
+
+func TestRegistry_Permissions(t *testing.T) {
+	r := NewRegistry()
+	ran := false
+	for _, name := range []string{"exec", "read_file", "web_search", "write_file"} {
+		r.Register(&mockTool{name: name, executeFunc: func(ctx context.Context, args map[string]any) *ToolResult {
+			ran = true
+			return &ToolResult{ForLLM: "ok"}
+		}})
+	}
+	r.Permissions = familyPolicy()
+
+	group := Caller{Channel: "telegram", ChatID: "-100200", UserID: "2002"}
+	defs := r.DefinitionsFor(group)
+	if len(defs) != 1 || defs[0].Function.Name != "web_search" {
+		t.Fatalf("group should only see web_search, got %+v", defs)
+	}
+	if len(r.Definitions()) != 4 {
+		t.Error("Definitions should stay unfiltered")
+	}
+	if len(r.DefinitionsFor(Caller{Channel: "cli"})) != 4 {
+		t.Error("callers without a matching rule should see every tool")
+	}
+
+	// tools hidden from the LLM are also refused when called anyway
+	ctx := WithCaller(context.Background(), group)
+	result := r.Execute(ctx, "write_file", map[string]any{"arg": "x"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "tool_not_permitted") || ran {
+		t.Fatalf("expected permission error without running the tool, got %+v", result)
+	}
+
+	ctx = WithCaller(context.Background(), Caller{Channel: "telegram", ChatID: "2002", UserID: "2002"})
+	result = r.Execute(ctx, "exec", map[string]any{"arg": "git push"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "tool_arg_not_permitted") || ran {
+		t.Fatalf("expected argument permission error, got %+v", result)
+	}
+	result = r.Execute(ctx, "exec", map[string]any{"arg": "git log -n 3"})
+	if result.IsError || !ran {
+		t.Fatalf("expected git log to run, got %+v", result)
+	}
+}
+
+func TestPermissionPolicy_CommandRules(t *testing.T) {
+	p := &PermissionPolicy{Rules: []PermissionRule{
+		{Channel: "telegram", Args: map[string]map[string][]string{
+			"exec": {"command": {"git status", "git log --oneline"}},
+		}},
+	}}
+	caller := Caller{Channel: "telegram", ChatID: "2002"}
+
+	for _, tool := range []string{"exec", "process_start"} {
+		if err := p.Check(caller, tool, map[string]any{"command": "git status"}); err != nil {
+			t.Errorf("%s: git status should be allowed: %v", tool, err)
+		}
+		// 每個管線階段都必須符合，不能在允許的命令後面接上其他命令
+		for _, cmd := range []string{"git status | cat /etc/passwd", "git status --output=x", "echo 'git status'", ""} {
+			if err := p.Check(caller, tool, map[string]any{"command": cmd}); err == nil {
+				t.Errorf("%s: %q should be rejected", tool, cmd)
+			}
+		}
+	}
+	if err := p.CheckCommand(caller, "exec", []string{"cat", "/etc/passwd"}); err == nil {
+		t.Error("CheckCommand should apply the command rule to a single stage")
+	}
+	if err := p.CheckCommand(Caller{Channel: "cli"}, "exec", []string{"cat", "x"}); err != nil {
+		t.Errorf("callers without a matching rule are unrestricted: %v", err)
+	}
+}
+
+func TestPermissionPolicy_ProcessStartCommandRules(t *testing.T) {
+	p := &PermissionPolicy{Rules: []PermissionRule{
+		{Args: map[string]map[string][]string{
+			"process_start": {"command": {"npm run *"}},
+		}},
+	}}
+	caller := Caller{Channel: "telegram"}
+
+	if err := p.Check(caller, "process_start", map[string]any{"command": "npm run dev"}); err != nil {
+		t.Errorf("npm run dev should be allowed: %v", err)
+	}
+	// process_start 的命令規則同樣逐一比對管線階段，而不是整個命令字串
+	for _, cmd := range []string{"npm run dev | cat /etc/passwd", "npm run x | tee out.txt"} {
+		if err := p.Check(caller, "process_start", map[string]any{"command": cmd}); err == nil {
+			t.Errorf("%q should be rejected", cmd)
+		}
+	}
+	if err := p.CheckCommand(caller, "process_start", []string{"cat", "x"}); err == nil {
+		t.Error("CheckCommand should apply the process_start rule")
+	}
+	// 只限制 process_start，exec 不受影響
+	if err := p.CheckCommand(caller, "exec", []string{"cat", "x"}); err != nil {
+		t.Errorf("exec is not restricted by a process_start rule: %v", err)
+	}
+}
+
+func TestPermissionPolicy_ArgsRejectParentSegments(t *testing.T) {
+	p := &PermissionPolicy{Rules: []PermissionRule{
+		{Args: map[string]map[string][]string{
+			"read_file": {"path": {"notes/*"}},
+			"exec":      {"command": {"cat notes/*"}},
+		}},
+	}}
+	caller := Caller{Channel: "telegram"}
+
+	if err := p.Check(caller, "read_file", map[string]any{"path": "notes/todo.md"}); err != nil {
+		t.Errorf("notes/todo.md should be allowed: %v", err)
+	}
+	for _, path := range []string{"notes/../secret.md", "notes/a/../../secret.md", `notes\..\secret.md`} {
+		if err := p.Check(caller, "read_file", map[string]any{"path": path}); err == nil {
+			t.Errorf("%q should be rejected", path)
+		}
+	}
+	if err := p.Check(caller, "exec", map[string]any{"command": "cat notes/todo.md"}); err != nil {
+		t.Errorf("cat notes/todo.md should be allowed: %v", err)
+	}
+	if err := p.Check(caller, "exec", map[string]any{"command": "cat notes/../secret.md"}); err == nil {
+		t.Error("command arguments with .. should be rejected")
+	}
+}
+
+func TestRegistry_CommandRulesApplyToCustomTools(t *testing.T) {
+	sandbox, _ := NewSandbox(t.TempDir())
+	execTool := &ExecTool{Sandbox: sandbox}
+	custom, err := NewCustomTool(CustomToolManifest{Name: "shout", Description: "x", Command: "echo {{msg}} | tr a-z A-Z"}, execTool, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry()
+	r.Register(execTool)
+	r.Register(custom)
+	r.Permissions = &PermissionPolicy{Rules: []PermissionRule{
+		{Args: map[string]map[string][]string{"exec": {"command": {"echo *"}}}},
+	}}
+	ctx := WithCaller(context.Background(), Caller{Channel: "telegram"})
+
+	if res := r.Execute(ctx, "exec", map[string]any{"command": "echo hi"}); res.IsError {
+		t.Fatalf("echo should run: %s", res.ForLLM)
+	}
+	if res := r.Execute(ctx, "exec", map[string]any{"command": "echo hi | tr a-z A-Z"}); !res.IsError {
+		t.Error("the tr stage is not allowed by the command rule")
+	}
+	if res := r.Execute(ctx, "shout", map[string]any{"msg": "hi"}); !res.IsError || !strings.Contains(res.ForLLM, "tool_arg_not_permitted") {
+		t.Errorf("custom command tools must follow the command rule, got %+v", res)
+	}
+}
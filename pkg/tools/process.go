@@ -0,0 +1,514 @@
+package tools
+
+// ============================================================================
+// 背景行程 (Background Processes)
+// ============================================================================
+// 開發伺服器、測試監看等長時間執行的命令會超過 exec 的逾時限制，
+// 因此改由 ProcessManager 在背景執行，並提供以下工具：
+//   - process_start：啟動背景行程 (與 exec 相同的命令解析、白名單與隔離)
+//   - process_poll ：讀取上次之後的新輸出與目前狀態
+//   - process_list ：列出此會話的背景行程
+//   - process_input：寫入行程的 stdin
+//   - process_kill ：終止行程 (包含它產生的子行程)
+//
+// 行程屬於啟動它的會話，其他會話看不到也無法操作；
+// 每個會話同時執行的行程數有上限。程式結束時呼叫 Shutdown 終止所有行程。
+// ============================================================================
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chiisen/mini_bot/pkg/i18n"
+)
+
+const (
+	defaultMaxProcessesPerSession = 4
+	defaultProcessOutputBytes     = 1 << 20 // 每個行程保留的輸出 (超過時丟棄最舊的部分)
+	processPollMaxBytes           = 32 << 10
+	processPollMaxWait            = 30 * time.Second
+	maxFinishedPerSession         = 10
+)
+
+// ProcessManager 管理所有背景行程
+type ProcessManager struct {
+	MaxPerSession  int // 每個會話同時執行的行程數上限，0 使用預設值
+	MaxOutputBytes int // 每個行程保留的輸出位元組數，0 使用預設值
+
+	mu     sync.Mutex
+	procs  map[string]*bgProcess
+	nextID int
+	closed bool
+}
+
+// NewProcessManager 建立背景行程管理器
+func NewProcessManager() *ProcessManager {
+	return &ProcessManager{procs: make(map[string]*bgProcess)}
+}
+
+// bgProcess 是一個背景行程
+type bgProcess struct {
+	id      string
+	session string
+	command string
+	started time.Time
+	cancel  context.CancelFunc
+	pipe    *pipeline
+	output  *processOutput
+	done    chan struct{}
+
+	mu       sync.Mutex
+	ended    time.Time
+	exitCode int
+	exitErr  error
+	killed   bool
+	readPos  int64 // 已由 process_poll 讀取的位置
+}
+
+// Start 在背景啟動一條已通過檢查的管線
+func (m *ProcessManager) Start(session, command, dir string, stages [][]string, isolation *ExecIsolation) (*bgProcess, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, fmt.Errorf("process manager is shut down")
+	}
+	limit := m.MaxPerSession
+	if limit <= 0 {
+		limit = defaultMaxProcessesPerSession
+	}
+	running := 0
+	for _, p := range m.procs {
+		if p.session == session && !p.exited() {
+			running++
+		}
+	}
+	if running >= limit {
+		return nil, fmt.Errorf("this session already has %d running background processes (limit %d); stop one with process_kill first", running, limit)
+	}
+	m.pruneFinished(session)
+
+	outputLimit := m.MaxOutputBytes
+	if outputLimit <= 0 {
+		outputLimit = defaultProcessOutputBytes
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &processOutput{limit: outputLimit}
+	pipe, err := startPipeline(ctx, dir, stages, isolation, out, true)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	m.nextID++
+	p := &bgProcess{
+		id:      fmt.Sprintf("p%d", m.nextID),
+		session: session,
+		command: command,
+		started: time.Now(),
+		cancel:  cancel,
+		pipe:    pipe,
+		output:  out,
+		done:    make(chan struct{}),
+	}
+	m.procs[p.id] = p
+	go p.wait()
+	return p, nil
+}
+
+// pruneFinished 移除此會話最舊的已結束行程，只保留最近的 maxFinishedPerSession 個 (呼叫端需持有 m.mu)
+func (m *ProcessManager) pruneFinished(session string) {
+	var finished []*bgProcess
+	for _, p := range m.procs {
+		if p.session == session && p.exited() {
+			finished = append(finished, p)
+		}
+	}
+	if len(finished) < maxFinishedPerSession {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].started.Before(finished[j].started) })
+	for _, p := range finished[:len(finished)-maxFinishedPerSession+1] {
+		delete(m.procs, p.id)
+	}
+}
+
+// Get 取得此會話的行程
+func (m *ProcessManager) Get(session, id string) (*bgProcess, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.procs[strings.TrimSpace(id)]
+	if !ok || p.session != session {
+		return nil, fmt.Errorf("no background process %q in this session; use process_list to see running processes", id)
+	}
+	return p, nil
+}
+
+// List 列出此會話的行程 (依啟動順序)
+func (m *ProcessManager) List(session string) []*bgProcess {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*bgProcess
+	for _, p := range m.procs {
+		if p.session == session {
+			list = append(list, p)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].started.Before(list[j].started) })
+	return list
+}
+
+// Shutdown 終止所有背景行程並等待它們結束，之後無法再啟動新的行程
+func (m *ProcessManager) Shutdown() {
+	m.mu.Lock()
+	m.closed = true
+	procs := make([]*bgProcess, 0, len(m.procs))
+	for _, p := range m.procs {
+		procs = append(procs, p)
+	}
+	m.mu.Unlock()
+
+	for _, p := range procs {
+		p.kill(5 * time.Second)
+	}
+}
+
+// wait 等待管線結束並記錄結束狀態
+func (p *bgProcess) wait() {
+	err := p.pipe.wait()
+	p.mu.Lock()
+	p.ended = time.Now()
+	p.exitErr = err
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		p.exitCode = 0
+	case errors.As(err, &exitErr):
+		p.exitCode = exitErr.ExitCode()
+	default:
+		p.exitCode = -1
+	}
+	p.mu.Unlock()
+	p.cancel()
+	close(p.done)
+}
+
+func (p *bgProcess) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// kill 終止整個行程群組並等待結束
+func (p *bgProcess) kill(timeout time.Duration) bool {
+	if p.exited() {
+		return false
+	}
+	p.mu.Lock()
+	p.killed = true
+	p.mu.Unlock()
+	p.cancel()
+	select {
+	case <-p.done:
+	case <-time.After(timeout):
+	}
+	return true
+}
+
+// status 回傳行程狀態的一行描述
+func (p *bgProcess) status() string {
+	if !p.exited() {
+		return fmt.Sprintf("running for %s", time.Since(p.started).Round(time.Second))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	elapsed := p.ended.Sub(p.started).Round(time.Second)
+	switch {
+	case p.killed:
+		return fmt.Sprintf("killed after %s", elapsed)
+	case p.exitCode == -1 && p.exitErr != nil:
+		return fmt.Sprintf("failed after %s: %v", elapsed, p.exitErr)
+	}
+	return fmt.Sprintf("exited with code %d after %s", p.exitCode, elapsed)
+}
+
+// readNew 回傳上次讀取之後的新輸出 (最多 processPollMaxBytes)
+func (p *bgProcess) readNew() (text string, dropped int64, more bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	data, next, dropped := p.output.since(p.readPos, processPollMaxBytes)
+	p.readPos = next
+	return string(data), dropped, next < p.output.total()
+}
+
+// processOutput 是只保留最後 limit 個位元組的輸出緩衝區
+type processOutput struct {
+	mu      sync.Mutex
+	limit   int
+	buf     []byte
+	written int64 // 總共寫入的位元組數
+}
+
+func (o *processOutput) Write(b []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf = append(o.buf, b...)
+	if over := len(o.buf) - o.limit; over > 0 {
+		o.buf = append(o.buf[:0], o.buf[over:]...)
+	}
+	o.written += int64(len(b))
+	return len(b), nil
+}
+
+func (o *processOutput) total() int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.written
+}
+
+// since 回傳從 pos 開始最多 max 個位元組，以及下一次讀取的位置與被丟棄的位元組數
+func (o *processOutput) since(pos int64, max int) ([]byte, int64, int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	first := o.written - int64(len(o.buf))
+	var dropped int64
+	if pos < first {
+		dropped = first - pos
+		pos = first
+	}
+	data := o.buf[pos-first:]
+	if len(data) > max {
+		data = data[:max]
+	}
+	return append([]byte(nil), data...), pos + int64(len(data)), dropped
+}
+
+// ============================================================================
+// ProcessStartTool: 啟動背景行程
+// ============================================================================
+type ProcessStartTool struct {
+	Exec    *ExecTool // 命令解析、白名單與隔離設定與 exec 相同
+	Manager *ProcessManager
+}
+
+func (t *ProcessStartTool) Name() string { return "process_start" }
+func (t *ProcessStartTool) Description() string {
+	return i18n.GetInstance().T("tools.process_start")
+}
+func (t *ProcessStartTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"command": map[string]any{"type": "string", "description": i18n.GetInstance().T("tool_params.command")},
+		},
+		"required": []string{"command"},
+	}
+}
+
+func (t *ProcessStartTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	command := stringArg(args, "command")
+	stages, err := t.Exec.prepare(ctx, command)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: command rejected by the exec sandbox: %v", err), IsError: true}
+	}
+	p, err := t.Manager.Start(SessionKeyFrom(ctx), command, t.Exec.Sandbox.Workspace, stages, t.Exec.isolation())
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+	return &ToolResult{ForLLM: fmt.Sprintf("Started background process %s: %s\nUse process_poll with id %q to read its output.", p.id, command, p.id)}
+}
+
+// ============================================================================
+// ProcessPollTool: 讀取背景行程的新輸出
+// ============================================================================
+type ProcessPollTool struct {
+	Manager *ProcessManager
+}
+
+func (t *ProcessPollTool) Name() string { return "process_poll" }
+func (t *ProcessPollTool) Description() string {
+	return i18n.GetInstance().T("tools.process_poll")
+}
+func (t *ProcessPollTool) Parameters() map[string]any {
+	tr := i18n.GetInstance()
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":           map[string]any{"type": "string", "description": tr.T("tool_params.process_id")},
+			"wait_seconds": map[string]any{"type": "integer", "description": tr.T("tool_params.wait_seconds")},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *ProcessPollTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	p, err := t.Manager.Get(SessionKeyFrom(ctx), stringArg(args, "id"))
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+
+	// 等待新輸出或行程結束
+	wait := time.Duration(intArg(args, "wait_seconds", 0)) * time.Second
+	if wait > processPollMaxWait {
+		wait = processPollMaxWait
+	}
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) && !p.exited() && p.output.total() <= p.readPosition() {
+		select {
+		case <-ctx.Done():
+			deadline = time.Now()
+		case <-p.done:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	return &ToolResult{ForLLM: formatProcessOutput(p)}
+}
+
+func (p *bgProcess) readPosition() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.readPos
+}
+
+// formatProcessOutput 組合行程狀態與新輸出
+func formatProcessOutput(p *bgProcess) string {
+	status := p.status()
+	text, dropped, more := p.readNew()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Process %s is %s.\n", p.id, status)
+	if dropped > 0 {
+		fmt.Fprintf(&sb, "[%d earlier bytes were discarded]\n", dropped)
+	}
+	if text == "" {
+		sb.WriteString("(no new output)")
+	} else {
+		sb.WriteString("Output:\n")
+		sb.WriteString(text)
+	}
+	if more {
+		sb.WriteString("\n[More output is available. Call process_poll again to continue.]")
+	}
+	return sb.String()
+}
+
+// ============================================================================
+// ProcessListTool: 列出背景行程
+// ============================================================================
+type ProcessListTool struct {
+	Manager *ProcessManager
+}
+
+func (t *ProcessListTool) Name() string { return "process_list" }
+func (t *ProcessListTool) Description() string {
+	return i18n.GetInstance().T("tools.process_list")
+}
+func (t *ProcessListTool) Parameters() map[string]any {
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+func (t *ProcessListTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	list := t.Manager.List(SessionKeyFrom(ctx))
+	if len(list) == 0 {
+		return &ToolResult{ForLLM: "No background processes in this session."}
+	}
+	var sb strings.Builder
+	for _, p := range list {
+		fmt.Fprintf(&sb, "%s  %s  %s\n", p.id, p.status(), p.command)
+	}
+	return &ToolResult{ForLLM: strings.TrimRight(sb.String(), "\n")}
+}
+
+// ============================================================================
+// ProcessInputTool: 寫入背景行程的 stdin
+// ============================================================================
+type ProcessInputTool struct {
+	Manager *ProcessManager
+}
+
+func (t *ProcessInputTool) Name() string { return "process_input" }
+func (t *ProcessInputTool) Description() string {
+	return i18n.GetInstance().T("tools.process_input")
+}
+func (t *ProcessInputTool) Parameters() map[string]any {
+	tr := i18n.GetInstance()
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":          map[string]any{"type": "string", "description": tr.T("tool_params.process_id")},
+			"input":       map[string]any{"type": "string", "description": tr.T("tool_params.process_input")},
+			"close_stdin": map[string]any{"type": "boolean", "description": tr.T("tool_params.close_stdin")},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *ProcessInputTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	p, err := t.Manager.Get(SessionKeyFrom(ctx), stringArg(args, "id"))
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+	if p.exited() {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: process %s has already %s", p.id, p.status()), IsError: true}
+	}
+	input := stringArg(args, "input")
+	if input != "" {
+		// 行程沒有讀取 stdin 時寫入會阻塞，不能讓工具呼叫一直等下去
+		written := make(chan error, 1)
+		go func() {
+			_, err := p.pipe.stdin.Write([]byte(input))
+			written <- err
+		}()
+		select {
+		case err = <-written:
+		case <-time.After(5 * time.Second):
+			err = fmt.Errorf("the process is not reading its input")
+		}
+		if err != nil {
+			return &ToolResult{ForLLM: fmt.Sprintf("Error: failed to write to process %s: %v", p.id, err), IsError: true}
+		}
+	}
+	if boolArg(args, "close_stdin") {
+		p.pipe.stdin.Close()
+		return &ToolResult{ForLLM: fmt.Sprintf("Wrote %d bytes to process %s and closed its stdin.", len(input), p.id)}
+	}
+	return &ToolResult{ForLLM: fmt.Sprintf("Wrote %d bytes to process %s.", len(input), p.id)}
+}
+
+// ============================================================================
+// ProcessKillTool: 終止背景行程
+// ============================================================================
+type ProcessKillTool struct {
+	Manager *ProcessManager
+}
+
+func (t *ProcessKillTool) Name() string { return "process_kill" }
+func (t *ProcessKillTool) Description() string {
+	return i18n.GetInstance().T("tools.process_kill")
+}
+func (t *ProcessKillTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id": map[string]any{"type": "string", "description": i18n.GetInstance().T("tool_params.process_id")},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *ProcessKillTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	p, err := t.Manager.Get(SessionKeyFrom(ctx), stringArg(args, "id"))
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+	p.kill(5 * time.Second)
+	return &ToolResult{ForLLM: formatProcessOutput(p)}
+}
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newProcessTools(t *testing.T, manager *ProcessManager) (*ProcessStartTool, *ProcessPollTool) {
+	sandbox, _ := NewSandbox(t.TempDir())
+	exec := &ExecTool{Sandbox: sandbox, Policy: DefaultExecPolicy().Merge(map[string]CommandRule{"sh": {}})}
+	return &ProcessStartTool{Exec: exec, Manager: manager}, &ProcessPollTool{Manager: manager}
+}
+
+func TestProcessTools_Lifecycle(t *testing.T) {
+	manager := NewProcessManager()
+	defer manager.Shutdown()
+	start, poll := newProcessTools(t, manager)
+	ctx := WithSessionKey(context.Background(), "cli_default")
+
+	res := start.Execute(ctx, map[string]any{"command": `sh -c 'echo ready; read line; echo "got $line"; exit 3'`})
+	if res.IsError || !strings.Contains(res.ForLLM, "Started background process p1") {
+		t.Fatalf("unexpected result: %s", res.ForLLM)
+	}
+
+	res = poll.Execute(ctx, map[string]any{"id": "p1", "wait_seconds": float64(5)})
+	if !strings.Contains(res.ForLLM, "is running") || !strings.Contains(res.ForLLM, "ready") {
+		t.Fatalf("unexpected poll: %s", res.ForLLM)
+	}
+	// 已讀過的輸出不會重複回傳
+	if res = poll.Execute(ctx, map[string]any{"id": "p1"}); !strings.Contains(res.ForLLM, "(no new output)") {
+		t.Errorf("expected no new output, got %s", res.ForLLM)
+	}
+
+	input := &ProcessInputTool{Manager: manager}
+	if res = input.Execute(ctx, map[string]any{"id": "p1", "input": "hello\n"}); res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+	<-manager.procs["p1"].done
+	res = poll.Execute(ctx, map[string]any{"id": "p1"})
+	if !strings.Contains(res.ForLLM, "exited with code 3") || !strings.Contains(res.ForLLM, "got hello") {
+		t.Errorf("unexpected poll: %s", res.ForLLM)
+	}
+	if res = input.Execute(ctx, map[string]any{"id": "p1", "input": "x"}); !res.IsError {
+		t.Error("expected error writing to an exited process")
+	}
+
+	list := (&ProcessListTool{Manager: manager}).Execute(ctx, nil)
+	if !strings.Contains(list.ForLLM, "p1  exited with code 3") {
+		t.Errorf("unexpected list: %s", list.ForLLM)
+	}
+
+	// 被拒絕的命令不會啟動
+	if res = start.Execute(ctx, map[string]any{"command": "rm -rf ."}); !res.IsError || !strings.Contains(res.ForLLM, "exec sandbox") {
+		t.Errorf("unexpected result: %s", res.ForLLM)
+	}
+}
+
+func TestProcessTools_SessionLimitsAndKill(t *testing.T) {
+	manager := NewProcessManager()
+	manager.MaxPerSession = 1
+	start, poll := newProcessTools(t, manager)
+	alice := WithSessionKey(context.Background(), "telegram_1")
+	bob := WithSessionKey(context.Background(), "telegram_2")
+
+	if res := start.Execute(alice, map[string]any{"command": "sleep 30"}); res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+	if res := start.Execute(alice, map[string]any{"command": "sleep 30"}); !res.IsError || !strings.Contains(res.ForLLM, "limit 1") {
+		t.Errorf("expected session limit error, got %s", res.ForLLM)
+	}
+	if res := start.Execute(bob, map[string]any{"command": "sleep 30"}); res.IsError {
+		t.Fatalf("other sessions must not be limited: %s", res.ForLLM)
+	}
+
+	// 其他會話無法讀取或終止
+	if res := poll.Execute(bob, map[string]any{"id": "p1"}); !res.IsError {
+		t.Errorf("expected p1 to be hidden from another session, got %s", res.ForLLM)
+	}
+	if res := (&ProcessKillTool{Manager: manager}).Execute(bob, map[string]any{"id": "p1"}); !res.IsError {
+		t.Errorf("expected kill from another session to fail, got %s", res.ForLLM)
+	}
+
+	res := (&ProcessKillTool{Manager: manager}).Execute(alice, map[string]any{"id": "p1"})
+	if res.IsError || !strings.Contains(res.ForLLM, "killed") {
+		t.Errorf("unexpected result: %s", res.ForLLM)
+	}
+	if res := start.Execute(alice, map[string]any{"command": "sleep 30"}); res.IsError {
+		t.Errorf("expected a free slot after kill: %s", res.ForLLM)
+	}
+
+	began := time.Now()
+	manager.Shutdown()
+	if time.Since(began) > 3*time.Second {
+		t.Error("shutdown did not kill processes promptly")
+	}
+	for _, p := range manager.procs {
+		if !p.exited() {
+			t.Errorf("process %s still running after shutdown", p.id)
+		}
+	}
+	if res := start.Execute(alice, map[string]any{"command": "sleep 1"}); !res.IsError {
+		t.Error("expected start after shutdown to fail")
+	}
+}
+
+func TestProcessOutput_KeepsTail(t *testing.T) {
+	out := &processOutput{limit: 8}
+	out.Write([]byte("0123"))
+	data, next, dropped := out.since(0, 100)
+	if string(data) != "0123" || next != 4 || dropped != 0 {
+		t.Fatalf("got %q %d %d", data, next, dropped)
+	}
+	out.Write([]byte("456789abcd"))
+	data, next, dropped = out.since(next, 3)
+	if string(data) != "678" || next != 9 || dropped != 2 {
+		t.Errorf("got %q %d %d", data, next, dropped)
+	}
+}
@@ -0,0 +1,217 @@
+package tools
+
+// ============================================================================
+// Quota: 工作區配額
+// ============================================================================
+// 限制檔案工具可以寫入的內容，避免 Agent 以 write_file / append_file 迴圈塞滿磁碟：
+//   - MaxFileBytes：單一檔案寫入後的大小 (所有根目錄都適用)
+//   - MaxTotalBytes：工作區內所有一般檔案的總大小
+//   - MaxFiles：工作區內一般檔案的數量
+//
+// 欄位為 0 表示不限制。總量與數量只計算工作區 (包含 .checkpoints、.trash 等內部目錄)，
+// 其他沙盒根目錄只檢查單一檔案大小。檢查點的內容也以 CheckQuota 計入，
+// 所以反覆以相同大小覆寫檔案無法讓 .checkpoints 無限制地成長。
+//
+// 使用量以走訪工作區計算並快取 quotaRefresh，期間內的寫入以差值更新，
+// 所以連續的小量寫入也會被擋下。寫入後超過配額的變更會被拒絕 (包含已經超過配額時
+// 不增加用量的寫入)，只有刪除與縮小檔案這類釋放空間的變更永遠允許。
+// exec 與背景行程寫入的檔案不受此限制 (見 ExecIsolation 的 FileSizeBytes)。
+// ============================================================================
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// quotaRefresh 是使用量快取的有效時間，過期後重新走訪工作區
+const quotaRefresh = 30 * time.Second
+
+// Quota 是工作區配額，欄位為 0 表示不限制
+type Quota struct {
+	MaxFileBytes  int64
+	MaxTotalBytes int64
+	MaxFiles      int64
+}
+
+// DefaultQuota 回傳預設配額：單一檔案 20 MB、工作區 1 GB、20000 個檔案
+func DefaultQuota() *Quota {
+	return &Quota{
+		MaxFileBytes:  20 << 20,
+		MaxTotalBytes: 1 << 30,
+		MaxFiles:      20000,
+	}
+}
+
+// Usage 是工作區目前的使用量
+type Usage struct {
+	Bytes int64
+	Files int64
+}
+
+// FileChange 描述一次寫入後檔案的大小
+type FileChange struct {
+	Path string // CheckWritePath 回傳的絕對路徑
+	Size int64  // 寫入後的大小；-1 表示刪除
+}
+
+// quotaState 是 Sandbox 的使用量快取
+type quotaState struct {
+	mu    sync.Mutex
+	usage Usage
+	at    time.Time
+}
+
+// Usage 重新走訪工作區並回傳目前的使用量
+func (s *Sandbox) Usage() (Usage, error) {
+	s.quota.mu.Lock()
+	defer s.quota.mu.Unlock()
+	return s.refreshUsage()
+}
+
+// refreshUsage 走訪工作區計算使用量 (呼叫端須持有 quota.mu)
+func (s *Sandbox) refreshUsage() (Usage, error) {
+	root := s.Workspace
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	var usage Usage
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // 無法讀取的項目略過，不影響其他檔案
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			usage.Bytes += info.Size()
+			usage.Files++
+		}
+		return nil
+	})
+	if err != nil {
+		return Usage{}, err
+	}
+	s.quota.usage, s.quota.at = usage, time.Now()
+	return usage, nil
+}
+
+// CheckQuota 檢查一組寫入是否符合配額；通過時先將差值計入使用量
+//
+// 參數：
+//   - changes: 每個檔案寫入後的大小
+//
+// 回傳：
+//   - error: 超過配額時的說明，會直接交給 LLM
+func (s *Sandbox) CheckQuota(changes ...FileChange) error {
+	q := s.Quota
+	if q == nil {
+		return nil
+	}
+
+	for _, c := range changes {
+		if q.MaxFileBytes > 0 && c.Size > q.MaxFileBytes {
+			return fmt.Errorf("quota exceeded: %s would be %s, over the %s per-file limit",
+				s.relPath(c.Path), formatBytes(c.Size), formatBytes(q.MaxFileBytes))
+		}
+	}
+	if q.MaxTotalBytes <= 0 && q.MaxFiles <= 0 {
+		return nil
+	}
+
+	var deltaBytes, deltaFiles int64
+	counted := false
+	for _, c := range changes {
+		if !s.inWorkspace(c.Path) {
+			continue
+		}
+		counted = true
+		var oldSize int64
+		existed := false
+		if info, err := os.Lstat(c.Path); err == nil && info.Mode().IsRegular() {
+			oldSize, existed = info.Size(), true
+		}
+		switch {
+		case c.Size < 0 && existed:
+			deltaBytes -= oldSize
+			deltaFiles--
+		case c.Size >= 0:
+			deltaBytes += c.Size - oldSize
+			if !existed {
+				deltaFiles++
+			}
+		}
+	}
+	if !counted {
+		return nil // 只寫入其他根目錄
+	}
+	s.quota.mu.Lock()
+	defer s.quota.mu.Unlock()
+	usage := s.quota.usage
+	if time.Since(s.quota.at) > quotaRefresh {
+		var err error
+		if usage, err = s.refreshUsage(); err != nil {
+			return fmt.Errorf("cannot measure workspace usage: %v", err)
+		}
+	}
+
+	// 已經超過配額時，只允許釋放空間的變更
+	frees := deltaBytes < 0 || deltaFiles < 0
+	if q.MaxTotalBytes > 0 && (deltaBytes > 0 || !frees) && usage.Bytes+deltaBytes > q.MaxTotalBytes {
+		return fmt.Errorf("quota exceeded: the workspace uses %s of %s and this write needs %s more; delete files (including .trash and .tool-output) to free space",
+			formatBytes(usage.Bytes), formatBytes(q.MaxTotalBytes), formatBytes(deltaBytes))
+	}
+	if q.MaxFiles > 0 && (deltaFiles > 0 || !frees) && usage.Files+deltaFiles > q.MaxFiles {
+		return fmt.Errorf("quota exceeded: the workspace has %d of %d files and this write adds %d; delete files to make room",
+			usage.Files, q.MaxFiles, deltaFiles)
+	}
+	s.quota.usage = Usage{Bytes: usage.Bytes + deltaBytes, Files: usage.Files + deltaFiles}
+	return nil
+}
+
+// inWorkspace 判斷 abs 是否位於工作區目錄內 (包含工作區內的其他根目錄)
+func (s *Sandbox) inWorkspace(abs string) bool {
+	if within(abs, s.Workspace) {
+		return true
+	}
+	resolved, err := filepath.EvalSymlinks(s.Workspace)
+	return err == nil && within(abs, resolved)
+}
+
+// treeChanges 列出將 src (檔案或目錄) 複製到 dst 後會建立的一般檔案
+func treeChanges(src, dst string) ([]FileChange, error) {
+	var changes []FileChange
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		changes = append(changes, FileChange{Path: filepath.Join(dst, rel), Size: info.Size()})
+		return nil
+	})
+	return changes, err
+}
+
+// formatBytes 以 KB / MB / GB 顯示位元組數
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
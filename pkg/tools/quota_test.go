@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSandbox_CheckQuota(t *testing.T) {
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "a.txt"), []byte("0123456789"), 0644)
+	sandbox, _ := NewSandbox(ws)
+	sandbox.Quota = &Quota{MaxFileBytes: 50, MaxTotalBytes: 60, MaxFiles: 2}
+
+	usage, err := sandbox.Usage()
+	if err != nil || usage.Bytes != 10 || usage.Files != 1 {
+		t.Fatalf("usage = %+v, %v", usage, err)
+	}
+
+	path := func(name string) string { return filepath.Join(ws, name) }
+	if err := sandbox.CheckQuota(FileChange{Path: path("big.txt"), Size: 51}); err == nil || !strings.Contains(err.Error(), "per-file limit") {
+		t.Errorf("expected per-file limit error, got %v", err)
+	}
+	if err := sandbox.CheckQuota(FileChange{Path: path("b.txt"), Size: 40}); err != nil {
+		t.Fatalf("10 + 40 bytes fits the quota: %v", err)
+	}
+	// the reservation above counts even before the file is written
+	if err := sandbox.CheckQuota(FileChange{Path: path("c.txt"), Size: 20}); err == nil || !strings.Contains(err.Error(), "uses") {
+		t.Errorf("expected total limit error, got %v", err)
+	}
+	// shrinking or deleting is always allowed
+	if err := sandbox.CheckQuota(FileChange{Path: path("a.txt"), Size: -1}); err != nil {
+		t.Errorf("deleting must be allowed: %v", err)
+	}
+	if err := sandbox.CheckQuota(FileChange{Path: path("c.txt"), Size: 1}, FileChange{Path: path("d.txt"), Size: 1}); err == nil || !strings.Contains(err.Error(), "files") {
+		t.Errorf("expected file count error, got %v", err)
+	}
+
+	// already over the limit (e.g. files written by exec): writes that do not
+	// free space are rejected, even when they keep the same size
+	os.WriteFile(path("a.txt"), []byte(strings.Repeat("x", 50)), 0644)
+	os.WriteFile(path("b.txt"), []byte(strings.Repeat("x", 40)), 0644)
+	if _, err := sandbox.Usage(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sandbox.CheckQuota(FileChange{Path: path("a.txt"), Size: 50}); err == nil || !strings.Contains(err.Error(), "uses") {
+		t.Errorf("same-size write over the limit should fail, got %v", err)
+	}
+	if err := sandbox.CheckQuota(FileChange{Path: path("a.txt"), Size: 10}); err != nil {
+		t.Errorf("shrinking must be allowed over the limit: %v", err)
+	}
+
+	sandbox.Quota = nil
+	if err := sandbox.CheckQuota(FileChange{Path: path("big.txt"), Size: 1 << 40}); err != nil {
+		t.Errorf("nil quota is unlimited: %v", err)
+	}
+}
+
+func TestWriteTools_Quota(t *testing.T) {
+	ws := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	sandbox.Quota = &Quota{MaxFileBytes: 8, MaxTotalBytes: 10}
+	ctx := context.Background()
+
+	write := &WriteFileTool{Sandbox: sandbox}
+	if res := write.Execute(ctx, map[string]any{"path": "a.txt", "content": "123456789"}); !res.IsError || !strings.Contains(res.ForLLM, "quota exceeded") {
+		t.Fatalf("expected quota error, got %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "a.txt")); !os.IsNotExist(err) {
+		t.Fatal("nothing should be written when the quota is exceeded")
+	}
+
+	// an append loop stops at the per-file limit
+	appendTool := &AppendFileTool{Sandbox: sandbox}
+	var res *ToolResult
+	for i := 0; i < 5; i++ {
+		if res = appendTool.Execute(ctx, map[string]any{"path": "log.txt", "content": "123"}); res.IsError {
+			break
+		}
+	}
+	if !res.IsError {
+		t.Fatal("append loop should hit the quota")
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, "log.txt")); len(data) != 6 {
+		t.Errorf("log.txt has %d bytes, want 6", len(data))
+	}
+
+	copyTool := &CopyPathTool{Sandbox: sandbox}
+	if res := copyTool.Execute(ctx, map[string]any{"source": "log.txt", "destination": "log2.txt"}); !res.IsError || !strings.Contains(res.ForLLM, "quota exceeded") {
+		t.Errorf("copy over the total quota should fail, got %+v", res)
+	}
+}
+
+func TestCheckpoints_CountTowardsQuota(t *testing.T) {
+	ws := t.TempDir()
+	sandbox, _ := NewSandbox(ws)
+	sandbox.Quota = &Quota{MaxTotalBytes: 4 << 10}
+	store := NewCheckpointStore(ws, filepath.Join(ws, ".checkpoints"))
+	store.Sandbox = sandbox
+	write := &WriteFileTool{Sandbox: sandbox, Checkpoints: store}
+	ctx := context.Background()
+
+	// rewriting a file with the same size adds a checkpoint blob each time
+	content := strings.Repeat("x", 1<<10)
+	var res *ToolResult
+	for i := 0; i < 10; i++ {
+		if res = write.Execute(ctx, map[string]any{"path": "a.txt", "content": content}); res.IsError {
+			break
+		}
+	}
+	if !res.IsError || !strings.Contains(res.ForLLM, "quota exceeded") {
+		t.Fatalf("checkpoints should hit the quota, got %+v", res)
+	}
+	usage, _ := sandbox.Usage()
+	if usage.Bytes > sandbox.Quota.MaxTotalBytes {
+		t.Errorf("workspace uses %d bytes, over the %d quota", usage.Bytes, sandbox.Quota.MaxTotalBytes)
+	}
+}
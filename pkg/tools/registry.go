@@ -21,6 +21,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/chiisen/mini_bot/pkg/i18n"
 	"github.com/chiisen/mini_bot/pkg/providers"
@@ -34,6 +35,14 @@ type ToolRegistry struct {
 	// tools 是一個 map，以工具名稱為鍵儲存工具實例
 	// 使用 map 可以實現 O(1) 時間複雜度的工具查詢
 	tools map[string]Tool
+
+	// Output 限制單一工具結果的大小，過長的結果會截斷並保存完整內容
+	// nil 表示不限制
+	Output *OutputPolicy
+
+	// Permissions 依呼叫者 (見 WithCaller) 限制可用的工具與參數
+	// nil 表示不限制
+	Permissions *PermissionPolicy
 }
 
 // NewRegistry 建立新的工具註冊表
@@ -64,6 +73,12 @@ func (r *ToolRegistry) Register(tool Tool) {
 	r.tools[tool.Name()] = tool
 }
 
+// Has 檢查指定名稱的工具是否已註冊
+func (r *ToolRegistry) Has(name string) bool {
+	_, ok := r.tools[name]
+	return ok
+}
+
 // ============================================================================
 // Execute: 執行工具
 // ============================================================================
@@ -71,8 +86,11 @@ func (r *ToolRegistry) Register(tool Tool) {
 // 這是工具系統的核心方法，負責：
 //  1. 工具查詢
 //  2. 錯誤處理 (Tool Not Found)
-//  3. Panic 捕獲 (防止一個工具的錯誤影響整個系統)
-//  4. 結果封裝
+//  3. 參數驗證 (依照工具的 Parameters() Schema，並在安全時自動轉型)
+//  4. 權限檢查 (依呼叫者的 PermissionPolicy)
+//  5. Panic 捕獲 (防止一個工具的錯誤影響整個系統)
+//  6. 輸出上限 (過長的結果截斷並保存到檔案，見 OutputPolicy)
+//  7. 結果封裝
 //
 // 參數：
 //   - ctx:  上下文物件，用於控制執行時間和取消
@@ -84,6 +102,8 @@ func (r *ToolRegistry) Register(tool Tool) {
 //
 // 錯誤處理：
 //   - 如果工具不存在，返回錯誤訊息
+//   - 如果呼叫者不能使用此工具或這組參數，返回拒絕原因，不會執行工具
+//   - 如果參數不符合 Schema，返回條列式的驗證錯誤，不會執行工具
 //   - 如果工具執行過程中發生 Panic，捕獲並返回錯誤訊息
 //   - 工具內部的錯誤會封裝在 ToolResult.ForLLM 中
 //
@@ -100,6 +120,32 @@ func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string
 		}
 	}
 
+	// -------------------------------------------------------------------------
+	// 參數驗證
+	// -------------------------------------------------------------------------
+	// 依照工具宣告的 JSON Schema 驗證參數，避免工具收到缺漏或錯誤型別的值
+	// 後靜默使用預設值 (例如 edit_file 缺少 start_line 時變成 0)
+	validArgs, argErrs := validateArgs(t.Parameters(), args)
+	if len(argErrs) > 0 {
+		return &ToolResult{
+			ForLLM:  formatArgErrors(name, argErrs),
+			IsError: true,
+		}
+	}
+	args = validArgs
+
+	// 權限檢查放在型別轉換之後，參數樣式比對的是工具實際收到的值
+	caller := CallerFrom(ctx)
+	if err := r.Permissions.Check(caller, name, args); err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+	// 命令規則也套用到工具實際執行的每個管線階段 (包含自訂命令工具)
+	if r.Permissions != nil {
+		ctx = withCommandCheck(ctx, func(argv []string) error {
+			return r.Permissions.CheckCommand(caller, name, argv)
+		})
+	}
+
 	// -------------------------------------------------------------------------
 	// Panic 捕獲機制
 	// -------------------------------------------------------------------------
@@ -121,8 +167,8 @@ func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string
 		res = t.Execute(ctx, args)
 	}()
 
-	// 返回執行結果
-	return res
+	// 返回執行結果 (過長時截斷)
+	return r.Output.Apply(name, res)
 }
 
 // ============================================================================
@@ -138,16 +184,43 @@ func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string
 //   - 在 Agent 啟動時生成系統提示詞
 //   - 讓 LLM 知道有哪些工具可用
 //
+// 排序：
+//   - 結果依工具名稱排序，確保每次呼叫的輸出完全一致
+//   - Go 的 map 迭代順序是隨機的，若不排序，系統提示詞與工具列表
+//     每次都會不同，導致提供者端的 Prompt Cache 失效，測試也會不穩定
+//
 // 回傳：
-//   - []providers.ToolDefinition: 工具定義列表
+//   - []providers.ToolDefinition: 工具定義列表 (不套用權限政策)
 //
 // ============================================================================
 func (r *ToolRegistry) Definitions() []providers.ToolDefinition {
+	return r.definitions(func(string) bool { return true })
+}
+
+// DefinitionsFor 只回傳呼叫者可以使用的工具定義，每次執行開始時呼叫，
+// 讓不允許的工具完全不會出現在提供給 LLM 的工具列表中
+func (r *ToolRegistry) DefinitionsFor(caller Caller) []providers.ToolDefinition {
+	return r.definitions(func(name string) bool {
+		return r.Permissions.AllowsTool(caller, name)
+	})
+}
+
+func (r *ToolRegistry) definitions(keep func(name string) bool) []providers.ToolDefinition {
+	// 先收集並排序工具名稱，確保輸出順序穩定
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
+		if keep(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
 	// 預分配切片容量，提高效能
-	var defs []providers.ToolDefinition
+	defs := make([]providers.ToolDefinition, 0, len(names))
 
-	// 遍歷所有工具，收集它們的定義
-	for _, t := range r.tools {
+	// 依排序後的名稱收集工具定義
+	for _, name := range names {
+		t := r.tools[name]
 		// 將 Tool 轉換為 providers.ToolDefinition 格式
 		defs = append(defs, providers.ToolDefinition{
 			Type: "function", // OpenAI 格式的工具類型
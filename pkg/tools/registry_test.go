@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRegistry_Definitions_SortedByName(t *testing.T) {
+	r := NewRegistry()
+	for _, name := range []string{"web_search", "exec", "read_file", "append_file", "list_dir"} {
+		r.Register(&mockTool{name: name})
+	}
+
+	want := []string{"append_file", "exec", "list_dir", "read_file", "web_search"}
+	for i := 0; i < 20; i++ {
+		defs := r.Definitions()
+		if len(defs) != len(want) {
+			t.Fatalf("expected %d definitions, got %d", len(want), len(defs))
+		}
+		for j, def := range defs {
+			if def.Function.Name != want[j] {
+				t.Fatalf("iteration %d: expected %s at position %d, got %s", i, want[j], j, def.Function.Name)
+			}
+		}
+	}
+}
+
+func TestRegistry_Execute_NotFound(t *testing.T) {
+	r := NewRegistry()
+
+	result := r.Execute(context.Background(), "missing", nil)
+	if !result.IsError {
+		t.Error("expected error for unknown tool")
+	}
+}
+
+func TestRegistry_Execute_RecoversPanic(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&mockTool{
+		name: "boom",
+		executeFunc: func(ctx context.Context, args map[string]any) *ToolResult {
+			panic("kaboom")
+		},
+	})
+
+	result := r.Execute(context.Background(), "boom", map[string]any{})
+	if !result.IsError {
+		t.Error("expected error result after panic")
+	}
+}
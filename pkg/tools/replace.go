@@ -0,0 +1,140 @@
+package tools
+
+// ============================================================================
+// ReplaceInFileTool: 搜尋取代編輯工具
+// ============================================================================
+// 功能：以精確的 old_string / new_string 取代檔案內容
+// 工具名稱：replace_in_file
+//
+// 與 edit_file 的差異：
+//   - edit_file 依行號取代，同一輪中第一次編輯後行號就可能失效
+//   - replace_in_file 依內容定位，找不到或找到多處 (且未設定 replace_all) 時直接失敗，
+//     不會改錯位置
+//
+// 成功時回傳簡短的 unified diff，讓 Agent 確認實際變更。
+// ============================================================================
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chiisen/mini_bot/pkg/i18n"
+)
+
+const replaceDiffMaxLines = 60
+
+type ReplaceInFileTool struct {
+	Sandbox     *Sandbox
+	Checkpoints *CheckpointStore // 修改前保存檢查點 (可為 nil)
+}
+
+func (t *ReplaceInFileTool) Name() string { return "replace_in_file" }
+func (t *ReplaceInFileTool) Description() string {
+	return i18n.GetInstance().T("tools.replace_in_file")
+}
+func (t *ReplaceInFileTool) Parameters() map[string]any {
+	tr := i18n.GetInstance()
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":        map[string]any{"type": "string", "description": tr.T("tool_params.path")},
+			"old_string":  map[string]any{"type": "string", "description": tr.T("tool_params.old_string")},
+			"new_string":  map[string]any{"type": "string", "description": tr.T("tool_params.new_string")},
+			"replace_all": map[string]any{"type": "boolean", "description": tr.T("tool_params.replace_all")},
+		},
+		"required": []string{"path", "old_string", "new_string"},
+	}
+}
+
+// Execute 執行搜尋取代
+//
+// 執行步驟：
+//  1. 驗證路徑並讀取檔案
+//  2. 計算 old_string 出現次數 (檔案使用 CRLF 時自動轉換換行)
+//  3. 0 次或多次 (未設定 replace_all) 時回傳錯誤與提示
+//  4. 取代、寫回並回傳 diff
+func (t *ReplaceInFileTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	path, _ := args["path"].(string)
+	oldString, _ := args["old_string"].(string)
+	newString, _ := args["new_string"].(string)
+	replaceAll := boolArg(args, "replace_all")
+
+	if oldString == "" {
+		return &ToolResult{ForLLM: "Error: old_string must not be empty. Use write_file to create a file.", IsError: true}
+	}
+	if oldString == newString {
+		return &ToolResult{ForLLM: "Error: old_string and new_string are identical; nothing to change.", IsError: true}
+	}
+
+	// 驗證路徑安全性
+	if err := validatePathName(path); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.path_invalid"), err), IsError: true}
+	}
+	safePath, err := t.Sandbox.CheckWritePath(path)
+	if err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+
+	data, err := os.ReadFile(safePath)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.read_failed"), err), IsError: true}
+	}
+	content := string(data)
+
+	// 模型送出的字串使用 \n，但檔案可能是 CRLF
+	if !strings.Contains(content, oldString) && strings.Contains(content, "\r\n") && !strings.Contains(oldString, "\r\n") {
+		oldString = strings.ReplaceAll(oldString, "\n", "\r\n")
+		newString = strings.ReplaceAll(newString, "\n", "\r\n")
+	}
+
+	count := strings.Count(content, oldString)
+	switch {
+	case count == 0:
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: old_string was not found in %s. It must match exactly, including whitespace and indentation; use read_file to check the current content.", path), IsError: true}
+	case count > 1 && !replaceAll:
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: old_string matches %d places in %s (lines %s). Include more surrounding lines to make it unique, or set replace_all to true.",
+			count, path, strings.Join(matchLineNumbers(content, oldString, 10), ", ")), IsError: true}
+	}
+
+	var updated string
+	if replaceAll {
+		updated = strings.ReplaceAll(content, oldString, newString)
+	} else {
+		updated = strings.Replace(content, oldString, newString, 1)
+	}
+
+	if err := t.Sandbox.CheckQuota(FileChange{Path: safePath, Size: int64(len(updated))}); err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+	if err := t.Checkpoints.Snapshot(SessionKeyFrom(ctx), t.Name(), safePath); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.checkpoint_failed"), err), IsError: true}
+	}
+	if err := os.WriteFile(safePath, []byte(updated), 0600); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.write_failed"), err), IsError: true}
+	}
+
+	noun := "occurrence"
+	if count > 1 {
+		noun = "occurrences"
+	}
+	diff := unifiedDiff(t.Sandbox.relPath(safePath), content, updated, replaceDiffMaxLines)
+	return &ToolResult{ForLLM: fmt.Sprintf("Replaced %d %s in %s.\n```diff\n%s```", count, noun, path, diff)}
+}
+
+// matchLineNumbers 回傳 sub 在 s 中各次出現的起始行號 (最多 limit 筆)
+func matchLineNumbers(s, sub string, limit int) []string {
+	var lines []string
+	offset := 0
+	for len(lines) < limit {
+		i := strings.Index(s[offset:], sub)
+		if i < 0 {
+			break
+		}
+		pos := offset + i
+		lines = append(lines, fmt.Sprint(strings.Count(s[:pos], "\n")+1))
+		offset = pos + len(sub)
+	}
+	return lines
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplaceInFileTool(t *testing.T) {
+	tmpDir := t.TempDir()
+	sandbox, _ := NewSandbox(tmpDir)
+	file := filepath.Join(tmpDir, "main.go")
+	os.WriteFile(file, []byte("package main\n\nfunc main() {\n\tprintln(\"hi\")\n\tprintln(\"hi\")\n}\n"), 0644)
+
+	tool := &ReplaceInFileTool{Sandbox: sandbox}
+	ctx := context.Background()
+
+	// 多處符合時必須失敗，且不修改檔案
+	res := tool.Execute(ctx, map[string]any{"path": "main.go", "old_string": `println("hi")`, "new_string": `println("bye")`})
+	if !res.IsError || !strings.Contains(res.ForLLM, "matches 2 places") || !strings.Contains(res.ForLLM, "lines 4, 5") {
+		t.Errorf("expected ambiguity error, got %+v", res)
+	}
+
+	// 加上上下文即可唯一定位
+	res = tool.Execute(ctx, map[string]any{"path": "main.go", "old_string": "{\n\tprintln(\"hi\")", "new_string": "{\n\tprintln(\"hello\")"})
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+	if !strings.Contains(res.ForLLM, "-\tprintln(\"hi\")\n+\tprintln(\"hello\")") || !strings.Contains(res.ForLLM, "--- a/main.go") {
+		t.Errorf("expected diff in result:\n%s", res.ForLLM)
+	}
+	data, _ := os.ReadFile(file)
+	if string(data) != "package main\n\nfunc main() {\n\tprintln(\"hello\")\n\tprintln(\"hi\")\n}\n" {
+		t.Errorf("unexpected content:\n%s", data)
+	}
+
+	// replace_all
+	res = tool.Execute(ctx, map[string]any{"path": "main.go", "old_string": "println", "new_string": "fmt.Println", "replace_all": true})
+	if res.IsError || !strings.Contains(res.ForLLM, "Replaced 2 occurrences") {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	// 找不到
+	res = tool.Execute(ctx, map[string]any{"path": "main.go", "old_string": "nothing here", "new_string": "x"})
+	if !res.IsError || !strings.Contains(res.ForLLM, "not found") {
+		t.Errorf("expected not found error, got %+v", res)
+	}
+
+	// 參數錯誤與沙盒
+	for _, args := range []map[string]any{
+		{"path": "main.go", "old_string": "", "new_string": "x"},
+		{"path": "main.go", "old_string": "same", "new_string": "same"},
+		{"path": "../outside.go", "old_string": "a", "new_string": "b"},
+		{"path": "missing.go", "old_string": "a", "new_string": "b"},
+	} {
+		if res := tool.Execute(ctx, args); !res.IsError {
+			t.Errorf("expected error for %v, got %s", args, res.ForLLM)
+		}
+	}
+}
+
+func TestReplaceInFileTool_CRLF(t *testing.T) {
+	tmpDir := t.TempDir()
+	sandbox, _ := NewSandbox(tmpDir)
+	file := filepath.Join(tmpDir, "notes.txt")
+	os.WriteFile(file, []byte("one\r\ntwo\r\nthree\r\n"), 0644)
+
+	res := (&ReplaceInFileTool{Sandbox: sandbox}).Execute(context.Background(), map[string]any{
+		"path": "notes.txt", "old_string": "one\ntwo", "new_string": "1\n2",
+	})
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+	data, _ := os.ReadFile(file)
+	if string(data) != "1\r\n2\r\nthree\r\n" {
+		t.Errorf("line endings not preserved: %q", data)
+	}
+}
@@ -4,15 +4,44 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 )
 
+// WorkspaceRoot is the root name that always refers to the workspace, e.g. "workspace:notes.md".
+const WorkspaceRoot = "workspace"
+
 // Sandbox provides path validation to restrict operations inside a specific directory.
+// Besides the workspace, extra named roots may be added with AddRoot; tools address
+// them as "name:path" or by absolute path.
 type Sandbox struct {
 	Workspace string
+	Roots     []SandboxRoot
+
+	// Unrestricted allows absolute paths outside every root (restrictToWorkspace = false).
+	// Read-only roots stay read-only.
+	Unrestricted bool
+
+	// Quota limits what file tools may write; nil means unlimited. See CheckQuota.
+	Quota *Quota
+	quota quotaState
+
+	// protected are internal directories (e.g. the checkpoint store) that tools
+	// may read but never modify. See Protect.
+	protected []string
 }
 
+// SandboxRoot is an extra directory tools may access.
+type SandboxRoot struct {
+	Name     string
+	Path     string // absolute path with symlinks resolved
+	ReadOnly bool
+}
+
+// rootNamePattern rejects single letters, which would be ambiguous with Windows drive letters.
+var rootNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]+$`)
+
 func NewSandbox(workspacePath string) (*Sandbox, error) {
 	absPath, err := filepath.Abs(workspacePath)
 	if err != nil {
@@ -29,26 +58,139 @@ func NewSandbox(workspacePath string) (*Sandbox, error) {
 	}, nil
 }
 
-func (s *Sandbox) isSubPath(path string) bool {
-	checkPath := path
-	for {
-		if checkPath == s.Workspace {
-			return true
+// AddRoot adds a named root. The directory must already exist.
+func (s *Sandbox) AddRoot(name, path string, readOnly bool) error {
+	if !rootNamePattern.MatchString(name) || name == WorkspaceRoot {
+		return fmt.Errorf("invalid root name %q: use at least two letters, digits, _ or -, and not %q", name, WorkspaceRoot)
+	}
+	if s.root(name) != nil {
+		return fmt.Errorf("duplicate root name %q", name)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return fmt.Errorf("root %s: %w", name, err)
+	}
+	if info, err := os.Stat(resolved); err != nil || !info.IsDir() {
+		return fmt.Errorf("root %s: %s is not a directory", name, path)
+	}
+	s.Roots = append(s.Roots, SandboxRoot{Name: name, Path: filepath.Clean(resolved), ReadOnly: readOnly})
+	return nil
+}
+
+// Protect makes dir read-only for tools, like a read-only root. It is used for
+// internal state the agent must not forge, such as checkpoint manifests.
+// dir is created if missing, so exec isolation can mount it read-only.
+func (s *Sandbox) Protect(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(abs, 0700); err != nil {
+		return err
+	}
+	if resolved, err := resolveMissing(filepath.Clean(abs)); err == nil {
+		abs = resolved
+	}
+	s.protected = append(s.protected, abs)
+	return nil
+}
+
+// protectedDir returns the protected directory containing abs, or "".
+func (s *Sandbox) protectedDir(abs string) string {
+	for _, dir := range s.protected {
+		if within(abs, dir) {
+			return dir
 		}
-		if checkPath == filepath.Dir(checkPath) {
-			return false
+	}
+	return ""
+}
+
+// readOnlyDirs returns the read-only roots and protected directories.
+func (s *Sandbox) readOnlyDirs() []string {
+	var dirs []string
+	for _, r := range s.Roots {
+		if r.ReadOnly {
+			dirs = append(dirs, r.Path)
 		}
-		checkPath = filepath.Dir(checkPath)
 	}
+	return append(dirs, s.protected...)
 }
 
-// CheckPath resolves input path to absolute and ensures it's inside Workspace.
-func (s *Sandbox) CheckPath(inputPath string) (string, error) {
-	var targetPath string
+func (s *Sandbox) root(name string) *SandboxRoot {
+	for i := range s.Roots {
+		if s.Roots[i].Name == name {
+			return &s.Roots[i]
+		}
+	}
+	return nil
+}
+
+// splitRoot splits "name:path" when name is a known root.
+func (s *Sandbox) splitRoot(inputPath string) (base, rest string, ok bool) {
+	name, rest, found := strings.Cut(inputPath, ":")
+	if !found {
+		return "", "", false
+	}
+	if name == WorkspaceRoot {
+		return s.Workspace, rest, true
+	}
+	if r := s.root(name); r != nil {
+		return r.Path, rest, true
+	}
+	return "", "", false
+}
+
+// targetPath turns input into a clean absolute path without resolving symlinks.
+func (s *Sandbox) targetPath(inputPath string) (string, error) {
+	if base, rest, ok := s.splitRoot(inputPath); ok {
+		if filepath.IsAbs(rest) {
+			return "", fmt.Errorf("invalid path: %s must be relative to its root", inputPath)
+		}
+		return filepath.Join(base, rest), nil
+	}
 	if filepath.IsAbs(inputPath) {
-		targetPath = inputPath
-	} else {
-		targetPath = filepath.Join(s.Workspace, inputPath)
+		return filepath.Clean(inputPath), nil
+	}
+	return filepath.Join(s.Workspace, inputPath), nil
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	if runtime.GOOS == "windows" {
+		path, dir = strings.ToLower(path), strings.ToLower(dir)
+	}
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// containingRoot returns the deepest root that contains abs, so a read-only root
+// nested in the workspace (e.g. workspace/tools) wins over the workspace itself.
+// The workspace is returned as a root named WorkspaceRoot.
+func (s *Sandbox) containingRoot(abs string) *SandboxRoot {
+	var best *SandboxRoot
+	if within(abs, s.Workspace) {
+		best = &SandboxRoot{Name: WorkspaceRoot, Path: s.Workspace}
+	} else if resolved, err := filepath.EvalSymlinks(s.Workspace); err == nil && within(abs, resolved) {
+		best = &SandboxRoot{Name: WorkspaceRoot, Path: resolved}
+	}
+	for i := range s.Roots {
+		r := &s.Roots[i]
+		if within(abs, r.Path) && (best == nil || len(r.Path) > len(best.Path)) {
+			best = r
+		}
+	}
+	return best
+}
+
+// CheckPath resolves input path to absolute and ensures it's inside Workspace or
+// another root. It checks read access; use CheckWritePath before modifying the path.
+func (s *Sandbox) CheckPath(inputPath string) (string, error) {
+	targetPath, err := s.targetPath(inputPath)
+	if err != nil {
+		return "", err
 	}
 
 	absTargetPath, err := filepath.EvalSymlinks(targetPath)
@@ -56,24 +198,181 @@ func (s *Sandbox) CheckPath(inputPath string) (string, error) {
 		if !os.IsNotExist(err) {
 			return "", fmt.Errorf("invalid path: %v", err)
 		}
-		absTargetPath = filepath.Clean(targetPath)
+		// The path does not exist yet (e.g. a file about to be written or a
+		// directory about to be created): resolve its deepest existing ancestor,
+		// so a symlinked parent cannot redirect the new entry outside.
+		absTargetPath, err = resolveMissing(filepath.Clean(targetPath))
+		if err != nil {
+			return "", err
+		}
 	} else {
 		absTargetPath = filepath.Clean(absTargetPath)
 	}
 
-	workspace := s.Workspace
-	if runtime.GOOS == "windows" {
-		workspace = strings.ToLower(workspace)
-		absTargetPath = strings.ToLower(absTargetPath)
+	if s.containingRoot(absTargetPath) == nil && !s.Unrestricted {
+		return "", fmt.Errorf("path escapes workspace bounds: %s", inputPath)
 	}
 
-	if absTargetPath != workspace && !strings.HasPrefix(absTargetPath, workspace+string(filepath.Separator)) {
-		return "", fmt.Errorf("path escapes workspace bounds: %s", inputPath)
+	return absTargetPath, nil
+}
+
+// CheckWritePath is CheckPath for operations that create, modify or remove the path.
+func (s *Sandbox) CheckWritePath(inputPath string) (string, error) {
+	abs, err := s.CheckPath(inputPath)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkWritable(abs, inputPath); err != nil {
+		return "", err
+	}
+	return abs, nil
+}
+
+// checkWritable rejects abs (as returned by CheckPath) when it lies in a read-only root.
+func (s *Sandbox) checkWritable(abs, inputPath string) error {
+	if r := s.containingRoot(abs); r != nil && r.ReadOnly {
+		return fmt.Errorf("path is in read-only root %s: %s", r.Name, inputPath)
+	}
+	if dir := s.protectedDir(abs); dir != "" {
+		return fmt.Errorf("path is in the internal directory %s, which tools cannot modify: %s", filepath.Base(dir), inputPath)
+	}
+	return nil
+}
+
+// checkRemovable is checkWritable for moving or deleting abs: a read-only root
+// nested inside abs (e.g. workspace/tools) would go with it, so it is rejected too.
+func (s *Sandbox) checkRemovable(abs, inputPath string) error {
+	if err := s.checkWritable(abs, inputPath); err != nil {
+		return err
+	}
+	for _, r := range s.Roots {
+		if r.ReadOnly && within(r.Path, abs) {
+			return fmt.Errorf("path contains read-only root %s: %s", r.Name, inputPath)
+		}
+	}
+	for _, dir := range s.protected {
+		if within(dir, abs) {
+			return fmt.Errorf("path contains the internal directory %s: %s", filepath.Base(dir), inputPath)
+		}
+	}
+	return nil
+}
+
+// resolveMissing resolves symlinks in the existing part of a path that does not
+// exist and appends the missing components. A broken symlink anywhere on the path
+// is rejected, because creating the path would create the link's target instead.
+func resolveMissing(path string) (string, error) {
+	var missing []string
+	dir := path
+	for {
+		if _, err := os.Lstat(dir); err == nil {
+			resolved, err := filepath.EvalSymlinks(dir)
+			if err != nil {
+				return "", fmt.Errorf("invalid path: %s goes through a broken symbolic link", path)
+			}
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
+			}
+			return filepath.Clean(resolved), nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return path, nil
+		}
+		missing = append(missing, filepath.Base(dir))
+		dir = parent
 	}
+}
 
-	if !s.isSubPath(absTargetPath) {
+// entryPath is like CheckPath but does not follow a symlink in the last path
+// component, so move and delete operate on the link itself rather than its target.
+// A root resolves to itself; callers must refuse to remove it.
+func (s *Sandbox) entryPath(inputPath string) (string, error) {
+	target, err := s.targetPath(inputPath)
+	if err != nil {
+		return "", err
+	}
+	if s.isRootPath(target) {
+		return s.CheckPath(target)
+	}
+	parent, err := s.CheckPath(filepath.Dir(target))
+	if err != nil {
 		return "", fmt.Errorf("path escapes workspace bounds: %s", inputPath)
 	}
+	return filepath.Join(parent, filepath.Base(target)), nil
+}
 
-	return absTargetPath, nil
+// isRootPath reports whether the unresolved path names the workspace or a root.
+func (s *Sandbox) isRootPath(path string) bool {
+	if path == s.Workspace {
+		return true
+	}
+	for _, r := range s.Roots {
+		if path == r.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// isRoot reports whether abs (as returned by CheckPath) is the workspace or another root.
+func (s *Sandbox) isRoot(abs string) bool {
+	root, err := s.CheckPath(s.Workspace)
+	if err == nil && abs == root {
+		return true
+	}
+	for _, r := range s.Roots {
+		if abs == r.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// relPath returns an absolute path from CheckPath relative to the workspace,
+// using forward slashes so it can be passed straight back to other tools.
+// Paths in other roots are returned as "name:path"; paths outside every root stay absolute.
+func (s *Sandbox) relPath(abs string) string {
+	r := s.containingRoot(abs)
+	if r == nil {
+		return filepath.ToSlash(abs)
+	}
+	base := r.Path
+	if r.Name == WorkspaceRoot {
+		if resolved, err := filepath.EvalSymlinks(base); err == nil {
+			base = resolved
+		}
+	}
+	rel, err := filepath.Rel(base, abs)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	if r.Name != WorkspaceRoot {
+		return r.Name + ":" + filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// InWritableRoot reports whether file tools can modify path: it lies in the
+// workspace or a read-write root and not in a read-only root nested inside it.
+// Paths outside every root are not reported, even when Unrestricted.
+func (s *Sandbox) InWritableRoot(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if resolved, err := resolveMissing(filepath.Clean(abs)); err == nil {
+		abs = resolved
+	}
+	r := s.containingRoot(abs)
+	return r != nil && !r.ReadOnly
+}
+
+// rootDir returns the directory of the root containing abs, or abs itself when
+// it is outside every root. Used to find where .gitignore lookup starts.
+func (s *Sandbox) rootDir(abs string) string {
+	if r := s.containingRoot(abs); r != nil {
+		return r.Path
+	}
+	return abs
 }
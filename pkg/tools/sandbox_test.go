@@ -91,3 +91,133 @@ func TestSandbox_CheckPath_ParentTraversal(t *testing.T) {
 		t.Error("expected error for path traversal")
 	}
 }
+
+func TestSandbox_CheckPath_MissingPathThroughSymlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	outside := t.TempDir()
+	sandbox, _ := NewSandbox(tmpDir)
+	if err := os.Symlink(outside, filepath.Join(tmpDir, "link")); err != nil {
+		t.Skip("symlinks not supported")
+	}
+	os.Symlink(filepath.Join(outside, "missing"), filepath.Join(tmpDir, "dangling"))
+
+	// 尚未存在的路徑也要以實際位置判斷
+	for _, p := range []string{"link/new.txt", "link/a/b/c", "dangling", "dangling/child"} {
+		if _, err := sandbox.CheckPath(p); err == nil {
+			t.Errorf("expected %q to be rejected", p)
+		}
+	}
+	if got, err := sandbox.CheckPath("new/dir/file.txt"); err != nil || got != filepath.Join(tmpDir, "new", "dir", "file.txt") {
+		t.Errorf("unexpected result %q, %v", got, err)
+	}
+}
+
+func TestSandbox_Roots(t *testing.T) {
+	workspace := t.TempDir()
+	notes := t.TempDir()
+	docs := t.TempDir()
+	os.WriteFile(filepath.Join(docs, "guide.md"), []byte("# guide"), 0644)
+
+	sandbox, _ := NewSandbox(workspace)
+	if err := sandbox.AddRoot("notes", notes, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := sandbox.AddRoot("docs", docs, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"c", "workspace", "notes", "bad name"} {
+		if err := sandbox.AddRoot(name, t.TempDir(), false); err == nil {
+			t.Errorf("AddRoot(%q) should fail", name)
+		}
+	}
+
+	// root:path and absolute paths both resolve into the root
+	abs, err := sandbox.CheckPath("docs:guide.md")
+	if err != nil || abs != filepath.Join(docs, "guide.md") {
+		t.Fatalf("docs:guide.md -> %q, %v", abs, err)
+	}
+	if _, err := sandbox.CheckPath(filepath.Join(notes, "todo.md")); err != nil {
+		t.Errorf("absolute path in a root should be allowed: %v", err)
+	}
+	if _, err := sandbox.CheckPath("docs:../escape"); err == nil {
+		t.Error("root:path must not escape the root")
+	}
+	if _, err := sandbox.CheckPath("workspace:a.txt"); err != nil {
+		t.Errorf("workspace: prefix should work: %v", err)
+	}
+
+	// the mode is enforced per operation
+	if _, err := sandbox.CheckWritePath("docs:guide.md"); err == nil {
+		t.Error("writing to a read-only root should fail")
+	}
+	if _, err := sandbox.CheckWritePath("notes:todo.md"); err != nil {
+		t.Errorf("writing to a read-write root should work: %v", err)
+	}
+
+	if rel := sandbox.relPath(filepath.Join(docs, "guide.md")); rel != "docs:guide.md" {
+		t.Errorf("relPath = %q, want docs:guide.md", rel)
+	}
+	if !sandbox.isRoot(notes) {
+		t.Error("extra roots must be protected like the workspace root")
+	}
+}
+
+func TestSandbox_NestedReadOnlyRoot(t *testing.T) {
+	workspace := t.TempDir()
+	toolsDir := filepath.Join(workspace, "tools")
+	os.MkdirAll(toolsDir, 0755)
+
+	sandbox, _ := NewSandbox(workspace)
+	if err := sandbox.AddRoot("tools", toolsDir, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sandbox.CheckWritePath("tools/deploy.json"); err == nil {
+		t.Error("the deepest root decides the mode; workspace/tools should be read-only")
+	}
+	if _, err := sandbox.CheckWritePath("other.txt"); err != nil {
+		t.Errorf("the rest of the workspace stays writable: %v", err)
+	}
+}
+
+func TestSandbox_Unrestricted(t *testing.T) {
+	sandbox, _ := NewSandbox(t.TempDir())
+	outside := filepath.Join(t.TempDir(), "x.txt")
+
+	if _, err := sandbox.CheckPath(outside); err == nil {
+		t.Fatal("outside paths are rejected by default")
+	}
+	sandbox.Unrestricted = true
+	if _, err := sandbox.CheckWritePath(outside); err != nil {
+		t.Errorf("restrictToWorkspace=false should allow outside paths: %v", err)
+	}
+	if rel := sandbox.relPath(outside); rel != filepath.ToSlash(outside) {
+		t.Errorf("outside paths stay absolute, got %q", rel)
+	}
+}
+
+func TestSandbox_InWritableRoot(t *testing.T) {
+	workspace := t.TempDir()
+	sandbox, _ := NewSandbox(workspace)
+	toolsDir := filepath.Join(workspace, "tools")
+	outside := t.TempDir()
+
+	if !sandbox.InWritableRoot(toolsDir) {
+		t.Error("a missing directory in the workspace is writable")
+	}
+	if sandbox.InWritableRoot(outside) {
+		t.Error("directories outside every root are not reported")
+	}
+	os.Mkdir(toolsDir, 0755)
+	if err := sandbox.AddRoot("tools", toolsDir, true); err != nil {
+		t.Fatal(err)
+	}
+	if sandbox.InWritableRoot(toolsDir) || sandbox.InWritableRoot(filepath.Join(toolsDir, "x.json")) {
+		t.Error("a read-only root nested in the workspace is not writable")
+	}
+	if err := sandbox.AddRoot("shared", outside, false); err != nil {
+		t.Fatal(err)
+	}
+	if !sandbox.InWritableRoot(outside) {
+		t.Error("a read-write root is writable")
+	}
+}
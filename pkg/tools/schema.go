@@ -0,0 +1,429 @@
+package tools
+
+// ============================================================================
+// 工具參數驗證 (Tool Argument Validation)
+// ============================================================================
+// 本檔案根據每個工具 Parameters() 回傳的 JSON Schema 驗證 LLM 傳入的參數。
+//
+// 設計原理：
+//   - 驗證在 ToolRegistry.Execute 中統一進行，工具本身不需要重複檢查
+//   - 支援 JSON Schema 的常用子集：type、required、enum、properties、items、
+//     minimum/maximum、minLength/maxLength、minItems/maxItems、additionalProperties
+//   - 在安全的情況下自動轉型 (例如 "5" -> 5、"true" -> true)，
+//     因為小型模型經常把數字或布林值包在引號裡
+//   - 所有錯誤一次回報，並附上參數路徑，讓 LLM 能一次修正
+//
+// 數值表示：
+//   - 轉型後的數字一律以 float64 表示，與 encoding/json 解碼的結果一致，
+//     工具端仍可使用 args["x"].(float64) 取值
+// ============================================================================
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chiisen/mini_bot/pkg/i18n"
+)
+
+// ArgError 描述一個未通過 Schema 驗證的參數
+type ArgError struct {
+	Path    string // 參數路徑，例如 "start_line" 或 "items[2].name"
+	Message string // 錯誤原因
+}
+
+func (e ArgError) String() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// validateArgs 依照 schema 驗證並轉型參數
+//
+// 回傳：
+//   - map[string]any: 轉型後的參數 (原始 map 不會被修改)
+//   - []ArgError:     所有驗證錯誤，依路徑排序；沒有錯誤時為 nil
+func validateArgs(schema map[string]any, args map[string]any) (map[string]any, []ArgError) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	if schema == nil {
+		return args, nil
+	}
+
+	var errs []ArgError
+	out, _ := validateObject("", schema, args, &errs)
+
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return out, errs
+}
+
+// validateValue 驗證單一值，回傳 (轉型後的值, 是否通過)
+func validateValue(path string, schema map[string]any, value any, errs *[]ArgError) (any, bool) {
+	value = normalizeNumber(value)
+
+	types := schemaTypes(schema)
+	if len(types) > 0 {
+		coerced, ok := coerceToAny(value, types)
+		if !ok {
+			*errs = append(*errs, ArgError{Path: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), describeValue(value))})
+			return value, false
+		}
+		value = coerced
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		allowed := toAnySlice(enum)
+		if !enumContains(allowed, value) {
+			*errs = append(*errs, ArgError{Path: path, Message: fmt.Sprintf("must be one of %s, got %s", formatEnum(allowed), describeValue(value))})
+			return value, false
+		}
+	}
+
+	valid := true
+	switch v := value.(type) {
+	case float64:
+		if min, ok := schemaNumber(schema, "minimum"); ok && v < min {
+			*errs = append(*errs, ArgError{Path: path, Message: fmt.Sprintf("must be >= %s, got %s", formatNumber(min), formatNumber(v))})
+			valid = false
+		}
+		if max, ok := schemaNumber(schema, "maximum"); ok && v > max {
+			*errs = append(*errs, ArgError{Path: path, Message: fmt.Sprintf("must be <= %s, got %s", formatNumber(max), formatNumber(v))})
+			valid = false
+		}
+		if min, ok := schemaNumber(schema, "exclusiveMinimum"); ok && v <= min {
+			*errs = append(*errs, ArgError{Path: path, Message: fmt.Sprintf("must be > %s, got %s", formatNumber(min), formatNumber(v))})
+			valid = false
+		}
+		if max, ok := schemaNumber(schema, "exclusiveMaximum"); ok && v >= max {
+			*errs = append(*errs, ArgError{Path: path, Message: fmt.Sprintf("must be < %s, got %s", formatNumber(max), formatNumber(v))})
+			valid = false
+		}
+	case string:
+		n := float64(len([]rune(v)))
+		if min, ok := schemaNumber(schema, "minLength"); ok && n < min {
+			*errs = append(*errs, ArgError{Path: path, Message: fmt.Sprintf("must be at least %s characters long", formatNumber(min))})
+			valid = false
+		}
+		if max, ok := schemaNumber(schema, "maxLength"); ok && n > max {
+			*errs = append(*errs, ArgError{Path: path, Message: fmt.Sprintf("must be at most %s characters long", formatNumber(max))})
+			valid = false
+		}
+	case []any:
+		n := float64(len(v))
+		if min, ok := schemaNumber(schema, "minItems"); ok && n < min {
+			*errs = append(*errs, ArgError{Path: path, Message: fmt.Sprintf("must contain at least %s items", formatNumber(min))})
+			valid = false
+		}
+		if max, ok := schemaNumber(schema, "maxItems"); ok && n > max {
+			*errs = append(*errs, ArgError{Path: path, Message: fmt.Sprintf("must contain at most %s items", formatNumber(max))})
+			valid = false
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			out := make([]any, len(v))
+			for i, item := range v {
+				coerced, ok := validateValue(fmt.Sprintf("%s[%d]", path, i), items, item, errs)
+				out[i] = coerced
+				valid = valid && ok
+			}
+			value = out
+		}
+	case map[string]any:
+		if _, hasProps := schema["properties"]; hasProps || schema["required"] != nil {
+			out, ok := validateObject(path, schema, v, errs)
+			value = out
+			valid = valid && ok
+		}
+	}
+
+	return value, valid
+}
+
+// validateObject 驗證物件的 required 與 properties
+func validateObject(path string, schema map[string]any, obj map[string]any, errs *[]ArgError) (map[string]any, bool) {
+	out := make(map[string]any, len(obj))
+	for k, v := range obj {
+		out[k] = v
+	}
+
+	valid := true
+	props, _ := schema["properties"].(map[string]any)
+
+	// JSON null 對可選參數而言等同於未提供
+	for k, v := range out {
+		if v == nil {
+			delete(out, k)
+		}
+	}
+
+	for _, name := range toStringSlice(schema["required"]) {
+		if _, ok := out[name]; !ok {
+			*errs = append(*errs, ArgError{Path: joinPath(path, name), Message: "required property is missing"})
+			valid = false
+		}
+	}
+
+	names := make([]string, 0, len(out))
+	for k := range out {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propSchema, known := props[name].(map[string]any)
+		if !known {
+			if ap, ok := schema["additionalProperties"].(bool); ok && !ap {
+				*errs = append(*errs, ArgError{Path: joinPath(path, name), Message: "unknown property"})
+				valid = false
+			}
+			continue
+		}
+		coerced, ok := validateValue(joinPath(path, name), propSchema, out[name], errs)
+		out[name] = coerced
+		valid = valid && ok
+	}
+
+	return out, valid
+}
+
+// coerceToAny 嘗試將值轉為任一允許的型別 (先比對完全相符，再嘗試轉型)
+func coerceToAny(value any, types []string) (any, bool) {
+	for _, t := range types {
+		if matchesType(value, t) {
+			return value, true
+		}
+	}
+	for _, t := range types {
+		if coerced, ok := coerce(value, t); ok {
+			return coerced, true
+		}
+	}
+	return value, false
+}
+
+func matchesType(value any, t string) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f) && !math.IsInf(f, 0)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+// coerce 在不會遺失資訊的前提下轉型
+func coerce(value any, t string) (any, bool) {
+	switch t {
+	case "integer":
+		if s, ok := value.(string); ok {
+			if n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+				return float64(n), true
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil && f == math.Trunc(f) && !math.IsInf(f, 0) {
+				return f, true
+			}
+		}
+	case "number":
+		if s, ok := value.(string); ok {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+				return f, true
+			}
+		}
+	case "boolean":
+		if s, ok := value.(string); ok {
+			switch strings.ToLower(strings.TrimSpace(s)) {
+			case "true":
+				return true, true
+			case "false":
+				return false, true
+			}
+		}
+	case "string":
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+	case "array":
+		switch v := value.(type) {
+		case []string:
+			out := make([]any, len(v))
+			for i, s := range v {
+				out[i] = s
+			}
+			return out, true
+		case string:
+			// 有些模型會把陣列序列化成字串再傳入
+			var arr []any
+			if strings.HasPrefix(strings.TrimSpace(v), "[") && json.Unmarshal([]byte(v), &arr) == nil {
+				return arr, true
+			}
+		}
+	case "object":
+		if s, ok := value.(string); ok {
+			var obj map[string]any
+			if strings.HasPrefix(strings.TrimSpace(s), "{") && json.Unmarshal([]byte(s), &obj) == nil {
+				return obj, true
+			}
+		}
+	}
+	return value, false
+}
+
+// normalizeNumber 將 Go 的整數型別統一為 float64 (與 JSON 解碼結果一致)
+func normalizeNumber(value any) any {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+	return value
+}
+
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []any:
+		return toStringSlice(t)
+	}
+	return nil
+}
+
+func schemaNumber(schema map[string]any, key string) (float64, bool) {
+	v, ok := normalizeNumber(schema[key]).(float64)
+	return v, ok
+}
+
+func toStringSlice(v any) []string {
+	switch s := v.(type) {
+	case []string:
+		return s
+	case []any:
+		out := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func toAnySlice(v any) []any {
+	switch s := v.(type) {
+	case []any:
+		return s
+	case []string:
+		out := make([]any, len(s))
+		for i, str := range s {
+			out[i] = str
+		}
+		return out
+	case []int:
+		out := make([]any, len(s))
+		for i, n := range s {
+			out[i] = float64(n)
+		}
+		return out
+	}
+	return nil
+}
+
+func enumContains(allowed []any, value any) bool {
+	for _, a := range allowed {
+		if normalizeNumber(a) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(allowed []any) string {
+	parts := make([]string, len(allowed))
+	for i, a := range allowed {
+		parts[i] = describeLiteral(normalizeNumber(a))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func describeLiteral(v any) string {
+	switch x := v.(type) {
+	case string:
+		return strconv.Quote(x)
+	case float64:
+		return formatNumber(x)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// describeValue 產生「型別 + 值」的描述，長字串會被截斷
+func describeValue(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		if r := []rune(x); len(r) > 40 {
+			x = string(r[:40]) + "..."
+		}
+		return "string " + strconv.Quote(x)
+	case float64:
+		return "number " + formatNumber(x)
+	case bool:
+		return "boolean " + strconv.FormatBool(x)
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// formatArgErrors 將驗證錯誤整理成 LLM 易於理解的條列格式
+func formatArgErrors(toolName string, errs []ArgError) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(i18n.GetInstance().T("errors.invalid_args"), toolName))
+	sb.WriteString("\n")
+	for _, e := range errs {
+		sb.WriteString("- " + e.String() + "\n")
+	}
+	sb.WriteString(i18n.GetInstance().T("errors.invalid_args_hint"))
+	return sb.String()
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func editSchema() map[string]any {
+	return (&EditFileTool{}).Parameters()
+}
+
+func TestValidateArgs_MissingRequired(t *testing.T) {
+	_, errs := validateArgs(editSchema(), map[string]any{
+		"path":        "a.txt",
+		"end_line":    float64(2),
+		"new_content": "x",
+	})
+
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	if errs[0].Path != "start_line" || !strings.Contains(errs[0].Message, "required") {
+		t.Errorf("unexpected error: %v", errs[0])
+	}
+}
+
+func TestValidateArgs_CoercesSafeValues(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"count":   map[string]any{"type": "integer"},
+			"ratio":   map[string]any{"type": "number"},
+			"enabled": map[string]any{"type": "boolean"},
+			"label":   map[string]any{"type": "string"},
+			"tags":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+	}
+
+	args := map[string]any{
+		"count":   "5",
+		"ratio":   "0.25",
+		"enabled": "true",
+		"label":   float64(42),
+		"tags":    `["a","b"]`,
+	}
+	out, errs := validateArgs(schema, args)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	if out["count"] != float64(5) {
+		t.Errorf("expected count 5, got %#v", out["count"])
+	}
+	if out["ratio"] != 0.25 {
+		t.Errorf("expected ratio 0.25, got %#v", out["ratio"])
+	}
+	if out["enabled"] != true {
+		t.Errorf("expected enabled true, got %#v", out["enabled"])
+	}
+	if out["label"] != "42" {
+		t.Errorf("expected label \"42\", got %#v", out["label"])
+	}
+	if tags, ok := out["tags"].([]any); !ok || len(tags) != 2 {
+		t.Errorf("expected tags to be decoded, got %#v", out["tags"])
+	}
+
+	// original map must not be modified
+	if args["count"] != "5" {
+		t.Error("validateArgs must not modify the input map")
+	}
+}
+
+func TestValidateArgs_Rejects(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"line":  map[string]any{"type": "integer", "minimum": 1, "maximum": 100},
+			"mode":  map[string]any{"type": "string", "enum": []string{"fast", "slow"}},
+			"name":  map[string]any{"type": "string", "minLength": 2},
+			"items": map[string]any{"type": "array", "items": map[string]any{"type": "integer"}},
+		},
+		"additionalProperties": false,
+	}
+
+	tests := []struct {
+		name string
+		args map[string]any
+		path string
+		want string
+	}{
+		{"non-integer float", map[string]any{"line": 1.5}, "line", "expected integer"},
+		{"non-numeric string", map[string]any{"line": "abc"}, "line", `got string "abc"`},
+		{"below minimum", map[string]any{"line": float64(0)}, "line", "must be >= 1"},
+		{"above maximum", map[string]any{"line": "101"}, "line", "must be <= 100"},
+		{"enum", map[string]any{"mode": "medium"}, "mode", `must be one of ["fast", "slow"]`},
+		{"min length", map[string]any{"name": "a"}, "name", "at least 2 characters"},
+		{"array item", map[string]any{"items": []any{float64(1), "x"}}, "items[1]", "expected integer"},
+		{"unknown property", map[string]any{"bogus": true}, "bogus", "unknown property"},
+		{"bool for integer", map[string]any{"line": true}, "line", "expected integer, got boolean"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := validateArgs(schema, tt.args)
+			if len(errs) != 1 {
+				t.Fatalf("expected 1 error, got %v", errs)
+			}
+			if errs[0].Path != tt.path {
+				t.Errorf("expected path %s, got %s", tt.path, errs[0].Path)
+			}
+			if !strings.Contains(errs[0].Message, tt.want) {
+				t.Errorf("expected message containing %q, got %q", tt.want, errs[0].Message)
+			}
+		})
+	}
+}
+
+func TestValidateArgs_NullTreatedAsMissing(t *testing.T) {
+	schema := (&ExecTool{}).Parameters()
+
+	out, errs := validateArgs(schema, map[string]any{"command": "ls", "timeout": nil})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if _, ok := out["timeout"]; ok {
+		t.Error("expected null optional argument to be dropped")
+	}
+
+	_, errs = validateArgs(schema, map[string]any{"command": nil})
+	if len(errs) != 1 || errs[0].Path != "command" {
+		t.Errorf("expected missing command error, got %v", errs)
+	}
+}
+
+func TestRegistry_Execute_ValidatesArgs(t *testing.T) {
+	var received map[string]any
+	r := NewRegistry()
+	r.Register(&mockTool{
+		name: "echo",
+		executeFunc: func(ctx context.Context, args map[string]any) *ToolResult {
+			received = args
+			return &ToolResult{ForLLM: "ok"}
+		},
+	})
+	r.Register(&EditFileTool{})
+
+	result := r.Execute(context.Background(), "edit_file", map[string]any{"path": "a.txt", "start_line": "x"})
+	if !result.IsError {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"edit_file", "start_line: expected integer", "end_line: required", "new_content: required"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("expected %q in %q", want, result.ForLLM)
+		}
+	}
+
+	result = r.Execute(context.Background(), "echo", map[string]any{"arg": float64(7)})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	if received["arg"] != "7" {
+		t.Errorf("expected coerced argument \"7\", got %#v", received["arg"])
+	}
+}
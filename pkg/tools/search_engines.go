@@ -0,0 +1,400 @@
+package tools
+
+// ============================================================================
+// 搜尋引擎後端 (Search Engine Backends)
+// ============================================================================
+// web_search 工具透過 SearchEngine 介面呼叫實際的搜尋服務，可在設定檔中選擇
+// 一個或多個後端，依序嘗試：前一個失敗時自動改用下一個。
+//
+// 提供的後端：
+//   1. DuckDuckGoEngine (duckduckgo) : 解析 html.duckduckgo.com 的 HTML 結果頁，免金鑰
+//   2. SearXNGEngine    (searxng)    : 自架 SearXNG 的 JSON API
+//   3. BraveEngine      (brave)      : Brave Search API (需 API Key)
+//   4. TavilyEngine     (tavily)     : Tavily Search API (需 API Key)
+// ============================================================================
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	searchTimeout      = 15 * time.Second
+	searchMaxBodyBytes = 2 << 20
+	searchUserAgent    = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.0.0 Safari/537.36"
+)
+
+// SearchResult 是單筆結構化搜尋結果
+type SearchResult struct {
+	Title   string
+	URL     string
+	Snippet string
+	Date    string // 發布日期 (後端有提供時)
+}
+
+// SearchEngine 是搜尋後端的共同介面
+type SearchEngine interface {
+	// Name 回傳後端名稱 (與設定檔中的名稱相同)
+	Name() string
+	// Search 執行搜尋並回傳最多 limit 筆結果
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+}
+
+// NewSearchEngine 依名稱建立搜尋後端
+//
+// 參數：
+//   - name:     後端名稱 (duckduckgo / searxng / brave / tavily)
+//   - endpoint: API 網址 (searxng 必填，其餘為空時使用官方網址)
+//   - apiKey:   API 金鑰 (brave 與 tavily 必填)
+func NewSearchEngine(name, endpoint, apiKey string) (SearchEngine, error) {
+	switch strings.ToLower(name) {
+	case "duckduckgo", "ddg":
+		return &DuckDuckGoEngine{Endpoint: endpoint}, nil
+	case "searxng":
+		if endpoint == "" {
+			return nil, fmt.Errorf("searxng requires a base URL")
+		}
+		return &SearXNGEngine{BaseURL: endpoint}, nil
+	case "brave":
+		if apiKey == "" {
+			return nil, fmt.Errorf("brave requires an API key")
+		}
+		return &BraveEngine{APIKey: apiKey, Endpoint: endpoint}, nil
+	case "tavily":
+		if apiKey == "" {
+			return nil, fmt.Errorf("tavily requires an API key")
+		}
+		return &TavilyEngine{APIKey: apiKey, Endpoint: endpoint}, nil
+	}
+	return nil, fmt.Errorf("unknown search engine %q", name)
+}
+
+// fetchSearchBody 送出請求並回傳回應內容，非 2xx 狀態視為錯誤
+func fetchSearchBody(req *http.Request) ([]byte, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", searchUserAgent)
+	}
+	client := &http.Client{Timeout: searchTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, searchMaxBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// htmlToText 去除片段中的標籤 (例如 <strong>) 並解碼 HTML Entity
+func htmlToText(s string) string {
+	var sb strings.Builder
+	for _, tok := range tokenizeHTML(s) {
+		if tok.kind == htmlText {
+			sb.WriteString(tok.text)
+		}
+	}
+	return collapseSpaces(sb.String())
+}
+
+func limitResults(results []SearchResult, limit int) []SearchResult {
+	if limit > 0 && len(results) > limit {
+		return results[:limit]
+	}
+	return results
+}
+
+// ============================================================================
+// DuckDuckGo (HTML)
+// ============================================================================
+
+const duckDuckGoEndpoint = "https://html.duckduckgo.com/html/"
+
+// DuckDuckGoEngine 解析 DuckDuckGo 的無 JavaScript 結果頁
+type DuckDuckGoEngine struct {
+	Endpoint string // 空字串時使用 html.duckduckgo.com
+}
+
+func (e *DuckDuckGoEngine) Name() string { return "duckduckgo" }
+
+func (e *DuckDuckGoEngine) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	endpoint := e.Endpoint
+	if endpoint == "" {
+		endpoint = duckDuckGoEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"?q="+url.QueryEscape(query), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := fetchSearchBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	page := string(body)
+	results := parseDuckDuckGoHTML(page)
+	if len(results) == 0 {
+		// 驗證頁或版面改變時不會有結果，也不會有「無結果」區塊，視為失敗以便改用下一個後端
+		if strings.Contains(page, "anomaly-modal") || strings.Contains(page, "challenge-form") {
+			return nil, fmt.Errorf("request was blocked by a bot challenge")
+		}
+		if !strings.Contains(page, "no-results") {
+			return nil, fmt.Errorf("could not parse result page")
+		}
+	}
+	return limitResults(results, limit), nil
+}
+
+// parseDuckDuckGoHTML 依 class 名稱擷取結果：result__a 為標題連結、result__snippet 為摘要
+func parseDuckDuckGoHTML(page string) []SearchResult {
+	var results []SearchResult
+	var field *string // 目前正在收集文字的欄位
+	var fieldTag string
+	depth := 0
+
+	for _, tok := range tokenizeHTML(page) {
+		switch tok.kind {
+		case htmlStartTag:
+			if field != nil {
+				if tok.name == fieldTag && !tok.selfClosing {
+					depth++
+				}
+				continue
+			}
+			classes := " " + tok.attrs["class"] + " "
+			switch {
+			case strings.Contains(classes, " result__a "):
+				results = append(results, SearchResult{URL: unwrapDuckDuckGoLink(tok.attrs["href"])})
+				field, fieldTag, depth = &results[len(results)-1].Title, tok.name, 1
+			case strings.Contains(classes, " result__snippet ") && len(results) > 0:
+				last := &results[len(results)-1]
+				if last.URL == "" {
+					last.URL = unwrapDuckDuckGoLink(tok.attrs["href"])
+				}
+				field, fieldTag, depth = &last.Snippet, tok.name, 1
+			}
+		case htmlEndTag:
+			if field != nil && tok.name == fieldTag {
+				depth--
+				if depth == 0 {
+					*field = collapseSpaces(*field)
+					field = nil
+				}
+			}
+		case htmlText:
+			if field != nil {
+				*field += tok.text
+			}
+		}
+	}
+
+	// 排除廣告 (連結指向 duckduckgo.com/y.js) 與沒有網址的項目
+	filtered := results[:0]
+	for _, r := range results {
+		if r.URL == "" || strings.Contains(r.URL, "duckduckgo.com/y.js") {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+// unwrapDuckDuckGoLink 將 //duckduckgo.com/l/?uddg=<網址> 轉回原始網址
+func unwrapDuckDuckGoLink(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	if target := u.Query().Get("uddg"); target != "" && strings.HasSuffix(u.Host, "duckduckgo.com") {
+		return target
+	}
+	if u.Scheme == "" && strings.HasPrefix(href, "//") {
+		return "https:" + href
+	}
+	return href
+}
+
+// ============================================================================
+// SearXNG (JSON)
+// ============================================================================
+
+// SearXNGEngine 呼叫 SearXNG 的 JSON API (需在實例設定中啟用 json 格式)
+type SearXNGEngine struct {
+	BaseURL string // 例如 http://localhost:8888
+}
+
+func (e *SearXNGEngine) Name() string { return "searxng" }
+
+func (e *SearXNGEngine) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	params := url.Values{"q": {query}, "format": {"json"}}
+	endpoint := strings.TrimRight(e.BaseURL, "/") + "/search?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	body, err := fetchSearchBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Results []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			Content       string `json:"content"`
+			PublishedDate string `json:"publishedDate"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(payload.Results))
+	for _, r := range payload.Results {
+		results = append(results, SearchResult{
+			Title:   htmlToText(r.Title),
+			URL:     r.URL,
+			Snippet: htmlToText(r.Content),
+			Date:    r.PublishedDate,
+		})
+	}
+	return limitResults(results, limit), nil
+}
+
+// ============================================================================
+// Brave Search API
+// ============================================================================
+
+const braveEndpoint = "https://api.search.brave.com/res/v1/web/search"
+
+// BraveEngine 呼叫 Brave Search API
+type BraveEngine struct {
+	APIKey   string
+	Endpoint string // 空字串時使用官方網址
+}
+
+func (e *BraveEngine) Name() string { return "brave" }
+
+func (e *BraveEngine) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	endpoint := e.Endpoint
+	if endpoint == "" {
+		endpoint = braveEndpoint
+	}
+	params := url.Values{"q": {query}}
+	if limit > 0 {
+		params.Set("count", fmt.Sprint(limit))
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", e.APIKey)
+	body, err := fetchSearchBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+				Age         string `json:"age"`
+				PageAge     string `json:"page_age"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(payload.Web.Results))
+	for _, r := range payload.Web.Results {
+		date := r.PageAge
+		if date == "" {
+			date = r.Age
+		}
+		results = append(results, SearchResult{
+			Title:   htmlToText(r.Title),
+			URL:     r.URL,
+			Snippet: htmlToText(r.Description),
+			Date:    date,
+		})
+	}
+	return limitResults(results, limit), nil
+}
+
+// ============================================================================
+// Tavily Search API
+// ============================================================================
+
+const tavilyEndpoint = "https://api.tavily.com/search"
+
+// TavilyEngine 呼叫 Tavily Search API
+type TavilyEngine struct {
+	APIKey   string
+	Endpoint string // 空字串時使用官方網址
+}
+
+func (e *TavilyEngine) Name() string { return "tavily" }
+
+func (e *TavilyEngine) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	endpoint := e.Endpoint
+	if endpoint == "" {
+		endpoint = tavilyEndpoint
+	}
+	reqBody := map[string]any{"query": query}
+	if limit > 0 {
+		reqBody["max_results"] = limit
+	}
+	data, _ := json.Marshal(reqBody)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	body, err := fetchSearchBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Results []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			Content       string `json:"content"`
+			PublishedDate string `json:"published_date"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(payload.Results))
+	for _, r := range payload.Results {
+		results = append(results, SearchResult{
+			Title:   r.Title,
+			URL:     r.URL,
+			Snippet: collapseSpaces(r.Content),
+			Date:    r.PublishedDate,
+		})
+	}
+	return limitResults(results, limit), nil
+}
@@ -0,0 +1,259 @@
+package tools
+
+// ============================================================================
+// SearchFilesTool: 搜尋檔案內容工具
+// ============================================================================
+// 功能：以正規表示式搜尋工作區內的檔案內容 (Go 原生實作，不經過 Shell)
+// 工具名稱：search_files
+// 輸出格式：
+//   - 符合的行：  路徑:行號: 內容
+//   - 上下文行：  路徑-行號- 內容
+//   - 不相鄰的區塊以 "--" 分隔
+//
+// 路徑一律相對於工作區，可直接作為 read_file / edit_file 的參數。
+// ============================================================================
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chiisen/mini_bot/pkg/i18n"
+)
+
+const (
+	searchDefaultMaxResults = 100
+	searchMaxResultsLimit   = 1000
+	searchMaxFileBytes      = 4 << 20 // 超過此大小的檔案不搜尋
+	searchMaxLineRunes      = 240     // 單行輸出的最大字元數
+)
+
+type SearchFilesTool struct {
+	Sandbox *Sandbox
+}
+
+func (t *SearchFilesTool) Name() string        { return "search_files" }
+func (t *SearchFilesTool) Description() string { return i18n.GetInstance().T("tools.search_files") }
+func (t *SearchFilesTool) Parameters() map[string]any {
+	tr := i18n.GetInstance()
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"pattern": map[string]any{"type": "string", "description": tr.T("tool_params.search_pattern")},
+			"path":    map[string]any{"type": "string", "description": tr.T("tool_params.search_path")},
+			"include": map[string]any{"type": "string", "description": tr.T("tool_params.include_globs")},
+			"exclude": map[string]any{"type": "string", "description": tr.T("tool_params.exclude_globs")},
+			"context_lines": map[string]any{
+				"type":        "integer",
+				"minimum":     0,
+				"maximum":     10,
+				"description": tr.T("tool_params.context_lines"),
+			},
+			"max_results": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"maximum":     searchMaxResultsLimit,
+				"description": tr.T("tool_params.max_matches"),
+			},
+			"literal":         map[string]any{"type": "boolean", "description": tr.T("tool_params.literal")},
+			"ignore_case":     map[string]any{"type": "boolean", "description": tr.T("tool_params.ignore_case")},
+			"include_hidden":  map[string]any{"type": "boolean", "description": tr.T("tool_params.include_hidden")},
+			"include_ignored": map[string]any{"type": "boolean", "description": tr.T("tool_params.include_ignored")},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+// Execute 執行搜尋
+//
+// 執行步驟：
+//  1. 編譯正規表示式並透過沙盒取得搜尋起點
+//  2. 走訪目錄 (略過 .git、隱藏檔與 .gitignore 忽略的路徑)
+//  3. 略過二進位檔與過大的檔案，逐行比對
+//  4. 達到 max_results 時停止並註明結果已截斷
+func (t *SearchFilesTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	pattern, _ := args["pattern"].(string)
+	path, _ := args["path"].(string)
+	if path == "" {
+		path = "."
+	}
+	include := splitGlobs(stringArg(args, "include"))
+	exclude := splitGlobs(stringArg(args, "exclude"))
+	contextLines := intArg(args, "context_lines", 0)
+	maxResults := intArg(args, "max_results", searchDefaultMaxResults)
+	if maxResults <= 0 || maxResults > searchMaxResultsLimit {
+		maxResults = searchDefaultMaxResults
+	}
+
+	if pattern == "" {
+		return &ToolResult{ForLLM: "Error: pattern is required", IsError: true}
+	}
+	if boolArg(args, "literal") {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if boolArg(args, "ignore_case") {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: invalid regular expression: %v", err), IsError: true}
+	}
+
+	// 驗證路徑安全性
+	if err := validatePathName(path); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf(i18n.GetInstance().T("errors.path_invalid"), err), IsError: true}
+	}
+	root, err := t.Sandbox.CheckPath(path)
+	if err != nil {
+		return &ToolResult{ForLLM: err.Error(), IsError: true}
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+
+	s := &fileSearch{re: re, contextLines: contextLines, maxResults: maxResults}
+	if !info.IsDir() {
+		s.searchFile(root, t.Sandbox.relPath(root))
+	} else {
+		opts := walkOptions{
+			SkipHidden:  !boolArg(args, "include_hidden"),
+			SkipIgnored: !boolArg(args, "include_ignored"),
+		}
+		err = walkWorkspace(ctx, t.Sandbox, root, opts, func(e walkEntry) error {
+			if !e.Entry.Type().IsRegular() && e.Entry.Type()&fs.ModeSymlink == 0 {
+				return nil
+			}
+			if len(include) > 0 && !matchAnyGlob(include, e.Rel) {
+				return nil
+			}
+			if matchAnyGlob(exclude, e.Rel) {
+				return nil
+			}
+			if s.searchFile(e.Abs, t.Sandbox.relPath(e.Abs)) {
+				return errStopWalk
+			}
+			return nil
+		})
+		if err != nil {
+			return &ToolResult{ForLLM: fmt.Sprintf("Error: search interrupted: %v", err), IsError: true}
+		}
+	}
+
+	return &ToolResult{ForLLM: s.String()}
+}
+
+// fileSearch 累積搜尋結果
+type fileSearch struct {
+	re           *regexp.Regexp
+	contextLines int
+	maxResults   int
+
+	out       strings.Builder
+	matches   int
+	files     int
+	truncated bool
+}
+
+// searchFile 搜尋單一檔案，回傳 true 表示已達結果上限
+func (s *fileSearch) searchFile(abs, rel string) bool {
+	info, err := os.Stat(abs)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > searchMaxFileBytes {
+		return false
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil || isBinary(data) {
+		return false
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	lastPrinted := -1 // 最後輸出的行索引，用於合併重疊的上下文
+	fileHasMatch := false
+
+	for i, line := range lines {
+		if !s.re.MatchString(line) {
+			continue
+		}
+		if s.matches >= s.maxResults {
+			s.truncated = true
+			return true
+		}
+		if !fileHasMatch {
+			fileHasMatch = true
+			s.files++
+		}
+		s.matches++
+
+		start := max(i-s.contextLines, lastPrinted+1)
+		if s.contextLines > 0 && s.out.Len() > 0 && (lastPrinted < 0 || start > lastPrinted+1) {
+			s.out.WriteString("--\n")
+		}
+		for j := start; j < i; j++ {
+			s.writeLine(rel, j, lines[j], '-')
+		}
+		s.writeLine(rel, i, line, ':')
+		lastPrinted = i
+
+		// 後方上下文：遇到下一個符合行時交給迴圈處理
+		for j := i + 1; j <= i+s.contextLines && j < len(lines); j++ {
+			if s.re.MatchString(lines[j]) {
+				break
+			}
+			s.writeLine(rel, j, lines[j], '-')
+			lastPrinted = j
+		}
+	}
+	return false
+}
+
+func (s *fileSearch) writeLine(rel string, idx int, text string, sep byte) {
+	text = strings.TrimRight(text, "\r")
+	if utf8.RuneCountInString(text) > searchMaxLineRunes {
+		text = string([]rune(text)[:searchMaxLineRunes]) + " …"
+	}
+	fmt.Fprintf(&s.out, "%s%c%d%c %s\n", rel, sep, idx+1, sep, text)
+}
+
+func (s *fileSearch) String() string {
+	if s.matches == 0 {
+		return "No matches found."
+	}
+	result := s.out.String()
+	if s.truncated {
+		result += fmt.Sprintf("\n[Results truncated at %d matches. Narrow the search with path, include or a more specific pattern.]\n", s.maxResults)
+	} else {
+		result += fmt.Sprintf("\n[%d matches in %d files]\n", s.matches, s.files)
+	}
+	return result
+}
+
+// isBinary 以前 8KB 是否含 NUL 字元判斷二進位檔 (與 git 的判斷方式相同)
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// stringArg / intArg / boolArg 讀取已經過 Schema 驗證的選填參數
+func stringArg(args map[string]any, key string) string {
+	v, _ := args[key].(string)
+	return v
+}
+
+func intArg(args map[string]any, key string, def int) int {
+	if v, ok := args[key].(float64); ok {
+		return int(v)
+	}
+	return def
+}
+
+func boolArg(args map[string]any, key string) bool {
+	v, _ := args[key].(bool)
+	return v
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupSearchWorkspace(t *testing.T) (string, *Sandbox) {
+	t.Helper()
+	ws := t.TempDir()
+	files := map[string]string{
+		"main.go":            "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"pkg/util/util.go":   "package util\n\n// Hello returns a greeting.\nfunc Hello() string {\n\treturn \"hello\"\n}\n",
+		"docs/guide.md":      "# Guide\n\nSay hello to the bot.\n",
+		"build/out.go":       "package out // hello\n",
+		".hidden/secret.txt": "hello from hidden\n",
+		".gitignore":         "build/\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(ws, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), 0644)
+	}
+	os.WriteFile(filepath.Join(ws, "image.bin"), []byte("hello\x00\x01\x02"), 0644)
+	sandbox, _ := NewSandbox(ws)
+	return ws, sandbox
+}
+
+func TestSearchFilesTool(t *testing.T) {
+	_, sandbox := setupSearchWorkspace(t)
+	tool := &SearchFilesTool{Sandbox: sandbox}
+	ctx := context.Background()
+
+	res := tool.Execute(ctx, map[string]any{"pattern": "hello"})
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+	for _, want := range []string{`main.go:4: 	println("hello")`, "pkg/util/util.go:5: \treturn \"hello\"", "docs/guide.md:3: Say hello to the bot."} {
+		if !strings.Contains(res.ForLLM, want) {
+			t.Errorf("expected %q in output:\n%s", want, res.ForLLM)
+		}
+	}
+	for _, unwanted := range []string{"build/out.go", ".hidden", "image.bin"} {
+		if strings.Contains(res.ForLLM, unwanted) {
+			t.Errorf("did not expect %q in output:\n%s", unwanted, res.ForLLM)
+		}
+	}
+
+	t.Run("include and exclude", func(t *testing.T) {
+		res := tool.Execute(ctx, map[string]any{"pattern": "hello", "include": "*.go", "exclude": "pkg/**"})
+		if !strings.Contains(res.ForLLM, "main.go:4:") || strings.Contains(res.ForLLM, "util.go") || strings.Contains(res.ForLLM, "guide.md") {
+			t.Errorf("unexpected output:\n%s", res.ForLLM)
+		}
+	})
+
+	t.Run("ignored and hidden on request", func(t *testing.T) {
+		res := tool.Execute(ctx, map[string]any{"pattern": "hello", "include_ignored": true, "include_hidden": true})
+		if !strings.Contains(res.ForLLM, "build/out.go:1:") || !strings.Contains(res.ForLLM, ".hidden/secret.txt:1:") {
+			t.Errorf("unexpected output:\n%s", res.ForLLM)
+		}
+	})
+
+	t.Run("context lines", func(t *testing.T) {
+		res := tool.Execute(ctx, map[string]any{"pattern": "^func Hello", "path": "pkg", "context_lines": float64(1)})
+		want := "pkg/util/util.go-3- // Hello returns a greeting.\npkg/util/util.go:4: func Hello() string {\npkg/util/util.go-5- \treturn \"hello\"\n"
+		if !strings.Contains(res.ForLLM, want) {
+			t.Errorf("expected context block:\n%s\ngot:\n%s", want, res.ForLLM)
+		}
+	})
+
+	t.Run("max results", func(t *testing.T) {
+		res := tool.Execute(ctx, map[string]any{"pattern": "hello", "ignore_case": true, "max_results": float64(1)})
+		if strings.Count(res.ForLLM, ": ") != 1 || !strings.Contains(res.ForLLM, "truncated at 1") {
+			t.Errorf("expected one truncated result:\n%s", res.ForLLM)
+		}
+	})
+
+	t.Run("literal", func(t *testing.T) {
+		res := tool.Execute(ctx, map[string]any{"pattern": "Hello()", "literal": true})
+		if !strings.Contains(res.ForLLM, "util.go:4:") || strings.Contains(res.ForLLM, "util.go:3:") {
+			t.Errorf("unexpected output:\n%s", res.ForLLM)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if res := tool.Execute(ctx, map[string]any{"pattern": "("}); !res.IsError {
+			t.Error("expected invalid regex error")
+		}
+		if res := tool.Execute(ctx, map[string]any{"pattern": "x", "path": "/etc"}); !res.IsError {
+			t.Error("expected path outside workspace to be rejected")
+		}
+		if res := tool.Execute(ctx, map[string]any{"pattern": "zzz_not_present"}); res.IsError || res.ForLLM != "No matches found." {
+			t.Errorf("unexpected no-match result: %+v", res)
+		}
+	})
+}
+
+func TestSearchFilesTool_SymlinkOutsideWorkspace(t *testing.T) {
+	ws, sandbox := setupSearchWorkspace(t)
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "leak.txt"), []byte("hello outside\n"), 0644)
+	if err := os.Symlink(filepath.Join(outside, "leak.txt"), filepath.Join(ws, "leak.txt")); err != nil {
+		t.Skip("symlinks not supported")
+	}
+
+	res := (&SearchFilesTool{Sandbox: sandbox}).Execute(context.Background(), map[string]any{"pattern": "outside"})
+	if strings.Contains(res.ForLLM, "leak.txt") {
+		t.Errorf("symlink escaping the workspace was searched:\n%s", res.ForLLM)
+	}
+}
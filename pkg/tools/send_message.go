@@ -0,0 +1,110 @@
+package tools
+
+// ============================================================================
+// SendMessageTool: 主動傳送訊息
+// ============================================================================
+// 讓 Agent 主動傳送訊息到設定好的頻道與聊天室，例如在 CLI 的長時間任務完成後
+// 通知使用者的 Telegram。
+//
+// Agent 只能指定目的地名稱 (例如 "me")，實際的頻道與聊天室 ID 來自設定檔，
+// 不在白名單中的目的地一律拒絕。訊息透過 MessageSender 送出，
+// 由 channels 套件實作，tools 套件不需要知道各頻道的細節。
+// ============================================================================
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chiisen/mini_bot/pkg/i18n"
+)
+
+// sendMessageMaxChars 是單則訊息的長度上限 (Telegram 上限為 4096 字元)
+const sendMessageMaxChars = 4000
+
+// MessageSender 將訊息送到指定頻道的聊天室
+type MessageSender interface {
+	SendMessage(ctx context.Context, channel, chatID, text string) error
+}
+
+// MessageTarget 是 Agent 可以傳送訊息的目的地
+type MessageTarget struct {
+	Channel     string // 例如 "telegram"
+	ChatID      string
+	Description string // 提供給 LLM 的說明，例如 "我的 Telegram 私人聊天室"
+}
+
+// SendMessageTool 傳送訊息到白名單中的目的地
+type SendMessageTool struct {
+	Sender  MessageSender            // 為 nil 時工具會回傳錯誤
+	Targets map[string]MessageTarget // 目的地名稱 -> 目的地
+}
+
+func (t *SendMessageTool) Name() string { return "send_message" }
+
+func (t *SendMessageTool) Description() string {
+	var sb strings.Builder
+	sb.WriteString(i18n.GetInstance().T("tools.send_message"))
+	for _, name := range t.targetNames() {
+		target := t.Targets[name]
+		fmt.Fprintf(&sb, "\n- %s (%s)", name, target.Channel)
+		if target.Description != "" {
+			sb.WriteString(": " + target.Description)
+		}
+	}
+	return sb.String()
+}
+
+func (t *SendMessageTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"to": map[string]any{
+				"type":        "string",
+				"enum":        t.targetNames(),
+				"description": i18n.GetInstance().T("tool_params.message_to"),
+			},
+			"message": map[string]any{
+				"type":        "string",
+				"description": i18n.GetInstance().T("tool_params.message_text"),
+			},
+		},
+		"required": []string{"to", "message"},
+	}
+}
+
+func (t *SendMessageTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	to, _ := args["to"].(string)
+	message, _ := args["message"].(string)
+
+	target, ok := t.Targets[to]
+	if !ok {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: unknown destination %q, use one of: %s", to, strings.Join(t.targetNames(), ", ")), IsError: true}
+	}
+	if strings.TrimSpace(message) == "" {
+		return &ToolResult{ForLLM: "Error: message is empty", IsError: true}
+	}
+	if n := utf8.RuneCountInString(message); n > sendMessageMaxChars {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: message has %d characters, the limit is %d; send a shorter summary", n, sendMessageMaxChars), IsError: true}
+	}
+	if t.Sender == nil {
+		return &ToolResult{ForLLM: "Error: no channel is available to send messages", IsError: true}
+	}
+
+	if err := t.Sender.SendMessage(ctx, target.Channel, target.ChatID, message); err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error sending message to %s: %v", to, err), IsError: true}
+	}
+	return &ToolResult{ForLLM: fmt.Sprintf("Message sent to %s (%s).", to, target.Channel)}
+}
+
+// targetNames 回傳排序後的目的地名稱，讓工具說明保持穩定
+func (t *SendMessageTool) targetNames() []string {
+	names := make([]string, 0, len(t.Targets))
+	for name := range t.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type recordingSender struct {
+	sent []string
+	err  error
+}
+
+func (s *recordingSender) SendMessage(ctx context.Context, channel, chatID, text string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, channel+"/"+chatID+": "+text)
+	return nil
+}
+
+func TestSendMessageTool(t *testing.T) {
+	sender := &recordingSender{}
+	tool := &SendMessageTool{
+		Sender: sender,
+		Targets: map[string]MessageTarget{
+			"me":     {Channel: "telegram", ChatID: "1001", Description: "my private chat"},
+			"family": {Channel: "telegram", ChatID: "-100200"},
+		},
+	}
+	ctx := context.Background()
+
+	if desc := tool.Description(); !strings.Contains(desc, "- family (telegram)") || !strings.Contains(desc, "- me (telegram): my private chat") {
+		t.Errorf("description should list the targets, got %q", desc)
+	}
+	enum := tool.Parameters()["properties"].(map[string]any)["to"].(map[string]any)["enum"].([]string)
+	if strings.Join(enum, ",") != "family,me" {
+		t.Errorf("enum = %v", enum)
+	}
+
+	if res := tool.Execute(ctx, map[string]any{"to": "me", "message": "build finished"}); res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+	if len(sender.sent) != 1 || sender.sent[0] != "telegram/1001: build finished" {
+		t.Fatalf("sent = %v", sender.sent)
+	}
+
+	// destinations outside the allowlist are refused, even as raw chat IDs
+	for _, to := range []string{"1001", "telegram:1001", ""} {
+		if res := tool.Execute(ctx, map[string]any{"to": to, "message": "hi"}); !res.IsError || !strings.Contains(res.ForLLM, "unknown destination") {
+			t.Errorf("to=%q should be rejected, got %+v", to, res)
+		}
+	}
+	if res := tool.Execute(ctx, map[string]any{"to": "me", "message": "  "}); !res.IsError {
+		t.Error("empty messages should be rejected")
+	}
+	if res := tool.Execute(ctx, map[string]any{"to": "me", "message": strings.Repeat("字", sendMessageMaxChars+1)}); !res.IsError {
+		t.Error("overlong messages should be rejected")
+	}
+	if len(sender.sent) != 1 {
+		t.Errorf("rejected messages must not be sent: %v", sender.sent)
+	}
+
+	sender.err = errors.New("status: 403")
+	if res := tool.Execute(ctx, map[string]any{"to": "family", "message": "hi"}); !res.IsError || !strings.Contains(res.ForLLM, "403") {
+		t.Errorf("expected send error, got %+v", res)
+	}
+
+	tool.Sender = nil
+	if res := tool.Execute(ctx, map[string]any{"to": "me", "message": "hi"}); !res.IsError {
+		t.Error("a tool without sender should fail")
+	}
+}
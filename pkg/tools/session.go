@@ -0,0 +1,22 @@
+package tools
+
+import "context"
+
+type sessionKeyCtx struct{}
+
+// DefaultSessionKey is used when a tool runs outside an agent session (e.g. mcp-serve).
+const DefaultSessionKey = "default"
+
+// WithSessionKey returns a context that carries the conversation session key,
+// so tools can keep per-session state such as checkpoints.
+func WithSessionKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, sessionKeyCtx{}, key)
+}
+
+// SessionKeyFrom returns the session key stored in ctx, or DefaultSessionKey.
+func SessionKeyFrom(ctx context.Context) string {
+	if key, ok := ctx.Value(sessionKeyCtx{}).(string); ok && key != "" {
+		return key
+	}
+	return DefaultSessionKey
+}
@@ -1,19 +1,40 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"regexp"
-	"runtime"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/chiisen/mini_bot/pkg/i18n"
 )
 
+// ============================================================================
+// ExecTool: 命令執行工具
+// ============================================================================
+// 功能：在工作區目錄執行命令並回傳輸出
+// 工具名稱：exec
+//
+// 安全機制：
+//   - 不經過 Shell：命令字串由 parseCommandLine 解析為 argv，
+//     管線 (|) 由 Go 連接，其他 Shell 語法直接拒絕
+//   - 每個管線階段都必須通過 ExecPolicy (程式白名單與參數規則)
+//   - 指向工作區外的路徑參數會被拒絕；未使用 namespace 隔離時，
+//     指向唯讀根目錄或內部目錄的參數也會被拒絕 (使用時改由唯讀掛載保護)
+//   - dangerPatterns 作為額外的一層檢查
+//   - 子行程以 ExecIsolation 執行：清理過的環境變數、獨立的行程群組，
+//     Linux 上另有 rlimit 與可選的 namespace 隔離
+//
+// ============================================================================
 type ExecTool struct {
-	Sandbox *Sandbox
+	Sandbox   *Sandbox
+	Policy    *ExecPolicy    // nil 時使用 DefaultExecPolicy
+	Isolation *ExecIsolation // nil 時使用 DefaultExecIsolation
 }
 
 func (t *ExecTool) Name() string        { return "exec" }
@@ -23,138 +44,109 @@ func (t *ExecTool) Parameters() map[string]any {
 		"type": "object",
 		"properties": map[string]any{
 			"command": map[string]any{"type": "string", "description": i18n.GetInstance().T("tool_params.command")},
-			"timeout": map[string]any{"type": "integer", "description": "Timeout in seconds, default 30"},
+			"timeout": map[string]any{"type": "integer", "description": fmt.Sprintf("Timeout in seconds, default %d, at most %d", execDefaultTimeout, execMaxTimeout)},
 		},
 		"required": []string{"command"},
 	}
 }
 
-var allowedCommands = map[string]bool{
-	"ls": true, "dir": true, "cd": true, "pwd": true,
-	"cat": true, "head": true, "tail": true, "less": true,
-	"grep": true, "egrep": true, "fgrep": true,
-	"find": true, "xargs": true,
-	"wc": true, "sort": true, "uniq": true, "cut": true,
-	"echo": true, "printf": true,
-	"stat": true, "file": true, "md5sum": true, "sha256sum": true,
-	"tree": true, "ls -la": true, "ls -l": true,
-}
+const (
+	execDefaultTimeout = 30
+	execMaxTimeout     = 600 // 更久的命令請用 process_start 在背景執行
+)
 
+// dangerPatterns 是政策之外的額外檢查；只比對命令位置的字，
+// 避免誤擋 git log --format=... 這類合法參數。$(...) 與反引號已由 parseCommandLine 拒絕。
 var dangerPatterns = []string{
 	`rm\s+-rf\s+/`,
 	`del\s+/f`,
 	`rmdir\s+/s`,
-	`format\b`,
-	`mkfs\b`,
-	`diskpart\b`,
+	`(^|[;&|]\s*)(format|mkfs|diskpart|shutdown|reboot|poweroff)\b`,
 	`dd\s+if=`,
-	`shutdown\b`,
-	`reboot\b`,
-	`poweroff\b`,
 	`:\(\)\{\s+:\|:&\s+\};:`,
 	`;\s*rm\s+`,
 	`\|\s*rm\s+`,
 	`&&\s*rm\s+`,
 	`\|\|\s*rm\s+`,
-	`\$\(.*\)`,
-	"`.*`",
 }
 
-var dangerCommands = []string{
-	"curl", "wget", "nc", "ncat", "bash", "powershell", "sh",
-	"python", "python3", "perl", "ruby", "php", "node",
-	"npm", "pip", "cargo", "go", "rustc",
-	"ssh", "scp", "sftp", "ftp", "telnet",
-	"chmod", "chown", "chgrp",
-	"kill", "killall", "pkill",
-	"mount", "umount",
-	"fdisk", "parted", "sfdisk",
-	"sudo", "su",
-	"git", "svn", "hg",
-	"docker", "kubectl", "helm",
-	"tar", "gzip", "bzip2", "xz", "zip", "unzip",
-	"sed", "awk", "vim", "nano", "emacs",
-	"ln", "unlink", "touch", "mkdir", "rmdir",
-}
-
-func validateCommand(cmd string) bool {
-	fields := strings.Fields(cmd)
-	if len(fields) == 0 {
-		return false
-	}
-
-	if len(cmd) > 500 {
-		return false
-	}
-
-	baseCmd := strings.ToLower(fields[0])
-
-	if _, ok := allowedCommands[baseCmd]; ok {
-		return true
-	}
-	if strings.HasPrefix(baseCmd, "ls-") {
-		return true
-	}
-
-	for _, dc := range dangerCommands {
-		if baseCmd == dc {
+func isCommandSafe(cmd string) bool {
+	for _, pattern := range dangerPatterns {
+		matched, _ := regexp.MatchString(pattern, cmd)
+		if matched {
 			return false
 		}
 	}
-
-	return len(fields) > 0
+	return true
 }
 
-func sanitizeCommand(cmd string) string {
-	sanitized := cmd
-	dangerChars := []string{";", "|", "&&", "||", "$(", "`", "\\\n"}
-	for _, dc := range dangerChars {
-		sanitized = strings.ReplaceAll(sanitized, dc, "")
+// prepare 解析命令並依政策檢查每個管線階段
+func (t *ExecTool) prepare(ctx context.Context, cmdStr string) ([][]string, error) {
+	if len(cmdStr) > 2000 {
+		return nil, fmt.Errorf("command is too long")
+	}
+	if !isCommandSafe(cmdStr) {
+		return nil, fmt.Errorf("command matches a dangerous pattern")
 	}
-	return strings.TrimSpace(sanitized)
+	words, err := parseCommandLine(cmdStr)
+	if err != nil {
+		return nil, err
+	}
+	return t.checkStages(ctx, words)
 }
 
-func isCommandSafe(cmd string) bool {
-	for _, pattern := range dangerPatterns {
-		matched, _ := regexp.MatchString(pattern, cmd)
-		if matched {
-			return false
+// checkStages 展開萬用字元並依政策檢查每個管線階段；
+// ctx 帶有工具權限的命令規則時 (見 withCommandCheck)，每個階段也必須符合
+func (t *ExecTool) checkStages(ctx context.Context, words [][]cmdWord) ([][]string, error) {
+	policy := t.Policy
+	if policy == nil {
+		policy = DefaultExecPolicy()
+	}
+	stages := make([][]string, len(words))
+	for i, w := range words {
+		argv := expandWords(t.Sandbox, w)
+		if err := policy.Check(argv); err != nil {
+			return nil, err
+		}
+		if err := checkPathArgs(t.Sandbox, argv, !t.isolation().Namespaces); err != nil {
+			return nil, err
 		}
+		if check := commandCheckFrom(ctx); check != nil {
+			if err := check(argv); err != nil {
+				return nil, err
+			}
+		}
+		stages[i] = argv
 	}
-	return true
+	return stages, nil
 }
 
 func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	cmdStr, _ := args["command"].(string)
 
-	sanitized := sanitizeCommand(cmdStr)
-	if sanitized != cmdStr {
-		cmdStr = sanitized
+	stages, err := t.prepare(ctx, cmdStr)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: command rejected by the exec sandbox: %v", err), IsError: true}
 	}
 
-	if !validateCommand(cmdStr) || !isCommandSafe(cmdStr) {
-		return &ToolResult{ForLLM: "Error: command rejected due to safety sandbox restrictions.", IsError: true}
-	}
+	return t.run(ctx, stages, execTimeout(args))
+}
 
-	timeoutSec := float64(30)
-	if to, ok := args["timeout"].(float64); ok && to > 0 {
-		timeoutSec = to
+// execTimeout 取得 timeout 參數 (秒)，未指定時使用預設值，超過上限時以上限為準
+func execTimeout(args map[string]any) float64 {
+	to, ok := args["timeout"].(float64)
+	if !ok || to <= 0 {
+		return execDefaultTimeout
 	}
+	return min(to, execMaxTimeout)
+}
 
+// run 以隔離設定執行已通過檢查的管線
+func (t *ExecTool) run(ctx context.Context, stages [][]string, timeoutSec float64) *ToolResult {
 	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSec)*time.Second)
 	defer cancel()
 
-	// Determine the shell based on OS
-	var c *exec.Cmd
-	if runtime.GOOS == "windows" {
-		c = exec.CommandContext(timeoutCtx, "cmd", "/c", cmdStr)
-	} else {
-		c = exec.CommandContext(timeoutCtx, "sh", "-c", cmdStr)
-	}
-	c.Dir = t.Sandbox.Workspace
-
-	outBytes, err := c.CombinedOutput()
-	resultStr := string(outBytes)
+	resultStr, err := runPipeline(timeoutCtx, t.Sandbox.Workspace, stages, t.isolation())
 
 	if timeoutCtx.Err() == context.DeadlineExceeded {
 		return &ToolResult{ForLLM: fmt.Sprintf("Timeout after %.0f seconds.\nOutput: %s", timeoutSec, resultStr), IsError: true}
@@ -166,3 +158,133 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 
 	return &ToolResult{ForLLM: fmt.Sprintf("Command exited successfully.\nOutput: %s", resultStr), IsError: false}
 }
+
+// isolation 回傳隔離設定，並帶入 Sandbox 中需要以唯讀掛載保護的目錄
+func (t *ExecTool) isolation() *ExecIsolation {
+	iso := DefaultExecIsolation()
+	if t.Isolation != nil {
+		copied := *t.Isolation
+		iso = &copied
+	}
+	iso.readOnly = t.Sandbox.readOnlyDirs()
+	return iso
+}
+
+// runPipeline 執行管線並等待所有階段結束
+//
+// 回傳最後一個階段的 stdout 與所有階段的 stderr；錯誤以最後一個階段為準 (與 Shell 相同)，
+// 前面的階段無法啟動時也會回報錯誤。
+func runPipeline(ctx context.Context, dir string, stages [][]string, isolation *ExecIsolation) (string, error) {
+	var out syncBuffer
+	p, err := startPipeline(ctx, dir, stages, isolation, &out, false)
+	if err != nil {
+		return "", err
+	}
+	err = p.wait()
+	return out.String(), err
+}
+
+// pipeline 是已啟動的管線
+type pipeline struct {
+	cmds     []*exec.Cmd
+	started  int
+	startErr error
+	stdin    io.WriteCloser // 第一個階段的 stdin (只有 withStdin 時才有)
+	home     string         // 所有階段共用的暫存 HOME，wait 結束後刪除
+}
+
+// startPipeline 依序啟動管線的各個階段，以 os.Pipe 連接前一階段的 stdout 與下一階段的 stdin，
+// 最後一個階段的 stdout 與所有階段的 stderr 寫入 out
+func startPipeline(ctx context.Context, dir string, stages [][]string, isolation *ExecIsolation, out io.Writer, withStdin bool) (*pipeline, error) {
+	home, err := newHome()
+	if err != nil {
+		return nil, fmt.Errorf("exec sandbox unavailable: %w", err)
+	}
+	cmds := make([]*exec.Cmd, len(stages))
+	for i, argv := range stages {
+		c, err := isolation.command(ctx, dir, home, argv)
+		if err != nil {
+			os.RemoveAll(home)
+			return nil, err
+		}
+		c.Stderr = out
+		cmds[i] = c
+	}
+	cmds[len(cmds)-1].Stdout = out
+
+	p := &pipeline{cmds: cmds, home: home}
+	if withStdin {
+		w, err := cmds[0].StdinPipe()
+		if err != nil {
+			os.RemoveAll(home)
+			return nil, err
+		}
+		p.stdin = w
+	}
+
+	var pipes []*os.File
+	closePipes := func() {
+		for _, f := range pipes {
+			f.Close()
+		}
+		pipes = nil
+	}
+	for i := 0; i < len(cmds)-1; i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			closePipes()
+			os.RemoveAll(home)
+			return nil, err
+		}
+		cmds[i].Stdout = w
+		cmds[i+1].Stdin = r
+		pipes = append(pipes, r, w)
+	}
+
+	for _, c := range cmds {
+		if p.startErr = c.Start(); p.startErr != nil {
+			break
+		}
+		p.started++
+	}
+	// 子行程已繼承管線的兩端，父行程必須關閉自己的副本，下游才會收到 EOF
+	closePipes()
+	if p.started == 0 {
+		os.RemoveAll(home)
+		return nil, p.startErr
+	}
+	return p, nil
+}
+
+// wait 等待所有已啟動的階段結束，並刪除暫存的 HOME
+func (p *pipeline) wait() error {
+	defer os.RemoveAll(p.home)
+	var waitErr error
+	for i := 0; i < p.started; i++ {
+		if err := p.cmds[i].Wait(); i == len(p.cmds)-1 {
+			waitErr = err
+		}
+	}
+	if p.startErr != nil {
+		return p.startErr
+	}
+	return waitErr
+}
+
+// syncBuffer 是可同時由多個子行程寫入的緩衝區
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -70,6 +71,23 @@ func TestExecTool_Timeout(t *testing.T) {
 	}
 }
 
+func TestExecTimeout(t *testing.T) {
+	tests := []struct {
+		args map[string]any
+		want float64
+	}{
+		{map[string]any{}, execDefaultTimeout},
+		{map[string]any{"timeout": 5.0}, 5},
+		{map[string]any{"timeout": -1.0}, execDefaultTimeout},
+		{map[string]any{"timeout": 1e12}, execMaxTimeout},
+	}
+	for _, tt := range tests {
+		if got := execTimeout(tt.args); got != tt.want {
+			t.Errorf("execTimeout(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
 func TestExecTool_NonZeroExit(t *testing.T) {
 	tmpDir := t.TempDir()
 	sandbox, _ := NewSandbox(tmpDir)
@@ -119,3 +137,69 @@ func TestIsCommandSafe(t *testing.T) {
 		}
 	}
 }
+
+func TestExecTool_ArgumentsAreNotMangled(t *testing.T) {
+	tmpDir := t.TempDir()
+	sandbox, _ := NewSandbox(tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, "log.txt"), []byte("alpha\nb|c\nbeta;gamma\nalpha\n"), 0644)
+
+	tool := ExecTool{Sandbox: sandbox}
+	result := tool.Execute(context.Background(), map[string]any{"command": `grep -E "alpha|beta;gamma" log.txt`})
+	if result.IsError || !strings.Contains(result.ForLLM, "beta;gamma") {
+		t.Errorf("unexpected result: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"command": `grep -F "b|c" log.txt`})
+	if result.IsError || !strings.Contains(result.ForLLM, "b|c") {
+		t.Errorf("unexpected result: %s", result.ForLLM)
+	}
+}
+
+func TestExecTool_Pipeline(t *testing.T) {
+	tmpDir := t.TempDir()
+	sandbox, _ := NewSandbox(tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, "words.txt"), []byte("b\na\nb\nc\nb\n"), 0644)
+
+	tool := ExecTool{Sandbox: sandbox}
+	result := tool.Execute(context.Background(), map[string]any{"command": "cat words.txt | sort | uniq -c | sort -rn | head -n 1"})
+	if result.IsError || !strings.Contains(result.ForLLM, "3 b") {
+		t.Errorf("unexpected result: %s", result.ForLLM)
+	}
+
+	// 管線中任一階段不在白名單內時，整條管線都不會執行
+	result = tool.Execute(context.Background(), map[string]any{"command": "cat words.txt | xargs rm"})
+	if !result.IsError || !strings.Contains(result.ForLLM, `"xargs" is not an allowed command`) {
+		t.Errorf("unexpected result: %s", result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "words.txt")); err != nil {
+		t.Error("file was removed")
+	}
+}
+
+func TestExecTool_Policy(t *testing.T) {
+	tmpDir := t.TempDir()
+	sandbox, _ := NewSandbox(tmpDir)
+	tool := ExecTool{Sandbox: sandbox, Policy: DefaultExecPolicy().Merge(map[string]CommandRule{
+		"git": {Allow: []string{"status"}, Deny: []string{"push"}},
+	})}
+
+	for cmd, want := range map[string]string{
+		"git push origin main":  "denied by the exec policy",
+		"git commit -m x":       "may only be run as: git status",
+		"cat /etc/passwd":       "outside the workspace",
+		"echo hi; ls":           `";"`,
+		"bash -c 'echo hi'":     "not an allowed command",
+		"find . -delete":        "denied",
+		"echo hi > /tmp/x":      `">"`,
+		"/bin/cat notes.txt":    "not an allowed command",
+		"git log --format=%H":   "may only be run as",
+		"curl http://localhost": "not an allowed command",
+		"sort -o/tmp/pwned a":   "denied by the exec policy",
+		"grep -f/etc/passwd a":  "outside the workspace",
+	} {
+		result := tool.Execute(context.Background(), map[string]any{"command": cmd})
+		if !result.IsError || !strings.Contains(result.ForLLM, want) {
+			t.Errorf("%s: got %q, want error containing %q", cmd, result.ForLLM, want)
+		}
+	}
+}
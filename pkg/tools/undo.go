@@ -0,0 +1,56 @@
+package tools
+
+// ============================================================================
+// UndoLastChangeTool: 復原上一次修改
+// ============================================================================
+// 功能：還原目前會話中最近一次由檔案工具造成的修改
+// 工具名稱：undo_last_change
+//
+// 修改檔案的工具 (write_file、edit_file、apply_patch 等) 在寫入前會建立檢查點，
+// 本工具取出最近的檢查點並把檔案恢復成修改前的內容；
+// 當時不存在的檔案會被刪除。重複呼叫可以繼續往前復原。
+// ============================================================================
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chiisen/mini_bot/pkg/i18n"
+)
+
+type UndoLastChangeTool struct {
+	Checkpoints *CheckpointStore
+}
+
+func (t *UndoLastChangeTool) Name() string { return "undo_last_change" }
+func (t *UndoLastChangeTool) Description() string {
+	return i18n.GetInstance().T("tools.undo_last_change")
+}
+func (t *UndoLastChangeTool) Parameters() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{},
+	}
+}
+
+func (t *UndoLastChangeTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	if t.Checkpoints == nil {
+		return &ToolResult{ForLLM: "Error: checkpoints are not enabled", IsError: true}
+	}
+	cp, err := t.Checkpoints.Undo(SessionKeyFrom(ctx))
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Undid %s from %s:", cp.Tool, cp.Time.Format("2006-01-02 15:04:05"))
+	for _, f := range cp.Files {
+		if f.Existed {
+			sb.WriteString("\n  restored " + f.Path)
+		} else {
+			sb.WriteString("\n  removed " + f.Path + " (did not exist before)")
+		}
+	}
+	return &ToolResult{ForLLM: sb.String()}
+}
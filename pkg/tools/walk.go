@@ -0,0 +1,306 @@
+package tools
+
+// ============================================================================
+// 工作區走訪 (Workspace Walking)
+// ============================================================================
+// 提供 search_files 等工具共用的目錄走訪、Glob 比對與 .gitignore 判斷。
+//
+// 設計原理：
+//   - 一律從 Sandbox 檢查過的路徑開始走訪，符號連結會再經過 CheckPath，
+//     指向工作區外的連結會被略過
+//   - .git 目錄永遠略過；隱藏檔與 .gitignore 忽略的檔案可選擇是否略過
+//   - Glob 支援 *、?、[...] 以及跨目錄的 **
+// ============================================================================
+
+import (
+	"bufio"
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// walkOptions 控制走訪行為
+type walkOptions struct {
+	SkipHidden  bool // 略過以 "." 開頭的檔案與目錄
+	SkipIgnored bool // 略過 .gitignore 忽略的路徑
+	MaxDepth    int  // 最大深度 (root 的直接子項為 1)，0 表示不限制
+	IncludeDirs bool // 是否對目錄呼叫 callback
+}
+
+// walkEntry 是走訪時回報給 callback 的項目
+type walkEntry struct {
+	Abs   string      // 絕對路徑
+	Rel   string      // 相對於走訪起點的路徑 (使用 "/")
+	Depth int         // 深度 (root 的直接子項為 1)
+	Entry fs.DirEntry // 原始目錄項目
+}
+
+// errStopWalk 讓 callback 提早結束走訪 (例如已達結果上限)
+var errStopWalk = fs.SkipAll
+
+// walkWorkspace 走訪 root 目錄 (必須是 Sandbox.CheckPath 的回傳值)
+//
+// 參數：
+//   - ctx:     取消時停止走訪
+//   - sandbox: 工作區沙盒
+//   - root:    起始目錄的絕對路徑
+//   - opts:    走訪選項
+//   - fn:      對每個項目呼叫；回傳 errStopWalk 停止走訪、fs.SkipDir 略過目錄
+func walkWorkspace(ctx context.Context, sandbox *Sandbox, root string, opts walkOptions, fn func(walkEntry) error) error {
+	var ignore *gitignore
+	if opts.SkipIgnored {
+		ignore = newGitignore(sandbox.rootDir(root), root)
+	}
+
+	return filepath.WalkDir(root, func(abs string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			// 無法讀取的項目直接略過，不中斷整個走訪
+			if d != nil && d.IsDir() && abs != root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if abs == root {
+			return nil
+		}
+
+		rel, relErr := filepath.Rel(root, abs)
+		if relErr != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		depth := strings.Count(rel, "/") + 1
+		name := d.Name()
+		isDir := d.IsDir()
+
+		if isDir && name == ".git" {
+			return fs.SkipDir
+		}
+		if opts.SkipHidden && strings.HasPrefix(name, ".") {
+			if isDir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if ignore != nil && ignore.ignored(abs, isDir) {
+			if isDir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			// 連結目標必須仍在工作區內
+			if _, err := sandbox.CheckPath(abs); err != nil {
+				return nil
+			}
+		}
+
+		if isDir {
+			if ignore != nil {
+				ignore.load(abs)
+			}
+			if opts.IncludeDirs {
+				if err := fn(walkEntry{Abs: abs, Rel: rel, Depth: depth, Entry: d}); err != nil {
+					return err
+				}
+			}
+			if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		return fn(walkEntry{Abs: abs, Rel: rel, Depth: depth, Entry: d})
+	})
+}
+
+// ============================================================================
+// Glob 比對
+// ============================================================================
+
+// matchGlob 比對以 "/" 分隔的路徑，"**" 可比對零或多層目錄
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			// 連續的 ** 視為一個
+			for len(pat) > 0 && pat[0] == "**" {
+				pat = pat[1:]
+			}
+			if len(pat) == 0 {
+				return true
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pat, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pat[0], parts[0]); err != nil || !ok {
+			return false
+		}
+		pat, parts = pat[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// matchPathGlob 套用常見慣例：不含 "/" 的樣式 (例如 *.go) 比對檔名，否則比對完整相對路徑
+func matchPathGlob(pattern, rel string) bool {
+	pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "./")
+	if pattern == "" {
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		return matchGlob(pattern, path.Base(rel))
+	}
+	return matchGlob(pattern, rel)
+}
+
+// splitGlobs 將逗號分隔的 Glob 清單轉為切片
+func splitGlobs(s string) []string {
+	var globs []string
+	for _, g := range strings.Split(s, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			globs = append(globs, g)
+		}
+	}
+	return globs
+}
+
+func matchAnyGlob(globs []string, rel string) bool {
+	for _, g := range globs {
+		if matchPathGlob(g, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// .gitignore
+// ============================================================================
+
+type ignoreRule struct {
+	base     string // .gitignore 所在目錄 (相對於工作區，使用 "/")
+	pattern  string
+	negate   bool // "!pattern"
+	dirOnly  bool // "pattern/"
+	anchored bool // 樣式含 "/"，相對於 base 比對
+}
+
+// gitignore 累積走訪路徑上的 .gitignore 規則；後出現的規則優先
+type gitignore struct {
+	workspace string
+	rules     []ignoreRule
+}
+
+// newGitignore 載入 root 所在的根目錄 (工作區或其他沙盒根目錄) 到 root (含) 之間所有的 .gitignore
+func newGitignore(workspace, root string) *gitignore {
+	// CheckPath 回傳的是解析過符號連結的路徑，工作區也要以相同方式表示
+	if resolved, err := filepath.EvalSymlinks(workspace); err == nil {
+		workspace = resolved
+	}
+	g := &gitignore{workspace: workspace}
+	rel, err := filepath.Rel(workspace, root)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		g.load(root)
+		return g
+	}
+	dir := workspace
+	g.load(dir)
+	if rel != "." {
+		for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+			dir = filepath.Join(dir, part)
+			g.load(dir)
+		}
+	}
+	return g
+}
+
+// load 讀取 dir/.gitignore 並加入規則
+func (g *gitignore) load(dir string) {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	base := g.relative(dir)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:] // \# 或 \! 表示字面字元
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		g.rules = append(g.rules, rule)
+	}
+}
+
+// relative 回傳相對於工作區的路徑 (使用 "/"，工作區本身為 "")
+func (g *gitignore) relative(abs string) string {
+	rel, err := filepath.Rel(g.workspace, abs)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// ignored 判斷路徑是否被忽略
+func (g *gitignore) ignored(abs string, isDir bool) bool {
+	rel := g.relative(abs)
+	ignored := false
+	for _, r := range g.rules {
+		p := rel
+		if r.base != "" {
+			if !strings.HasPrefix(rel, r.base+"/") {
+				continue
+			}
+			p = rel[len(r.base)+1:]
+		}
+		if r.dirOnly && !isDir {
+			continue
+		}
+		var ok bool
+		if r.anchored {
+			ok = matchGlob(r.pattern, p)
+		} else {
+			ok = matchGlob(r.pattern, path.Base(p))
+		}
+		if ok {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
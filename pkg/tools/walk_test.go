@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "pkg/tools/main.go", true},
+		{"pkg/**", "pkg/tools/main.go", true},
+		{"pkg/**/main.go", "pkg/main.go", true},
+		{"pkg/*/main.go", "pkg/a/b/main.go", false},
+		{"docs/?.md", "docs/a.md", true},
+		{"[ab].txt", "c.txt", false},
+		{"build", "build", true},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+
+	if !matchPathGlob("*.go", "deep/dir/x.go") {
+		t.Error("patterns without a slash should match the file name at any depth")
+	}
+}
+
+func TestGitignore(t *testing.T) {
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, ".gitignore"), []byte("# comment\n*.log\n!keep.log\nbuild/\n/root-only.txt\n"), 0644)
+	os.MkdirAll(filepath.Join(ws, "sub"), 0755)
+	os.WriteFile(filepath.Join(ws, "sub", ".gitignore"), []byte("local.txt\n"), 0644)
+
+	g := newGitignore(ws, ws)
+	g.load(filepath.Join(ws, "sub"))
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"sub/deep.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"root-only.txt", false, true},
+		{"sub/root-only.txt", false, false},
+		{"sub/local.txt", false, true},
+		{"local.txt", false, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := g.ignored(filepath.Join(ws, filepath.FromSlash(tt.rel)), tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, dir=%v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chiisen/mini_bot/pkg/i18n"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+const (
+	webFetchMaxBytes        = 5 << 20 // maximum response body that will be downloaded
+	webFetchTimeout         = 20 * time.Second
+	webFetchDefaultMaxChars = 20000
+	webFetchMaxRedirects    = 5
+)
+
+// WebFetchTool downloads a page and returns its readable content as markdown-ish text.
+// Long pages are paginated: the result states the next offset to request.
+type WebFetchTool struct {
+	// AllowPrivate permits loopback and private network addresses.
+	// It is off by default so the agent can't be used to probe the local network.
+	AllowPrivate bool
+}
+
+func (t *WebFetchTool) Name() string        { return "web_fetch" }
+func (t *WebFetchTool) Description() string { return i18n.GetInstance().T("tools.web_fetch") }
+func (t *WebFetchTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"url": map[string]any{"type": "string", "description": i18n.GetInstance().T("tool_params.url")},
+			"offset": map[string]any{
+				"type":        "integer",
+				"minimum":     0,
+				"description": i18n.GetInstance().T("tool_params.offset_chars"),
+			},
+			"max_chars": map[string]any{
+				"type":        "integer",
+				"minimum":     500,
+				"maximum":     100000,
+				"description": i18n.GetInstance().T("tool_params.max_chars"),
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+func (t *WebFetchTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	rawURL, _ := args["url"].(string)
+	offset := 0
+	if v, ok := args["offset"].(float64); ok {
+		offset = int(v)
+	}
+	maxChars := webFetchDefaultMaxChars
+	if v, ok := args["max_chars"].(float64); ok {
+		maxChars = int(v)
+	}
+
+	target, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return &ToolResult{ForLLM: "Error: url must be an absolute http or https URL", IsError: true}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", target.String(), nil)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error creating request: %v", err), IsError: true}
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,application/json;q=0.8,*/*;q=0.5")
+
+	resp, err := t.client().Do(req)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error fetching %s: %v", target, err), IsError: true}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &ToolResult{ForLLM: fmt.Sprintf("Failed to fetch %s, HTTP status: %d", target, resp.StatusCode), IsError: true}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, webFetchMaxBytes+1))
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error reading response: %v", err), IsError: true}
+	}
+	truncatedDownload := len(body) > webFetchMaxBytes
+	if truncatedDownload {
+		body = body[:webFetchMaxBytes]
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = http.DetectContentType(body)
+		mediaType, params, _ = mime.ParseMediaType(mediaType)
+	}
+	isHTML := mediaType == "text/html" || mediaType == "application/xhtml+xml"
+	body, err = decodeBody(body, params["charset"], isHTML)
+	if err != nil {
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+
+	finalURL := resp.Request.URL
+	var title, text string
+	switch {
+	case isHTML:
+		title, text = extractReadable(string(body), finalURL)
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") || mediaType == "application/xml":
+		text = strings.ToValidUTF8(string(body), "�")
+	default:
+		return &ToolResult{ForLLM: fmt.Sprintf("Error: unsupported content type %q (%d bytes). web_fetch only handles HTML and text.", mediaType, len(body)), IsError: true}
+	}
+
+	return &ToolResult{ForLLM: formatFetchPage(finalURL.String(), title, text, offset, maxChars, truncatedDownload)}
+}
+
+// metaCharset finds <meta charset="big5"> or the http-equiv Content-Type form.
+var metaCharset = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.-]+)`)
+
+// decodeBody converts body to UTF-8 using the charset from the Content-Type
+// header or, for HTML without one, from a <meta> tag in the first 1024 bytes.
+// Labels are resolved like browsers do (e.g. big5, shift_jis, gbk); only
+// unknown labels on a body that is not already UTF-8 are an error.
+func decodeBody(body []byte, charset string, isHTML bool) ([]byte, error) {
+	if charset == "" && isHTML && !utf8.Valid(body) {
+		if m := metaCharset.FindSubmatch(body[:min(len(body), 1024)]); m != nil {
+			charset = string(m[1])
+		}
+	}
+	if charset == "" {
+		return body, nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		if utf8.Valid(body) {
+			return body, nil // mislabeled but readable
+		}
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	if name, _ := htmlindex.Name(enc); name == "utf-8" {
+		return body, nil
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode %s content: %v", charset, err)
+	}
+	return decoded, nil
+}
+
+// formatFetchPage returns the [offset, offset+maxChars) character window of text
+// with a header and, when more remains, the offset to continue from.
+func formatFetchPage(pageURL, title, text string, offset, maxChars int, truncatedDownload bool) string {
+	runes := []rune(text)
+	total := len(runes)
+	if offset > total {
+		offset = total
+	}
+	end := offset + maxChars
+	if end > total {
+		end = total
+	}
+
+	var sb strings.Builder
+	sb.WriteString("URL: " + pageURL + "\n")
+	if title != "" {
+		sb.WriteString("Title: " + title + "\n")
+	}
+	sb.WriteString("\n")
+	if total == 0 {
+		sb.WriteString("(no readable content found)\n")
+	} else {
+		sb.WriteString(string(runes[offset:end]))
+		sb.WriteString("\n")
+	}
+
+	if end < total {
+		sb.WriteString(fmt.Sprintf("\n[Showing characters %d-%d of %d. Call web_fetch again with offset=%d to read more.]\n", offset, end, total, end))
+	} else if offset > 0 {
+		sb.WriteString(fmt.Sprintf("\n[Showing characters %d-%d of %d. End of page.]\n", offset, end, total))
+	}
+	if truncatedDownload {
+		sb.WriteString(fmt.Sprintf("[Note: page exceeded %d bytes; only the beginning was downloaded.]\n", webFetchMaxBytes))
+	}
+	return sb.String()
+}
+
+func (t *WebFetchTool) client() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !t.AllowPrivate {
+		// Checked at connect time, after DNS resolution, so redirects and
+		// DNS tricks can't reach internal addresses either.
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip != nil && isPrivateIP(ip) {
+				return fmt.Errorf("refusing to connect to private address %s", host)
+			}
+			return nil
+		}
+	}
+
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   webFetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= webFetchMaxRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %s", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsInterfaceLocalMulticast()
+}
@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+const sampleArticle = `<!DOCTYPE html>
+<html><head><title>Sample &amp; Page</title><style>body{color:red}</style></head>
+<body>
+<nav><a href="/home">Home</a> <a href="/about">About</a></nav>
+<main>
+  <h1>Main Heading</h1>
+  <p>First paragraph with a <a href="/docs/intro">relative link</a>.</p>
+  <h2>Details</h2>
+  <ul><li>One</li><li>Two</li></ul>
+  <pre>line 1
+line 2</pre>
+  <script>alert("x")</script>
+  <div hidden>secret</div>
+</main>
+<footer>Copyright</footer>
+</body></html>`
+
+func TestExtractReadable(t *testing.T) {
+	base, _ := url.Parse("https://example.com/page")
+	title, text := extractReadable(sampleArticle, base)
+
+	if title != "Sample & Page" {
+		t.Errorf("title = %q", title)
+	}
+	for _, want := range []string{
+		"# Main Heading",
+		"## Details",
+		"[relative link](https://example.com/docs/intro)",
+		"- One",
+		"- Two",
+		"```\nline 1\nline 2\n```",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in output:\n%s", want, text)
+		}
+	}
+	for _, unwanted := range []string{"Home", "Copyright", "alert", "secret", "color:red"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("did not expect %q in output:\n%s", unwanted, text)
+		}
+	}
+}
+
+func TestWebFetchTool(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(sampleArticle))
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("abcdefghij", 100)))
+	})
+	big5Page, _ := traditionalchinese.Big5.NewEncoder().String(`<html><head><meta charset="big5"><title>中央氣象署</title></head><body><p>臺北市今日晴時多雲</p></body></html>`)
+	mux.HandleFunc("/big5", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=Big5")
+		w.Write([]byte(big5Page))
+	})
+	mux.HandleFunc("/big5-meta", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(big5Page))
+	})
+	mux.HandleFunc("/unknown-charset", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=x-unknown")
+		w.Write([]byte(big5Page))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte{0x89, 'P', 'N', 'G'})
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/article", http.StatusFound)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tool := &WebFetchTool{AllowPrivate: true}
+	ctx := context.Background()
+
+	t.Run("html", func(t *testing.T) {
+		res := tool.Execute(ctx, map[string]any{"url": srv.URL + "/redirect"})
+		if res.IsError {
+			t.Fatalf("unexpected error: %s", res.ForLLM)
+		}
+		if !strings.Contains(res.ForLLM, "Title: Sample & Page") || !strings.Contains(res.ForLLM, "URL: "+srv.URL+"/article") {
+			t.Errorf("missing header: %s", res.ForLLM)
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		res := tool.Execute(ctx, map[string]any{"url": srv.URL + "/plain", "max_chars": float64(600)})
+		if res.IsError {
+			t.Fatalf("unexpected error: %s", res.ForLLM)
+		}
+		if !strings.Contains(res.ForLLM, "offset=600") {
+			t.Errorf("expected continuation hint: %s", res.ForLLM)
+		}
+		res = tool.Execute(ctx, map[string]any{"url": srv.URL + "/plain", "offset": float64(600), "max_chars": float64(600)})
+		if !strings.Contains(res.ForLLM, "600-1000 of 1000") || !strings.Contains(res.ForLLM, "End of page") {
+			t.Errorf("unexpected second page: %s", res.ForLLM)
+		}
+	})
+
+	t.Run("big5", func(t *testing.T) {
+		for _, path := range []string{"/big5", "/big5-meta"} {
+			res := tool.Execute(ctx, map[string]any{"url": srv.URL + path})
+			if res.IsError || !strings.Contains(res.ForLLM, "Title: 中央氣象署") || !strings.Contains(res.ForLLM, "臺北市今日晴時多雲") {
+				t.Errorf("%s: expected decoded Big5 text, got %s", path, res.ForLLM)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := map[string]string{
+			"unknown charset":  srv.URL + "/unknown-charset",
+			"unsupported type": srv.URL + "/image",
+			"http status":      srv.URL + "/missing",
+			"bad scheme":       "file:///etc/passwd",
+			"relative":         "/article",
+		}
+		for name, u := range cases {
+			if res := tool.Execute(ctx, map[string]any{"url": u}); !res.IsError {
+				t.Errorf("%s: expected error, got %s", name, res.ForLLM)
+			}
+		}
+	})
+
+	t.Run("blocks private addresses", func(t *testing.T) {
+		res := (&WebFetchTool{}).Execute(ctx, map[string]any{"url": srv.URL + "/article"})
+		if !res.IsError || !strings.Contains(res.ForLLM, "private address") {
+			t.Errorf("expected private address to be refused, got %s", res.ForLLM)
+		}
+	})
+}
@@ -3,17 +3,19 @@ package tools
 import (
 	"context"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"regexp"
 	"strings"
-	"time"
 
 	"github.com/chiisen/mini_bot/pkg/i18n"
 )
 
-type WebSearchTool struct{}
+const defaultSearchResults = 5
+
+// WebSearchTool queries the configured search engines in order, falling back
+// to the next one when a backend fails. With no engines configured it uses DuckDuckGo.
+type WebSearchTool struct {
+	Engines    []SearchEngine
+	MaxResults int // default number of results, 5 when zero
+}
 
 func (t *WebSearchTool) Name() string        { return "web_search" }
 func (t *WebSearchTool) Description() string { return i18n.GetInstance().T("tools.web_search") }
@@ -22,71 +24,78 @@ func (t *WebSearchTool) Parameters() map[string]any {
 		"type": "object",
 		"properties": map[string]any{
 			"query": map[string]any{"type": "string", "description": "The search query"},
+			"max_results": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"maximum":     20,
+				"description": i18n.GetInstance().T("tool_params.max_results"),
+			},
 		},
 		"required": []string{"query"},
 	}
 }
 
-// Very basic HTML strip regex for DuckDuckGo
-var ddgResultRegex = regexp.MustCompile(`(?s)<a class="result__url" href="([^"]+)".*?<a class="result__snippet[^>]+>(.*?)</a>`)
-var tagRemover = regexp.MustCompile(`<[^>]*>`)
-
 func (t *WebSearchTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	query, ok := args["query"].(string)
-	if !ok || query == "" {
+	if !ok || strings.TrimSpace(query) == "" {
 		return &ToolResult{ForLLM: "Error: query is required", IsError: true}
 	}
-
-	searchURL := fmt.Sprintf("https://html.duckduckgo.com/html/?q=%s", url.QueryEscape(query))
-
-	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
-	if err != nil {
-		return &ToolResult{ForLLM: fmt.Sprintf("Error creating request: %v", err), IsError: true}
+	limit := t.MaxResults
+	if limit <= 0 {
+		limit = defaultSearchResults
 	}
-
-	// DDG HTML requires a user-agent to not block requests immediately
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.0.0 Safari/537.36")
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return &ToolResult{ForLLM: fmt.Sprintf("Error fetching search results: %v", err), IsError: true}
+	if v, ok := args["max_results"].(float64); ok && v > 0 {
+		limit = int(v)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return &ToolResult{ForLLM: fmt.Sprintf("Failed to fetch results, HTTP status: %d", resp.StatusCode), IsError: true}
+	engines := t.Engines
+	if len(engines) == 0 {
+		engines = []SearchEngine{&DuckDuckGoEngine{}}
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return &ToolResult{ForLLM: fmt.Sprintf("Error reading response: %v", err), IsError: true}
+	var failures []string
+	for _, engine := range engines {
+		results, err := engine.Search(ctx, query, limit)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", engine.Name(), err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		return &ToolResult{ForLLM: formatSearchResults(engine.Name(), results, failures)}
 	}
 
-	bodyStr := string(body)
-	matches := ddgResultRegex.FindAllStringSubmatch(bodyStr, 5) // Get top 5 results
-
-	if len(matches) == 0 {
-		return &ToolResult{ForLLM: "No results found or parser failed.", IsError: false}
+	return &ToolResult{
+		ForLLM:  "Error: all search engines failed:\n- " + strings.Join(failures, "\n- "),
+		IsError: true,
 	}
+}
 
+func formatSearchResults(engine string, results []SearchResult, failures []string) string {
 	var sb strings.Builder
-	sb.WriteString("Web Search Results:\n")
-	for i, match := range matches {
-		if len(match) == 3 {
-			link := match[1]
-			snippet := tagRemover.ReplaceAllString(match[2], "")
-
-			// DDG redirects
-			if strings.HasPrefix(link, "//duckduckgo.com/l/?uddg=") {
-				link = strings.TrimPrefix(link, "//duckduckgo.com/l/?uddg=")
-				link, _ = url.QueryUnescape(link)
-				link = strings.Split(link, "&rut=")[0]
-			}
+	if len(failures) > 0 {
+		sb.WriteString(fmt.Sprintf("(fell back to %s after: %s)\n", engine, strings.Join(failures, "; ")))
+	}
+	if len(results) == 0 {
+		sb.WriteString("No results found.")
+		return sb.String()
+	}
 
-			sb.WriteString(fmt.Sprintf("%d. URL: %s\n   Snippet: %s\n\n", i+1, link, strings.TrimSpace(snippet)))
+	sb.WriteString(fmt.Sprintf("Web Search Results (%s):\n", engine))
+	for i, r := range results {
+		title := r.Title
+		if title == "" {
+			title = r.URL
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s\n   URL: %s\n", i+1, title, r.URL))
+		if r.Date != "" {
+			sb.WriteString(fmt.Sprintf("   Date: %s\n", r.Date))
 		}
+		if r.Snippet != "" {
+			sb.WriteString(fmt.Sprintf("   Snippet: %s\n", r.Snippet))
+		}
+		sb.WriteString("\n")
 	}
-
-	return &ToolResult{ForLLM: sb.String(), IsError: false}
+	return sb.String()
 }
@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureServer serves a recorded search response and records the last request.
+func fixtureServer(t *testing.T, fixture, contentType string, last **http.Request) *httptest.Server {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "search", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if last != nil {
+			*last = r.Clone(context.Background())
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func checkGenericsResults(t *testing.T, results []SearchResult) {
+	t.Helper()
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d: %+v", len(results), results)
+	}
+	if results[0].URL != "https://go.dev/doc/tutorial/generics" {
+		t.Errorf("unexpected first URL %q", results[0].URL)
+	}
+	if !strings.HasPrefix(results[0].Title, "Tutorial: Getting started with generics") {
+		t.Errorf("unexpected first title %q", results[0].Title)
+	}
+	if !strings.Contains(results[0].Snippet, "basics of generics") || strings.Contains(results[0].Snippet, "<") {
+		t.Errorf("unexpected first snippet %q", results[0].Snippet)
+	}
+	if results[1].URL != "https://go.dev/blog/intro-generics" {
+		t.Errorf("unexpected second URL %q", results[1].URL)
+	}
+}
+
+func TestDuckDuckGoEngine(t *testing.T) {
+	var req *http.Request
+	srv := fixtureServer(t, "duckduckgo.html", "text/html", &req)
+
+	results, err := (&DuckDuckGoEngine{Endpoint: srv.URL}).Search(context.Background(), "golang generics", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGenericsResults(t, results)
+	if req.URL.Query().Get("q") != "golang generics" {
+		t.Errorf("unexpected query %q", req.URL.RawQuery)
+	}
+	if !strings.Contains(results[1].Snippet, "programming & type parameters") {
+		t.Errorf("entities not decoded: %q", results[1].Snippet)
+	}
+
+	limited, _ := (&DuckDuckGoEngine{Endpoint: srv.URL}).Search(context.Background(), "golang generics", 1)
+	if len(limited) != 1 {
+		t.Errorf("expected limit to apply, got %d results", len(limited))
+	}
+}
+
+func TestDuckDuckGoEngine_Blocked(t *testing.T) {
+	srv := fixtureServer(t, "duckduckgo_blocked.html", "text/html", nil)
+	if _, err := (&DuckDuckGoEngine{Endpoint: srv.URL}).Search(context.Background(), "q", 5); err == nil {
+		t.Error("expected error for bot challenge page")
+	}
+}
+
+func TestSearXNGEngine(t *testing.T) {
+	var req *http.Request
+	srv := fixtureServer(t, "searxng.json", "application/json", &req)
+
+	results, err := (&SearXNGEngine{BaseURL: srv.URL + "/"}).Search(context.Background(), "golang generics", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGenericsResults(t, results)
+	if req.URL.Path != "/search" || req.URL.Query().Get("format") != "json" {
+		t.Errorf("unexpected request %s", req.URL)
+	}
+	if results[1].Date != "2022-03-22T00:00:00" {
+		t.Errorf("unexpected date %q", results[1].Date)
+	}
+}
+
+func TestBraveEngine(t *testing.T) {
+	var req *http.Request
+	srv := fixtureServer(t, "brave.json", "application/json", &req)
+
+	results, err := (&BraveEngine{APIKey: "test-key", Endpoint: srv.URL}).Search(context.Background(), "golang generics", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGenericsResults(t, results)
+	if req.Header.Get("X-Subscription-Token") != "test-key" || req.URL.Query().Get("count") != "5" {
+		t.Errorf("unexpected request %s %v", req.URL, req.Header)
+	}
+	if results[0].Date != "2023-08-01T00:00:00" || results[1].Date != "March 22, 2022" {
+		t.Errorf("unexpected dates %q %q", results[0].Date, results[1].Date)
+	}
+}
+
+func TestTavilyEngine(t *testing.T) {
+	var body map[string]any
+	var auth string
+	data, _ := os.ReadFile(filepath.Join("testdata", "search", "tavily.json"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	results, err := (&TavilyEngine{APIKey: "tvly-test", Endpoint: srv.URL}).Search(context.Background(), "golang generics", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGenericsResults(t, results)
+	if auth != "Bearer tvly-test" || body["query"] != "golang generics" || body["max_results"] != float64(3) {
+		t.Errorf("unexpected request auth=%q body=%v", auth, body)
+	}
+	if strings.Contains(results[0].Snippet, "\n") {
+		t.Errorf("snippet whitespace not collapsed: %q", results[0].Snippet)
+	}
+}
+
+func TestNewSearchEngine(t *testing.T) {
+	if _, err := NewSearchEngine("brave", "", ""); err == nil {
+		t.Error("expected brave without key to fail")
+	}
+	if _, err := NewSearchEngine("searxng", "", ""); err == nil {
+		t.Error("expected searxng without URL to fail")
+	}
+	if _, err := NewSearchEngine("bing", "", ""); err == nil {
+		t.Error("expected unknown engine to fail")
+	}
+	if e, err := NewSearchEngine("DuckDuckGo", "", ""); err != nil || e.Name() != "duckduckgo" {
+		t.Errorf("unexpected result %v %v", e, err)
+	}
+}
+
+func TestWebSearchTool_Fallback(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer failing.Close()
+	good := fixtureServer(t, "searxng.json", "application/json", nil)
+
+	tool := &WebSearchTool{Engines: []SearchEngine{
+		&BraveEngine{APIKey: "k", Endpoint: failing.URL},
+		&SearXNGEngine{BaseURL: good.URL},
+	}}
+	res := tool.Execute(context.Background(), map[string]any{"query": "golang generics"})
+	if res.IsError {
+		t.Fatalf("unexpected error: %s", res.ForLLM)
+	}
+	for _, want := range []string{"fell back to searxng", "brave: HTTP status 429", "1. Tutorial: Getting started with generics", "Date: 2022-03-22T00:00:00"} {
+		if !strings.Contains(res.ForLLM, want) {
+			t.Errorf("expected %q in output:\n%s", want, res.ForLLM)
+		}
+	}
+
+	tool.Engines = tool.Engines[:1]
+	res = tool.Execute(context.Background(), map[string]any{"query": "golang generics"})
+	if !res.IsError || !strings.Contains(res.ForLLM, "all search engines failed") {
+		t.Errorf("expected failure when every engine fails, got %s", res.ForLLM)
+	}
+}